@@ -0,0 +1,25 @@
+package rtmidi
+
+import "errors"
+
+// ErrUnsupportedPlatform is returned when opening ports in a build without
+// a MIDI backend, such as one with cgo disabled.
+var ErrUnsupportedPlatform = errors.New("rtmidi: no MIDI backend on this platform")
+
+func (api API) String() string {
+	switch api {
+	case APIUnspecified:
+		return "unspecified"
+	case APILinuxALSA:
+		return "alsa"
+	case APIUnixJack:
+		return "jack"
+	case APIMacOSXCore:
+		return "coreaudio"
+	case APIWindowsMM:
+		return "winmm"
+	case APIDummy:
+		return "dummy"
+	}
+	return "?"
+}
@@ -0,0 +1,210 @@
+package rtmidi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var errVerify = errors.New("verification failed")
+
+// DumpProfile describes how to back up and restore the patches of a device.
+type DumpProfile struct {
+	// Name identifies the device.
+	Name string
+	// Request is the dump request for a single patch; the byte at
+	// PatchIndex is replaced with the patch number. As byte 0 is the F0
+	// starting the request, a PatchIndex of zero or less leaves the request
+	// unchanged.
+	Request    []byte
+	PatchIndex int
+	// ReplyPrefix is the start of every dump message the device sends in
+	// reply. Match, if set, is used instead.
+	ReplyPrefix []byte
+	Match       func(patch int, msg []byte) bool
+	// Replies is the number of messages making up one patch; zero means 1.
+	Replies int
+	// Delay is the gap between messages sent to the device.
+	Delay time.Duration
+	// Timeout is how long to wait for each reply; zero means 2 seconds.
+	Timeout time.Duration
+	// Retries is how many times a failed patch is attempted again.
+	Retries int
+}
+
+func (p *DumpProfile) request(patch int) []byte {
+	req := append([]byte(nil), p.Request...)
+	if p.PatchIndex > 0 && p.PatchIndex < len(req) {
+		req[p.PatchIndex] = byte(patch & 0x7f)
+	}
+	return req
+}
+
+func (p *DumpProfile) match(patch int, msg []byte) bool {
+	if p.Match != nil {
+		return p.Match(patch, msg)
+	}
+	return bytes.HasPrefix(msg, p.ReplyPrefix)
+}
+
+func (p *DumpProfile) replies() int {
+	if p.Replies > 0 {
+		return p.Replies
+	}
+	return 1
+}
+
+func (p *DumpProfile) timeout() time.Duration {
+	if p.Timeout > 0 {
+		return p.Timeout
+	}
+	return 2 * time.Second
+}
+
+// Patch is the dump of one patch of a device.
+type Patch struct {
+	Number   int
+	Messages [][]byte
+}
+
+// BulkDump backs up and restores banks of patches of a device connected to
+// a pair of ports, as described by its DumpProfile. While running it takes
+// over the callback of the input.
+type BulkDump struct {
+	Profile *DumpProfile
+	// Progress, if set, is called after each patch with the number of
+	// patches done and the total.
+	Progress func(done, total int)
+
+	in  MIDIIn
+	out MIDIOut
+}
+
+// NewBulkDump returns a BulkDump for the device described by profile.
+func NewBulkDump(in MIDIIn, out MIDIOut, profile *DumpProfile) *BulkDump {
+	return &BulkDump{Profile: profile, in: in, out: out}
+}
+
+func (b *BulkDump) progress(done, total int) {
+	if b.Progress != nil {
+		b.Progress(done, total)
+	}
+}
+
+// Backup requests the given patches from the device.
+func (b *BulkDump) Backup(ctx context.Context, patches []int) ([]Patch, error) {
+	r, err := newSysexReader(b.in, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var bank []Patch
+	for i, n := range patches {
+		var msgs [][]byte
+		for try := 0; ; try++ {
+			msgs, err = b.fetch(ctx, r, n)
+			if err == nil || try >= b.Profile.Retries || ctx.Err() != nil {
+				break
+			}
+		}
+		if err != nil {
+			return bank, fmt.Errorf("rtmidi: backing up patch %d: %w", n, err)
+		}
+		bank = append(bank, Patch{Number: n, Messages: msgs})
+		b.progress(i+1, len(patches))
+		if err := b.pause(ctx); err != nil {
+			return bank, err
+		}
+	}
+	return bank, nil
+}
+
+func (b *BulkDump) fetch(ctx context.Context, r *sysexReader, patch int) ([][]byte, error) {
+	p := b.Profile
+	r.drain()
+	if err := b.out.SendMessage(p.request(patch)); err != nil {
+		return nil, err
+	}
+	var msgs [][]byte
+	deadline := time.Now().Add(p.timeout())
+	for len(msgs) < p.replies() {
+		msg, err := r.next(ctx, time.Until(deadline))
+		if err != nil {
+			return nil, err
+		}
+		if p.match(patch, msg) {
+			msgs = append(msgs, msg)
+			deadline = time.Now().Add(p.timeout())
+		}
+	}
+	return msgs, nil
+}
+
+func (b *BulkDump) pause(ctx context.Context) error {
+	if b.Profile.Delay <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(b.Profile.Delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Restore sends a bank of patches back to the device. With verify set, each
+// patch is requested again afterwards and compared with what was sent, and
+// sent again if it differs.
+func (b *BulkDump) Restore(ctx context.Context, bank []Patch, verify bool) error {
+	var r *sysexReader
+	if verify {
+		var err error
+		if r, err = newSysexReader(b.in, nil); err != nil {
+			return err
+		}
+		defer r.Close()
+	}
+	for i, patch := range bank {
+		var err error
+		for try := 0; ; try++ {
+			err = b.restore(ctx, r, patch)
+			if err == nil || try >= b.Profile.Retries || ctx.Err() != nil {
+				break
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("rtmidi: restoring patch %d: %w", patch.Number, err)
+		}
+		b.progress(i+1, len(bank))
+	}
+	return nil
+}
+
+func (b *BulkDump) restore(ctx context.Context, r *sysexReader, patch Patch) error {
+	for _, msg := range patch.Messages {
+		if err := b.out.SendMessage(msg); err != nil {
+			return err
+		}
+		if err := b.pause(ctx); err != nil {
+			return err
+		}
+	}
+	if r == nil {
+		return nil
+	}
+	got, err := b.fetch(ctx, r, patch.Number)
+	if err != nil {
+		return err
+	}
+	if len(got) != len(patch.Messages) {
+		return errVerify
+	}
+	for i := range got {
+		if !bytes.Equal(got[i], patch.Messages[i]) {
+			return errVerify
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package rtmidi
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// fakeSynth answers dump requests F0 41 11 <patch> F7 with two messages
+// per patch and stores patches sent to it.
+type fakeSynth struct {
+	mu      sync.Mutex
+	in      *fakeIn
+	patches map[byte][][]byte
+	pending [][]byte
+}
+
+func (s *fakeSynth) receive(msg []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case len(msg) == 5 && msg[1] == 0x41 && msg[2] == 0x11:
+		replies := s.patches[msg[3]]
+		go func() {
+			for _, r := range replies {
+				s.in.deliver(r)
+			}
+		}()
+	case len(msg) > 4 && msg[1] == 0x41 && msg[2] == 0x12:
+		s.pending = append(s.pending, msg)
+		if len(s.pending) == 2 {
+			s.patches[msg[3]] = s.pending
+			s.pending = nil
+		}
+	}
+}
+
+func dumpMessages(patch byte, data byte) [][]byte {
+	return [][]byte{{0xf0, 0x41, 0x12, patch, 0, data, 0xf7}, {0xf0, 0x41, 0x12, patch, 1, data, 0xf7}}
+}
+
+func TestBulkDump(t *testing.T) {
+	in, out := &fakeIn{}, &fakeOut{}
+	synth := &fakeSynth{in: in, patches: map[byte][][]byte{
+		0: dumpMessages(0, 10),
+		1: dumpMessages(1, 11),
+	}}
+	out.onSend = synth.receive
+	profile := &DumpProfile{
+		Name:        "test",
+		Request:     []byte{0xf0, 0x41, 0x11, 0, 0xf7},
+		PatchIndex:  3,
+		ReplyPrefix: []byte{0xf0, 0x41, 0x12},
+		Replies:     2,
+		Retries:     1,
+	}
+	b := NewBulkDump(in, out, profile)
+	var done int
+	b.Progress = func(n, total int) { done = n }
+	bank, err := b.Backup(context.Background(), []int{0, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Patch{{0, dumpMessages(0, 10)}, {1, dumpMessages(1, 11)}}
+	if !reflect.DeepEqual(bank, want) || done != 2 {
+		t.Errorf("Backup = %v", bank)
+	}
+
+	bank[0].Messages = dumpMessages(0, 20)
+	if err := b.Restore(context.Background(), bank[:1], true); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(synth.patches[0], dumpMessages(0, 20)) {
+		t.Errorf("synth has %v", synth.patches[0])
+	}
+}
+
+func TestBulkDumpCanceled(t *testing.T) {
+	in, out := &fakeIn{}, &fakeOut{}
+	profile := &DumpProfile{
+		Name:        "test",
+		Request:     []byte{0xf0, 0x41, 0x11, 0, 0xf7},
+		PatchIndex:  3,
+		ReplyPrefix: []byte{0xf0, 0x41, 0x12},
+		Retries:     1,
+	}
+	b := NewBulkDump(in, out, profile)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := b.Backup(ctx, []int{0}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Backup = %v", err)
+	}
+	if err := b.Restore(ctx, []Patch{{0, dumpMessages(0, 10)}}, true); !errors.Is(err, context.Canceled) {
+		t.Errorf("Restore = %v", err)
+	}
+}
+
+func TestDumpProfileRequest(t *testing.T) {
+	for index, want := range map[int][]byte{
+		-1: {0xf0, 0x41, 0x11, 0x00, 0xf7},
+		0:  {0xf0, 0x41, 0x11, 0x00, 0xf7},
+		3:  {0xf0, 0x41, 0x11, 0x05, 0xf7},
+		5:  {0xf0, 0x41, 0x11, 0x00, 0xf7},
+	} {
+		p := &DumpProfile{Request: []byte{0xf0, 0x41, 0x11, 0x00, 0xf7}, PatchIndex: index}
+		if got := p.request(5); !reflect.DeepEqual(got, want) {
+			t.Errorf("PatchIndex %d: request % x, want % x", index, got, want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package rtmidi
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ControllerNames maps control change numbers to names. Used as the names
+// of a device, numbers it leaves out fall back to StandardControllerNames.
+type ControllerNames map[int]string
+
+// StandardControllerNames holds the names the MIDI specification gives to
+// control change numbers. Undefined numbers are left out.
+var StandardControllerNames = ControllerNames{
+	0:   "Bank Select",
+	1:   "Modulation Wheel",
+	2:   "Breath Controller",
+	4:   "Foot Controller",
+	5:   "Portamento Time",
+	6:   "Data Entry",
+	7:   "Volume",
+	8:   "Balance",
+	10:  "Pan",
+	11:  "Expression",
+	12:  "Effect Control 1",
+	13:  "Effect Control 2",
+	16:  "General Purpose 1",
+	17:  "General Purpose 2",
+	18:  "General Purpose 3",
+	19:  "General Purpose 4",
+	32:  "Bank Select LSB",
+	33:  "Modulation Wheel LSB",
+	34:  "Breath Controller LSB",
+	36:  "Foot Controller LSB",
+	37:  "Portamento Time LSB",
+	38:  "Data Entry LSB",
+	39:  "Volume LSB",
+	40:  "Balance LSB",
+	42:  "Pan LSB",
+	43:  "Expression LSB",
+	44:  "Effect Control 1 LSB",
+	45:  "Effect Control 2 LSB",
+	64:  "Sustain",
+	65:  "Portamento",
+	66:  "Sostenuto",
+	67:  "Soft Pedal",
+	68:  "Legato Footswitch",
+	69:  "Hold 2",
+	70:  "Sound Variation",
+	71:  "Resonance",
+	72:  "Release Time",
+	73:  "Attack Time",
+	74:  "Brightness",
+	75:  "Decay Time",
+	76:  "Vibrato Rate",
+	77:  "Vibrato Depth",
+	78:  "Vibrato Delay",
+	79:  "Sound Controller 10",
+	80:  "General Purpose 5",
+	81:  "General Purpose 6",
+	82:  "General Purpose 7",
+	83:  "General Purpose 8",
+	84:  "Portamento Control",
+	88:  "High Resolution Velocity Prefix",
+	91:  "Reverb Send",
+	92:  "Tremolo Depth",
+	93:  "Chorus Send",
+	94:  "Celeste Depth",
+	95:  "Phaser Depth",
+	96:  "Data Increment",
+	97:  "Data Decrement",
+	98:  "NRPN LSB",
+	99:  "NRPN MSB",
+	100: "RPN LSB",
+	101: "RPN MSB",
+	120: "All Sound Off",
+	121: "Reset All Controllers",
+	122: "Local Control",
+	123: "All Notes Off",
+	124: "Omni Off",
+	125: "Omni On",
+	126: "Mono On",
+	127: "Poly On",
+}
+
+// Name returns the name of controller cc, or "" if neither n nor the
+// standard table names it. A nil ControllerNames gives the standard names.
+func (n ControllerNames) Name(cc int) string {
+	if name, ok := n[cc]; ok {
+		return name
+	}
+	return StandardControllerNames[cc]
+}
+
+// Label returns the name of controller cc for display, or "CC <n>" if it
+// has none.
+func (n ControllerNames) Label(cc int) string {
+	if name := n.Name(cc); name != "" {
+		return name
+	}
+	return fmt.Sprintf("CC %d", cc)
+}
+
+var deviceControllers = struct {
+	sync.Mutex
+	names map[string]ControllerNames
+}{names: map[string]ControllerNames{}}
+
+// RegisterControllerNames records the controller names of a device, such as
+// "Filter Cutoff" for the CC a synth maps its cutoff to. The device is
+// matched by normalized port name, see NormalizePortName. Registering nil
+// removes the device.
+func RegisterControllerNames(device string, names ControllerNames) {
+	deviceControllers.Lock()
+	defer deviceControllers.Unlock()
+	key := NormalizePortName(device)
+	if names == nil {
+		delete(deviceControllers.names, key)
+		return
+	}
+	deviceControllers.names[key] = names
+}
+
+// DeviceControllerNames returns the controller names registered for the
+// device behind a port name, or nil, which names standard controllers only.
+func DeviceControllerNames(port string) ControllerNames {
+	deviceControllers.Lock()
+	defer deviceControllers.Unlock()
+	return deviceControllers.names[NormalizePortName(port)]
+}
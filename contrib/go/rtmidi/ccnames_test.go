@@ -0,0 +1,37 @@
+package rtmidi
+
+import "testing"
+
+func TestControllerNames(t *testing.T) {
+	RegisterControllerNames("Minilogue", ControllerNames{43: "Filter Cutoff", 64: "Hold"})
+	defer RegisterControllerNames("Minilogue", nil)
+
+	names := DeviceControllerNames("minilogue:minilogue MIDI 2 24:1")
+	for _, tt := range []struct {
+		names ControllerNames
+		cc    int
+		name  string
+		label string
+	}{
+		{nil, 7, "Volume", "Volume"},
+		{nil, 43, "Expression LSB", "Expression LSB"},
+		{nil, 20, "", "CC 20"},
+		{names, 43, "Filter Cutoff", "Filter Cutoff"},
+		{names, 64, "Hold", "Hold"},
+		{names, 7, "Volume", "Volume"},
+	} {
+		if got := tt.names.Name(tt.cc); got != tt.name {
+			t.Errorf("Name(%d) = %q, want %q", tt.cc, got, tt.name)
+		}
+		if got := tt.names.Label(tt.cc); got != tt.label {
+			t.Errorf("Label(%d) = %q, want %q", tt.cc, got, tt.label)
+		}
+	}
+
+	if got, want := FormatMessageNames([]byte{0xb2, 43, 90}, names), "ControlChange ch=3 cc=43 (Filter Cutoff) value=90"; got != want {
+		t.Errorf("FormatMessageNames = %q, want %q", got, want)
+	}
+	if DeviceControllerNames("Launchkey Mini") != nil {
+		t.Error("names returned for an unregistered device")
+	}
+}
@@ -0,0 +1,167 @@
+package rtmidi
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultChordWindow is how close together the NoteOns of a chord must
+// start for a ChordDetector, unless set otherwise.
+const DefaultChordWindow = 30 * time.Millisecond
+
+// ChordNote is a note of a Chord.
+type ChordNote struct {
+	Channel, Key, Velocity int
+}
+
+// Chord is a group of notes started at about the same time.
+type Chord struct {
+	// At is the time of the first NoteOn.
+	At time.Time
+	// Notes are the notes of the chord, from the lowest key.
+	Notes []ChordNote
+}
+
+// Keys returns the keys of the chord, from the lowest.
+func (c Chord) Keys() []int {
+	keys := make([]int, len(c.Notes))
+	for i, n := range c.Notes {
+		keys[i] = n.Key
+	}
+	return keys
+}
+
+// String names the notes of the chord, such as "C4 E4 G4".
+func (c Chord) String() string {
+	names := make([]string, len(c.Notes))
+	for i, n := range c.Notes {
+		names[i] = NoteName(n.Key)
+	}
+	return strings.Join(names, " ")
+}
+
+// ChordDetector groups the NoteOns of an input that start within a short
+// window of the first into chords, on any channel, for applications that
+// trigger on chords or show what is played. A chord is reported once its
+// window has passed.
+type ChordDetector struct {
+	// MinNotes is the fewest notes reported as a chord. Zero means 1, which
+	// reports single notes too.
+	MinNotes int
+
+	window time.Duration
+	fn     func(Chord)
+
+	mu      sync.Mutex
+	pending *Chord
+	timer   funcTimer
+	clock   Clock
+	stopped bool
+}
+
+// NewChordDetector returns a ChordDetector calling fn with every chord,
+// grouping NoteOns that start within window of the first. A window of
+// zero means DefaultChordWindow.
+func NewChordDetector(window time.Duration, fn func(Chord)) *ChordDetector {
+	if window <= 0 {
+		window = DefaultChordWindow
+	}
+	return &ChordDetector{window: window, fn: fn, clock: SystemClock}
+}
+
+// SetClock sets the clock that times the windows of chords and the
+// messages given to Feed, SystemClock by default.
+func (d *ChordDetector) SetClock(c Clock) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clock = clockOr(c)
+}
+
+// Feed processes a message received now.
+func (d *ChordDetector) Feed(msg []byte) {
+	d.mu.Lock()
+	clock := d.clock
+	d.mu.Unlock()
+	d.FeedAt(msg, clock.Now())
+}
+
+// FeedAt processes a message received at the given time. A chord whose
+// window has passed by then is reported first.
+func (d *ChordDetector) FeedAt(msg []byte, at time.Time) {
+	if !isNoteOn(msg) {
+		return
+	}
+	d.mu.Lock()
+	if d.stopped {
+		d.mu.Unlock()
+		return
+	}
+	var done *Chord
+	if d.pending != nil && at.Sub(d.pending.At) > d.window {
+		done = d.takeLocked()
+	}
+	if d.pending == nil {
+		d.pending = &Chord{At: at}
+		c := d.pending
+		d.timer = afterFunc(d.clock, d.window, func() { d.expire(c) })
+	}
+	d.pending.Notes = append(d.pending.Notes, ChordNote{int(msg[0] & 0x0f), int(msg[1]), int(msg[2])})
+	d.mu.Unlock()
+	d.report(done)
+}
+
+// Callback can be passed to MIDIIn.SetCallback to detect the chords of an
+// input directly.
+func (d *ChordDetector) Callback(m MIDIIn, msg []byte, t float64) {
+	d.Feed(msg)
+}
+
+// Flush reports the chord being gathered, without waiting for its window
+// to pass.
+func (d *ChordDetector) Flush() {
+	d.mu.Lock()
+	c := d.takeLocked()
+	d.mu.Unlock()
+	d.report(c)
+}
+
+// Stop stops the detector, dropping the chord being gathered; no more
+// chords are reported.
+func (d *ChordDetector) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopped = true
+	d.takeLocked()
+}
+
+func (d *ChordDetector) expire(c *Chord) {
+	d.mu.Lock()
+	if d.pending != c {
+		d.mu.Unlock()
+		return
+	}
+	c = d.takeLocked()
+	d.mu.Unlock()
+	d.report(c)
+}
+
+// takeLocked returns the chord being gathered, sorted, and starts over.
+func (d *ChordDetector) takeLocked() *Chord {
+	c := d.pending
+	if c == nil {
+		return nil
+	}
+	d.pending = nil
+	d.timer.Stop()
+	sort.SliceStable(c.Notes, func(i, j int) bool { return c.Notes[i].Key < c.Notes[j].Key })
+	return c
+}
+
+func (d *ChordDetector) report(c *Chord) {
+	if c == nil || len(c.Notes) < d.MinNotes || d.fn == nil {
+		return
+	}
+	d.fn(*c)
+}
@@ -0,0 +1,65 @@
+package rtmidi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestChordDetector(t *testing.T) {
+	ms := time.Millisecond
+	found := make(chan Chord, 10)
+	d := NewChordDetector(30*ms, func(c Chord) { found <- c })
+	d.MinNotes = 2
+	t0 := time.Now()
+	for _, ev := range []struct {
+		at  time.Duration
+		msg []byte
+	}{
+		{0, []byte{0x90, 67, 90}},
+		{5 * ms, []byte{0x90, 60, 100}},
+		{10 * ms, []byte{0xb0, 64, 127}},
+		{20 * ms, []byte{0x91, 64, 80}},
+		{40 * ms, []byte{0x80, 60, 0}},
+		{100 * ms, []byte{0x90, 72, 100}},
+		{200 * ms, []byte{0x90, 48, 100}},
+		{210 * ms, []byte{0x90, 55, 100}},
+	} {
+		d.FeedAt(ev.msg, t0.Add(ev.at))
+	}
+	d.Flush()
+	d.Stop()
+	var chords []Chord
+	for len(found) > 0 {
+		chords = append(chords, <-found)
+	}
+	if len(chords) != 2 {
+		t.Fatalf("got chords %v, want 2", chords)
+	}
+	want := []ChordNote{{0, 60, 100}, {1, 64, 80}, {0, 67, 90}}
+	if !reflect.DeepEqual(chords[0].Notes, want) || !chords[0].At.Equal(t0) {
+		t.Errorf("first chord %+v, want %v at start", chords[0], want)
+	}
+	if s := chords[0].String(); s != "C4 E4 G4" {
+		t.Errorf("String() = %q", s)
+	}
+	if keys := chords[1].Keys(); !reflect.DeepEqual(keys, []int{48, 55}) {
+		t.Errorf("second chord keys %v", keys)
+	}
+}
+
+func TestChordDetectorWindow(t *testing.T) {
+	got := make(chan Chord, 1)
+	d := NewChordDetector(10*time.Millisecond, func(c Chord) { got <- c })
+	defer d.Stop()
+	d.Feed([]byte{0x90, 60, 100})
+	d.Feed([]byte{0x90, 64, 100})
+	select {
+	case c := <-got:
+		if len(c.Notes) != 2 {
+			t.Errorf("chord %v, want 2 notes", c)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("chord not reported after its window")
+	}
+}
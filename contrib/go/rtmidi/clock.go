@@ -0,0 +1,284 @@
+package rtmidi
+
+import (
+	"sync"
+	"time"
+)
+
+// PulseSource is implemented by MIDI clocks. Registered functions are called
+// for every timing clock pulse, 24 per quarter note, with the number of the
+// pulse counted from the start of the song. They are called from the clock's
+// own goroutine and must not block. Tempo returns the current tempo in beats
+// per minute, or zero if it is unknown.
+type PulseSource interface {
+	OnPulse(fn func(pulse int)) (cancel func())
+	Tempo() float64
+}
+
+type pulseHub struct {
+	mu   sync.Mutex
+	next int
+	ids  []int
+	fns  []func(int)
+}
+
+func (h *pulseHub) OnPulse(fn func(pulse int)) (cancel func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	id := h.next
+	h.next++
+	h.ids = append(h.ids, id)
+	h.fns = append(h.fns, fn)
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		for i := range h.ids {
+			if h.ids[i] == id {
+				h.ids = append(h.ids[:i:i], h.ids[i+1:]...)
+				h.fns = append(h.fns[:i:i], h.fns[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (h *pulseHub) emit(p int) {
+	h.mu.Lock()
+	fns := h.fns
+	h.mu.Unlock()
+	for _, fn := range fns {
+		fn(p)
+	}
+}
+
+// ClockMaster generates MIDI timing clock at a given tempo, sending it along
+// with transport messages to a MIDIOut, and drives PulseSource listeners.
+type ClockMaster struct {
+	pulseHub
+
+	out   MIDIOut
+	mu    sync.Mutex
+	bpm   float64
+	pulse int
+	rt    Realtime
+	clock Clock
+	stop  chan struct{}
+	done  chan struct{}
+	ctl   chan realtimeRequest
+}
+
+// NewClockMaster returns a stopped ClockMaster sending to out at bpm. out may
+// be nil to only drive local listeners.
+func NewClockMaster(out MIDIOut, bpm float64) *ClockMaster {
+	return &ClockMaster{out: out, bpm: bpm, clock: SystemClock}
+}
+
+// SetClock sets the clock the pulses are timed by, SystemClock by default.
+// It takes effect when the clock is next started or continued.
+func (c *ClockMaster) SetClock(clock Clock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clock = clockOr(clock)
+}
+
+// Tempo returns the current tempo in beats per minute.
+func (c *ClockMaster) Tempo() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bpm
+}
+
+// SetTempo changes the tempo, taking effect from the next pulse.
+func (c *ClockMaster) SetTempo(bpm float64) {
+	if bpm <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bpm = bpm
+}
+
+// Start sends Start and runs the clock from the beginning of the song.
+func (c *ClockMaster) Start() error {
+	c.halt()
+	c.mu.Lock()
+	c.pulse = 0
+	c.mu.Unlock()
+	return c.run(statusStart)
+}
+
+// Continue sends Continue and runs the clock from the current position.
+func (c *ClockMaster) Continue() error {
+	c.halt()
+	return c.run(statusContinue)
+}
+
+// Stop stops the clock and sends Stop.
+func (c *ClockMaster) Stop() error {
+	c.halt()
+	return c.send(statusStop)
+}
+
+// SetRealtime sets how the goroutine sending the clock runs. If the clock
+// is stopped, the settings are applied when it starts, and failing to raise
+// its priority then goes unreported.
+func (c *ClockMaster) SetRealtime(rt Realtime) error {
+	c.mu.Lock()
+	c.rt = rt
+	ctl, done := c.ctl, c.done
+	c.mu.Unlock()
+	if ctl == nil {
+		return nil
+	}
+	return requestRealtime(ctl, done, rt)
+}
+
+// Running reports whether the clock is running.
+func (c *ClockMaster) Running() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stop != nil
+}
+
+// Position returns the number of pulses sent since the start of the song.
+func (c *ClockMaster) Position() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pulse
+}
+
+func (c *ClockMaster) send(status byte) error {
+	if c.out == nil {
+		return nil
+	}
+	return c.out.SendMessage([]byte{status})
+}
+
+func (c *ClockMaster) run(status byte) error {
+	if err := c.send(status); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.stop, c.done = make(chan struct{}), make(chan struct{})
+	c.ctl = make(chan realtimeRequest)
+	stop, done, ctl, rt, clock := c.stop, c.done, c.ctl, c.rt, c.clock
+	c.mu.Unlock()
+	go c.loop(stop, done, ctl, rt, clock)
+	return nil
+}
+
+func (c *ClockMaster) halt() {
+	c.mu.Lock()
+	stop, done := c.stop, c.done
+	c.stop, c.done, c.ctl = nil, nil, nil
+	c.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+func (c *ClockMaster) loop(stop, done chan struct{}, ctl chan realtimeRequest, rt Realtime, clock Clock) {
+	defer close(done)
+	var thread realtimeThread
+	thread.set(rt)
+	next := clock.Now()
+	timer := clock.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case req := <-ctl:
+			req.reply <- thread.set(req.rt)
+			continue
+		case <-timer.C():
+		}
+		c.mu.Lock()
+		p := c.pulse
+		c.pulse++
+		interval := time.Duration(float64(time.Minute) / (c.bpm * ClocksPerQuarter))
+		c.mu.Unlock()
+		c.send(statusClock)
+		c.emit(p)
+		next = next.Add(interval)
+		timer.Reset(next.Sub(clock.Now()))
+	}
+}
+
+// ClockFollower follows the timing clock and transport of an external device
+// and drives PulseSource listeners from it.
+type ClockFollower struct {
+	*TransportFollower
+	pulseHub
+
+	// Smoothing is the weight, between 0 and 1, given to each new clock
+	// interval in the tempo estimate. Zero means 0.1.
+	Smoothing float64
+
+	mu       sync.Mutex
+	clock    Clock
+	last     time.Time
+	interval float64
+}
+
+// NewClockFollower returns a ClockFollower whose transport event stream
+// buffers up to n events.
+func NewClockFollower(n int) *ClockFollower {
+	return &ClockFollower{TransportFollower: NewTransportFollower(n), clock: SystemClock}
+}
+
+// SetClock sets the clock that times the pulses fed, SystemClock by
+// default. A Player following f should use the same clock.
+func (f *ClockFollower) SetClock(c Clock) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clock = clockOr(c)
+}
+
+// Callback can be passed to MIDIIn.SetCallback to follow the input directly.
+func (f *ClockFollower) Callback(m MIDIIn, msg []byte, t float64) {
+	f.Feed(msg)
+}
+
+// Feed processes a received MIDI message.
+func (f *ClockFollower) Feed(msg []byte) {
+	f.mu.Lock()
+	now := f.clock.Now()
+	f.mu.Unlock()
+	f.feedAt(msg, now)
+}
+
+func (f *ClockFollower) feedAt(msg []byte, now time.Time) {
+	if len(msg) > 0 && msg[0] == statusClock {
+		f.mu.Lock()
+		if !f.last.IsZero() {
+			d := float64(now.Sub(f.last))
+			if f.interval == 0 {
+				f.interval = d
+			} else {
+				a := f.Smoothing
+				if a <= 0 || a > 1 {
+					a = 0.1
+				}
+				f.interval += a * (d - f.interval)
+			}
+		}
+		f.last = now
+		f.mu.Unlock()
+	}
+	if p, ok := f.TransportFollower.feed(msg); ok {
+		f.emit(p)
+	}
+}
+
+// Tempo returns the estimated tempo of the incoming clock in beats per minute,
+// or zero if it is not known yet.
+func (f *ClockFollower) Tempo() float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.interval == 0 {
+		return 0
+	}
+	return float64(time.Minute) / (f.interval * ClocksPerQuarter)
+}
@@ -0,0 +1,275 @@
+package rtmidi
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and makes timers for the timing components of the
+// package, such as Scheduler, Player, Recorder, ClockMaster and the
+// detectors, which use SystemClock unless given another with SetClock.
+// Outputs are given one with the SetClock function, and functions waiting
+// on devices, such as SysExRequest, take one as an argument. A FakeClock
+// only moves when told to, so that code built on them can be tested in
+// simulated time, and other implementations can follow an external time
+// source such as JACK transport or MIDI Time Code.
+type Clock interface {
+	Now() time.Time
+	// NewTimer returns a timer sending the time on its channel once d has
+	// passed.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a timer made by a Clock, which works as a time.Timer does.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// SystemClock is the clock of the system, as given by the time package.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time { return t.Timer.C }
+
+// clockOr returns c, or SystemClock if c is nil.
+func clockOr(c Clock) Clock {
+	if c == nil {
+		return SystemClock
+	}
+	return c
+}
+
+// clockSetter is implemented by outputs whose timing can follow a Clock.
+type clockSetter interface {
+	setClock(c Clock)
+}
+
+// SetClock sets the clock timing out, SystemClock by default: the
+// durations of notes started with PlayNote and the times given to
+// SendMessageAt. Messages handed to the scheduler of the backend are then
+// sent after the delay measured on c, in the time of the backend.
+func SetClock(out MIDIOut, c Clock) error {
+	s, ok := out.(clockSetter)
+	if !ok {
+		return errors.New("rtmidi: port does not support setting its clock")
+	}
+	s.setClock(clockOr(c))
+	return nil
+}
+
+// funcTimer is a timer made by afterFunc, which works as one made by
+// time.AfterFunc does.
+type funcTimer interface {
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// afterFunc calls fn in its own goroutine once d has passed on clock.
+func afterFunc(clock Clock, d time.Duration, fn func()) funcTimer {
+	if _, ok := clock.(systemClock); ok {
+		return time.AfterFunc(d, fn)
+	}
+	t := &clockFunc{clock: clock, fn: fn}
+	t.Reset(d)
+	return t
+}
+
+// clockFunc is a funcTimer of a Clock other than SystemClock. Each Reset
+// starts a goroutine waiting for a timer of the clock, which stop cancels.
+type clockFunc struct {
+	clock Clock
+	fn    func()
+
+	mu   sync.Mutex
+	stop chan struct{}
+}
+
+func (t *clockFunc) Stop() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stop == nil {
+		return false
+	}
+	close(t.stop)
+	t.stop = nil
+	return true
+}
+
+func (t *clockFunc) Reset(d time.Duration) bool {
+	active := t.Stop()
+	t.mu.Lock()
+	stop := make(chan struct{})
+	t.stop = stop
+	timer := t.clock.NewTimer(d)
+	t.mu.Unlock()
+	go func() {
+		select {
+		case <-timer.C():
+			t.mu.Lock()
+			fire := t.stop == stop
+			if fire {
+				t.stop = nil
+			}
+			t.mu.Unlock()
+			if fire {
+				t.fn()
+			}
+		case <-stop:
+			timer.Stop()
+		}
+	}()
+	return active
+}
+
+// sleepCtx waits for d to pass on clock or until ctx is done.
+func sleepCtx(ctx context.Context, clock Clock, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := clock.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// resetTimer makes timer fire after d, replacing it with a timer of clock
+// if it belongs to another clock. The clocks a loop goes through are told
+// apart by id, as Clocks need not be comparable. timer may be nil.
+func resetTimer(timer Timer, timerClock int, clock Clock, id int, d time.Duration) (Timer, int) {
+	if timer != nil && timerClock == id {
+		if !timer.Stop() {
+			select {
+			case <-timer.C():
+			default:
+			}
+		}
+		timer.Reset(d)
+		return timer, id
+	}
+	if timer != nil {
+		timer.Stop()
+	}
+	return clock.NewTimer(d), id
+}
+
+// FakeClock is a Clock whose time only moves with Advance, firing the
+// timers that fall due on the way.
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFakeClock returns a FakeClock set to start.
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now returns the time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer returns a timer firing once the clock has advanced by d.
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the clock forward by d. The timers falling due fire in
+// order, each with the clock set to its time.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for {
+		sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].at.Before(c.timers[j].at) })
+		if len(c.timers) == 0 || c.timers[0].at.After(end) {
+			break
+		}
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		if t.at.After(c.now) {
+			c.now = t.at
+		}
+		select {
+		case t.c <- c.now:
+		default:
+		}
+	}
+	c.now = end
+	c.mu.Unlock()
+}
+
+// Timers returns the number of timers waiting to fire, which tells a test
+// that a goroutine has gone back to waiting.
+func (c *FakeClock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// removeLocked removes t from the waiting timers, reporting whether it was
+// waiting.
+func (c *FakeClock) removeLocked(t *fakeTimer) bool {
+	for i, x := range c.timers {
+		if x == t {
+			c.timers = append(c.timers[:i:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	c     chan time.Time
+	at    time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.removeLocked(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	active := c.removeLocked(t)
+	t.at = c.now.Add(d)
+	if d <= 0 {
+		select {
+		case t.c <- c.now:
+		default:
+		}
+		return active
+	}
+	c.timers = append(c.timers, t)
+	return active
+}
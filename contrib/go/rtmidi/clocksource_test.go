@@ -0,0 +1,282 @@
+package rtmidi
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// waitTimers waits until n timers of c are waiting, that is until the
+// goroutines using c have gone back to waiting.
+func waitTimers(t *testing.T, c *FakeClock, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for c.Timers() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d timers waiting, want %d", c.Timers(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+	a, b := c.NewTimer(2*time.Second), c.NewTimer(time.Second)
+	c.Advance(1500 * time.Millisecond)
+	select {
+	case at := <-b.C():
+		if want := start.Add(time.Second); !at.Equal(want) {
+			t.Errorf("fired at %v, want %v", at, want)
+		}
+	default:
+		t.Error("timer not fired")
+	}
+	select {
+	case <-a.C():
+		t.Error("timer fired early")
+	default:
+	}
+	if !a.Stop() || a.Stop() {
+		t.Error("Stop did not report the timer as active once")
+	}
+	a.Reset(time.Second)
+	if c.Timers() != 1 {
+		t.Errorf("%d timers", c.Timers())
+	}
+	c.Advance(time.Second)
+	if len(a.C()) != 1 || !c.Now().Equal(start.Add(2500*time.Millisecond)) {
+		t.Errorf("after Advance: %d fired, now %v", len(a.C()), c.Now())
+	}
+}
+
+func TestSchedulerFakeClock(t *testing.T) {
+	c := NewFakeClock(time.Now())
+	out := &fakeOut{}
+	s := NewScheduler(out)
+	defer s.Close()
+	s.SetClock(c)
+	waitTimers(t, c, 1)
+
+	s.ScheduleAfter(time.Second, []byte{0x90, 60, 100})
+	s.ScheduleAfter(2*time.Second, []byte{0x80, 60, 0})
+	waitTimers(t, c, 1)
+	c.Advance(999 * time.Millisecond)
+	waitTimers(t, c, 1)
+	if n := len(out.messages()); n != 0 {
+		t.Fatalf("%d messages sent early", n)
+	}
+	c.Advance(time.Millisecond)
+	if got := waitMessages(t, out, 1); len(got) != 1 {
+		t.Fatalf("sent %v", got)
+	}
+	c.Advance(time.Second)
+	waitMessages(t, out, 2)
+}
+
+func TestPlayerFakeClock(t *testing.T) {
+	smf := &SMF{Tempo: NewTempoMap(96, 120), Tracks: []Track{{
+		{Time: 0, Message: []byte{0x90, 60, 100}},
+		{Time: time.Second, Message: []byte{0x80, 60, 0}},
+	}}}
+	c := NewFakeClock(time.Now())
+	out := &fakeOut{}
+	p := NewPlayer(out, smf)
+	defer p.Close()
+	p.SetClock(c)
+	p.Play()
+	waitMessages(t, out, 1)
+	waitTimers(t, c, 1)
+	if pos := p.Position(); pos != 0 {
+		t.Errorf("Position() = %v before the clock moved", pos)
+	}
+	c.Advance(500 * time.Millisecond)
+	waitTimers(t, c, 1)
+	if pos := p.Position(); pos != 1 {
+		t.Errorf("Position() = %v, want 1", pos)
+	}
+	if n := len(out.messages()); n != 1 {
+		t.Errorf("%d messages sent early", n)
+	}
+	c.Advance(500 * time.Millisecond)
+	want := [][]byte{{0x90, 60, 100}, {0x80, 60, 0}}
+	if got := waitMessages(t, out, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("played % x", got)
+	}
+}
+
+func TestClockMasterFakeClock(t *testing.T) {
+	c := NewFakeClock(time.Now())
+	m := NewClockMaster(nil, 125)
+	m.SetClock(c)
+	pulses := make(chan int, 100)
+	m.OnPulse(func(p int) { pulses <- p })
+	m.Start()
+	defer m.Stop()
+	<-pulses
+	// At 125 BPM a pulse is 20ms.
+	for i := 0; i < 24; i++ {
+		waitTimers(t, c, 1)
+		c.Advance(20 * time.Millisecond)
+		<-pulses
+	}
+	if p := m.Position(); p != 25 {
+		t.Errorf("Position() = %d after a quarter note", p)
+	}
+}
+
+func TestRecorderFakeClock(t *testing.T) {
+	c := NewFakeClock(time.Now())
+	r := NewRecorder()
+	r.SetClock(c)
+	r.Start()
+	c.Advance(250 * time.Millisecond)
+	r.Feed([]byte{0x90, 60, 100})
+	if tr := r.Stop(); len(tr) != 1 || tr[0].Time != 250*time.Millisecond {
+		t.Errorf("recorded %v", tr)
+	}
+}
+
+func TestClockFollowerFakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	f := NewClockFollower(4)
+	f.SetClock(clock)
+	f.Feed([]byte{0xfa})
+	for i := 0; i < 24; i++ {
+		f.Feed([]byte{0xf8})
+		clock.Advance(time.Second / 48)
+	}
+	if bpm := f.Tempo(); math.Abs(bpm-120) > 0.01 {
+		t.Errorf("tempo %v, want 120", bpm)
+	}
+}
+
+// advanceUntil advances c in steps of d until out has n messages, returning
+// how far it advanced.
+func advanceUntil(t *testing.T, c *FakeClock, out *fakeOut, n int, d time.Duration) time.Duration {
+	t.Helper()
+	var total time.Duration
+	deadline := time.Now().Add(2 * time.Second)
+	for len(out.messages()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d messages sent, want %d", len(out.messages()), n)
+		}
+		c.Advance(d)
+		total += d
+		time.Sleep(time.Millisecond)
+	}
+	return total
+}
+
+func TestSequencerFakeClock(t *testing.T) {
+	c := NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	out := &fakeOut{}
+	sched := NewScheduler(out)
+	defer sched.Close()
+	sched.SetClock(c)
+	seq := NewSequencer(sched)
+	seq.Chain(&Pattern{Steps: []Step{{Note: 60, Velocity: 100}}, StepLength: GridQuarter})
+	src := &fakePulses{bpm: 120}
+	seq.Attach(src)
+	defer seq.Detach()
+	src.emit(0)
+	// The NoteOff ends half of a quarter note at 120 BPM later.
+	if d := advanceUntil(t, c, out, 2, 10*time.Millisecond); d < 250*time.Millisecond {
+		t.Errorf("NoteOff sent after %v", d)
+	}
+}
+
+func TestPlayNoteFakeClock(t *testing.T) {
+	c := NewFakeClock(time.Now())
+	out := &fakeOut{}
+	if err := SetClock(out, c); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PlayNote(out, 0, 60, 100, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	c.Advance(999 * time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	if n := len(out.messages()); n != 1 {
+		t.Fatalf("%d messages sent before the note ended", n)
+	}
+	c.Advance(time.Millisecond)
+	if got := waitMessages(t, out, 2); len(got) != 2 || got[1][0] != 0x80 {
+		t.Errorf("sent % x", got)
+	}
+}
+
+func TestSendMessageAtFakeClock(t *testing.T) {
+	c := NewFakeClock(time.Now())
+	out := &fakeOut{}
+	defer out.Close()
+	SetClock(out, c)
+	if err := SendMessageAt(out, []byte{0xfa}, c.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if d := advanceUntil(t, c, out, 1, 100*time.Millisecond); d < time.Second {
+		t.Errorf("sent after %v", d)
+	}
+}
+
+func TestIdleDetectorFakeClock(t *testing.T) {
+	c := NewFakeClock(time.Now())
+	idle := make(chan struct{}, 1)
+	d := NewIdleDetector(time.Second, func() { idle <- struct{}{} }, nil)
+	defer d.Stop()
+	d.SetClock(c)
+	c.Advance(time.Second)
+	select {
+	case <-idle:
+	case <-time.After(2 * time.Second):
+		t.Fatal("not idle after the timeout")
+	}
+}
+
+func TestChordDetectorFakeClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+	chords := make(chan Chord, 1)
+	d := NewChordDetector(0, func(ch Chord) { chords <- ch })
+	d.SetClock(c)
+	d.Feed([]byte{0x90, 60, 100})
+	d.Feed([]byte{0x90, 64, 100})
+	c.Advance(DefaultChordWindow)
+	select {
+	case ch := <-chords:
+		if !ch.At.Equal(start) || len(ch.Notes) != 2 {
+			t.Errorf("chord %v at %v", ch, ch.At)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("chord not reported after its window")
+	}
+}
+
+func TestTapTempoFakeClock(t *testing.T) {
+	c := NewFakeClock(time.Now())
+	tt := NewTapTempo()
+	tt.SetClock(c)
+	for i := 0; i < 3; i++ {
+		tt.Tap()
+		c.Advance(500 * time.Millisecond)
+	}
+	if bpm := tt.BPM(); math.Abs(bpm-120) > 0.01 {
+		t.Errorf("tempo %v, want 120", bpm)
+	}
+}
+
+func TestSysExRequestFakeClock(t *testing.T) {
+	c := NewFakeClock(time.Now())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := SysExRequest(context.Background(), c, &fakeIn{}, &fakeOut{}, []byte{0xf0, 0xf7}, nil, time.Second)
+		errs <- err
+	}()
+	waitTimers(t, c, 1)
+	c.Advance(time.Second)
+	if err := <-errs; err != ErrTimeout {
+		t.Errorf("SysExRequest = %v, want ErrTimeout", err)
+	}
+}
@@ -0,0 +1,248 @@
+package rtmidi
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultMaxSysEx is the longest SysEx message a Decoder accepts unless
+// told otherwise.
+const DefaultMaxSysEx = 64 * 1024
+
+// ValidateMessage reports whether msg is a single complete MIDI message: a
+// status byte followed by the right number of data bytes, or a SysEx
+// message ending in F7.
+func ValidateMessage(msg []byte) error {
+	if len(msg) == 0 {
+		return errors.New("rtmidi: empty message")
+	}
+	status := msg[0]
+	if status < 0x80 {
+		return fmt.Errorf("rtmidi: message starts with data byte %02X", status)
+	}
+	if status == 0xf0 {
+		if len(msg) < 2 || msg[len(msg)-1] != 0xf7 {
+			return errors.New("rtmidi: unterminated SysEx")
+		}
+		for _, b := range msg[1 : len(msg)-1] {
+			if b >= 0x80 {
+				return fmt.Errorf("rtmidi: status byte %02X inside SysEx", b)
+			}
+		}
+		return nil
+	}
+	n := messageLen(status)
+	if n == 0 {
+		return fmt.Errorf("rtmidi: undefined status byte %02X", status)
+	}
+	if len(msg) != n {
+		return fmt.Errorf("rtmidi: %s with %d bytes, want %d", FormatMessage(msg[:1]), len(msg), n)
+	}
+	for _, b := range msg[1:] {
+		if b >= 0x80 {
+			return fmt.Errorf("rtmidi: status byte %02X in place of data", b)
+		}
+	}
+	return nil
+}
+
+// messageLen returns the length of a message other than SysEx with the
+// given status byte, or 0 if the status is undefined or F7.
+func messageLen(status byte) int {
+	switch {
+	case status < 0x80:
+		return 0
+	case status < 0xf0:
+		return channelMessageLen(status)
+	case status == 0xf1, status == 0xf3:
+		return 2
+	case status == 0xf2:
+		return 3
+	case status == 0xf6, status == 0xf8, status >= 0xfa && status != 0xfd:
+		return 1
+	}
+	return 0
+}
+
+// Decoder splits a MIDI byte stream, as read from a serial port or a raw
+// device, into complete messages. It follows running status, passes
+// realtime messages interleaved with other messages on at once, and drops
+// what it cannot make sense of: data bytes without a status, undefined
+// status bytes, messages cut short by a new status and SysEx longer than
+// MaxSysEx. Every message it returns passes ValidateMessage, and
+// re-encoding its messages with an Encoder and decoding them again gives
+// the same messages.
+//
+// The zero value is ready to use.
+type Decoder struct {
+	// MaxSysEx is the longest SysEx message accepted, including F0 and F7.
+	// Zero means DefaultMaxSysEx.
+	MaxSysEx int
+
+	running byte
+	buf     []byte
+	skip    bool
+	dropped int
+}
+
+// Decode consumes b and returns the messages it completes. A message may
+// span several calls. The returned messages do not share memory with b or
+// with each other.
+func (d *Decoder) Decode(b []byte) [][]byte {
+	var msgs [][]byte
+	for _, c := range b {
+		if msg := d.decodeByte(c); msg != nil {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+func (d *Decoder) decodeByte(c byte) []byte {
+	switch {
+	case c >= 0xf8:
+		// Realtime messages may appear anywhere, even inside SysEx.
+		if messageLen(c) == 0 {
+			d.dropped++
+			return nil
+		}
+		return []byte{c}
+	case c == 0xf7:
+		if len(d.buf) == 0 || d.buf[0] != 0xf0 {
+			d.abandon()
+			d.running = 0
+			d.dropped++
+			return nil
+		}
+		msg := append(d.buf, c)
+		d.buf = nil
+		if d.skip {
+			d.skip = false
+			d.dropped += len(msg)
+			return nil
+		}
+		return msg
+	case c >= 0x80:
+		d.abandon()
+		if c < 0xf0 {
+			d.running = c
+		} else {
+			d.running = 0
+		}
+		switch n := messageLen(c); {
+		case c == 0xf0:
+			d.buf = []byte{c}
+		case n == 0:
+			d.dropped++
+		case n == 1:
+			return []byte{c}
+		default:
+			d.buf = []byte{c}
+		}
+		return nil
+	}
+
+	if len(d.buf) == 0 {
+		if d.running == 0 {
+			d.dropped++
+			return nil
+		}
+		d.buf = []byte{d.running}
+	}
+	if d.buf[0] == 0xf0 {
+		max := d.MaxSysEx
+		if max <= 0 {
+			max = DefaultMaxSysEx
+		}
+		if d.skip || len(d.buf)+2 > max {
+			d.skip = true
+			d.dropped++
+			return nil
+		}
+		d.buf = append(d.buf, c)
+		return nil
+	}
+	d.buf = append(d.buf, c)
+	if len(d.buf) < messageLen(d.buf[0]) {
+		return nil
+	}
+	msg := d.buf
+	d.buf = nil
+	return msg
+}
+
+// abandon drops a message cut short by a status byte.
+func (d *Decoder) abandon() {
+	if len(d.buf) == 0 {
+		return
+	}
+	// Skipped SysEx bytes were counted as they came.
+	d.dropped += len(d.buf)
+	d.buf, d.skip = nil, false
+}
+
+// Dropped returns the number of bytes dropped so far.
+func (d *Decoder) Dropped() int {
+	return d.dropped
+}
+
+// Reset discards a partly decoded message and the running status, as after
+// reconnecting to a device.
+func (d *Decoder) Reset() {
+	d.running, d.buf, d.skip = 0, nil, false
+}
+
+// Encoder writes messages as a MIDI byte stream. The zero value writes
+// every status byte.
+type Encoder struct {
+	// RunningStatus leaves out the status byte of a channel message when it
+	// repeats the one before.
+	RunningStatus bool
+
+	running byte
+}
+
+// Append appends msg to dst as stream bytes. It returns an error, leaving
+// dst as it was, if msg fails ValidateMessage.
+func (e *Encoder) Append(dst, msg []byte) ([]byte, error) {
+	if err := ValidateMessage(msg); err != nil {
+		return dst, err
+	}
+	status := msg[0]
+	switch {
+	case status >= 0xf8:
+		// Realtime messages leave running status alone.
+	case status >= 0xf0:
+		e.running = 0
+	case e.RunningStatus && status == e.running:
+		return append(dst, msg[1:]...), nil
+	default:
+		e.running = status
+	}
+	return append(dst, msg...), nil
+}
+
+// Reset makes the next channel message start with its status byte.
+func (e *Encoder) Reset() {
+	e.running = 0
+}
+
+// CodecSeedCorpus returns byte streams exercising the corners of the MIDI
+// stream format: running status, realtime bytes inside other messages,
+// interrupted and unterminated SysEx, stray data and undefined status
+// bytes. The package's fuzz tests start from them, and programs fuzzing
+// their own MIDI parsing can add them to their corpus.
+func CodecSeedCorpus() [][]byte {
+	return [][]byte{
+		{0x90, 60, 100, 64, 100, 60, 0},
+		{0x90, 60, 0xf8, 100, 0xb0, 7},
+		{0xf0, 0x7e, 0x7f, 0x06, 0x01, 0xf7},
+		{0xf0, 0x41, 0xfe, 0x10, 0xf7, 0xc0, 5, 6},
+		{0xf0, 0x43, 0x10, 0x90, 60, 100},
+		{0x40, 0x40, 0xf7, 0xf4, 0xf5, 0xf9, 0xfd},
+		{0xf2, 0x10, 0x20, 0xf1, 0x33, 0xf6, 0x22},
+		{0xe0, 0x00, 0x40, 0x7f, 0x7f, 0xd0, 0x10, 0x20},
+		{0xfa, 0xf8, 0xf8, 0xfc, 0xff},
+		{},
+	}
+}
@@ -0,0 +1,133 @@
+package rtmidi
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestValidateMessage(t *testing.T) {
+	for _, test := range []struct {
+		msg []byte
+		ok  bool
+	}{
+		{[]byte{0x90, 60, 100}, true},
+		{[]byte{0xc0, 5}, true},
+		{[]byte{0xf0, 0x7e, 0xf7}, true},
+		{[]byte{0xf8}, true},
+		{[]byte{0xf2, 1, 2}, true},
+		{nil, false},
+		{[]byte{60, 100}, false},
+		{[]byte{0x90, 60}, false},
+		{[]byte{0xc0, 5, 6}, false},
+		{[]byte{0x90, 60, 0x90}, false},
+		{[]byte{0xf0, 0x7e}, false},
+		{[]byte{0xf0, 0x90, 0xf7}, false},
+		{[]byte{0xf4}, false},
+		{[]byte{0xf7}, false},
+	} {
+		if err := ValidateMessage(test.msg); (err == nil) != test.ok {
+			t.Errorf("ValidateMessage(% X) = %v", test.msg, err)
+		}
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	for _, test := range []struct {
+		in      []byte
+		want    string
+		dropped int
+	}{
+		{[]byte{0x90, 60, 100, 64, 100}, "[90 3C 64][90 40 64]", 0},
+		{[]byte{0x90, 60, 0xf8, 100}, "[F8][90 3C 64]", 0},
+		{[]byte{0xf0, 0x7e, 0xfe, 0x01, 0xf7, 0xc0, 5, 6}, "[FE][F0 7E 01 F7][C0 05][C0 06]", 0},
+		{[]byte{0xf0, 0x43, 0x10, 0x90, 60, 100}, "[90 3C 64]", 3},
+		{[]byte{0x40, 0x40, 0xf7, 0xf4, 0xf9, 0x90, 1, 2}, "[90 01 02]", 5},
+		{[]byte{0xb0, 7, 0xf1, 0x33, 0x22}, "[F1 33]", 3},
+		{[]byte{0xb0, 7, 0xf7, 0x10}, "", 4},
+		{[]byte{0xf6, 0xe0, 0, 0x40}, "[F6][E0 00 40]", 0},
+	} {
+		var d Decoder
+		got := ""
+		for _, msg := range d.Decode(test.in) {
+			got += fmt.Sprintf("[% X]", msg)
+		}
+		if got != test.want || d.Dropped() != test.dropped {
+			t.Errorf("Decode(% X) = %s, %d dropped, want %s, %d dropped", test.in, got, d.Dropped(), test.want, test.dropped)
+		}
+	}
+}
+
+func TestDecoderMaxSysEx(t *testing.T) {
+	d := Decoder{MaxSysEx: 4}
+	msgs := d.Decode([]byte{0xf0, 1, 2, 0xf7, 0xf0, 1, 2, 3, 0xf7, 0xf8})
+	if len(msgs) != 2 || len(msgs[0]) != 4 || msgs[1][0] != 0xf8 || d.Dropped() != 5 {
+		t.Errorf("got % X, %d dropped", msgs, d.Dropped())
+	}
+}
+
+func TestDecoderSplit(t *testing.T) {
+	var d Decoder
+	var msgs [][]byte
+	for _, c := range []byte{0xf0, 1, 0xf7, 0x90, 60, 100, 61, 100} {
+		msgs = append(msgs, d.Decode([]byte{c})...)
+	}
+	if len(msgs) != 3 {
+		t.Errorf("got % X", msgs)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	e := Encoder{RunningStatus: true}
+	var b []byte
+	var err error
+	for _, msg := range [][]byte{{0x90, 60, 100}, {0xf8}, {0x90, 60, 0}, {0xf2, 0, 0}, {0x90, 61, 1}} {
+		if b, err = e.Append(b, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []byte{0x90, 60, 100, 0xf8, 60, 0, 0xf2, 0, 0, 0x90, 61, 1}
+	if !bytes.Equal(b, want) {
+		t.Errorf("encoded % X, want % X", b, want)
+	}
+	if b2, err := e.Append(b, []byte{0x90, 60}); err == nil || len(b2) != len(b) {
+		t.Error("invalid message encoded")
+	}
+}
+
+// checkCodec checks the Decoder's guarantees on a stream.
+func checkCodec(t *testing.T, stream []byte) {
+	var d Decoder
+	msgs := d.Decode(stream)
+	e := Encoder{RunningStatus: len(stream)%2 == 0}
+	var enc []byte
+	for _, msg := range msgs {
+		var err error
+		if enc, err = e.Append(enc, msg); err != nil {
+			t.Fatalf("Decode(% X) returned invalid message: %v", stream, err)
+		}
+	}
+	var d2 Decoder
+	again := d2.Decode(enc)
+	if len(again) != len(msgs) || d2.Dropped() != 0 {
+		t.Fatalf("round trip of % X: % X, then % X", stream, msgs, again)
+	}
+	for i := range msgs {
+		if !bytes.Equal(msgs[i], again[i]) {
+			t.Fatalf("round trip of % X: % X, then % X", stream, msgs, again)
+		}
+	}
+}
+
+func TestCodecSeedCorpus(t *testing.T) {
+	for _, stream := range CodecSeedCorpus() {
+		checkCodec(t, stream)
+	}
+}
+
+func FuzzDecoder(f *testing.F) {
+	for _, stream := range CodecSeedCorpus() {
+		f.Add(stream)
+	}
+	f.Fuzz(checkCodec)
+}
@@ -0,0 +1,93 @@
+package rtmidi
+
+import (
+	"context"
+	"time"
+)
+
+// CountIn counts a performer in: it waits for the next bar to start on src,
+// lets bars bars go by and returns the time of the downbeat that follows.
+// If m is not nil it is attached to src to click through the count-in, and
+// is left attached so that it keeps time during the take; its time
+// signature sets the length of a bar, which is otherwise 4/4. src must be
+// running, or be started by the caller while CountIn waits. The downbeat
+// is timed by clock, SystemClock if nil, which should be the clock of
+// whatever the time is given to.
+func CountIn(ctx context.Context, clock Clock, src PulseSource, m *Metronome, bars int) (time.Time, error) {
+	clock = clockOr(clock)
+	num, den := 4, 4
+	if m != nil {
+		m.Attach(src)
+		num, den = m.TimeSignature()
+	}
+	perBar := num * 4 * ClocksPerQuarter / den
+	end := make(chan time.Time, 1)
+	first := -1
+	cancel := src.OnPulse(func(pulse int) {
+		if first < 0 {
+			if !barStart(m, pulse, perBar) {
+				return
+			}
+			first = pulse
+		}
+		if pulse-first >= bars*perBar {
+			select {
+			case end <- clock.Now():
+			default:
+			}
+		}
+	})
+	defer cancel()
+	select {
+	case t := <-end:
+		return t, nil
+	case <-ctx.Done():
+		return time.Time{}, ctx.Err()
+	}
+}
+
+// barStart reports whether pulse starts a bar of m, or of 4/4 if m is nil.
+func barStart(m *Metronome, pulse, perBar int) bool {
+	if m == nil {
+		return pulse%perBar == 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return pulse >= m.sigStart && (pulse-m.sigStart)%perBar == 0
+}
+
+// StartAfterCountIn counts in with CountIn and starts recording on the
+// downbeat that ends the count-in, so that nothing played during the
+// count-in is recorded and the take starts on a bar. The downbeat is timed
+// by the clock of the recorder.
+func (r *Recorder) StartAfterCountIn(ctx context.Context, src PulseSource, m *Metronome, bars int) error {
+	r.mu.Lock()
+	clock := r.clock
+	r.mu.Unlock()
+	t, err := CountIn(ctx, clock, src, m, bars)
+	if err != nil {
+		return err
+	}
+	r.StartAt(t)
+	return nil
+}
+
+// PlayFrom starts playing preroll quarter notes before beats, so that a
+// performer hears the lead-in before a punch-in, and returns the time at
+// which playback will reach beats. Passing it to Recorder.StartAt records
+// from the punch-in point. It uses the player's own clock; while following
+// an external clock the position is set by the clock instead.
+func (p *Player) PlayFrom(beats, preroll float64) time.Time {
+	from := beats - preroll
+	if from < 0 {
+		from = 0
+	}
+	p.Stop()
+	p.Locate(from)
+	p.Play()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ppqn := float64(p.tempo.PPQN)
+	d := p.tempo.Duration(int(beats*ppqn)) - p.tempo.Duration(int(from*ppqn))
+	return p.startTime.Add(d)
+}
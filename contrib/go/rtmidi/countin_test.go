@@ -0,0 +1,119 @@
+package rtmidi
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// manualPulses is a PulseSource driven by the test.
+type manualPulses struct {
+	pulseHub
+}
+
+func (s *manualPulses) Tempo() float64 { return 120 }
+
+func (s *manualPulses) listeners() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.fns)
+}
+
+func TestCountIn(t *testing.T) {
+	out := &fakeOut{}
+	m := NewMetronome(out)
+	m.SetTimeSignature(3, 4)
+	m.Pulse(0)
+	m.Pulse(96)
+	src := &manualPulses{}
+
+	rec := NewRecorder()
+	errc := make(chan error)
+	go func() { errc <- rec.StartAfterCountIn(context.Background(), src, m, 2) }()
+	for src.listeners() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	// Starts counting at the bar starting on pulse 168, in 3/4.
+	for p := 100; p < 168+2*72; p++ {
+		src.emit(p)
+		rec.Feed([]byte{0x90, 60, 100})
+	}
+	select {
+	case <-errc:
+		t.Fatal("count-in ended early")
+	case <-time.After(10 * time.Millisecond):
+	}
+	src.emit(168 + 2*72)
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if !rec.Recording() || len(rec.Track()) != 0 {
+		t.Errorf("recording %v with %d events from the count-in", rec.Recording(), len(rec.Track()))
+	}
+	rec.Feed([]byte{0x90, 62, 100})
+	if len(rec.Track()) != 1 {
+		t.Error("take not recorded after the count-in")
+	}
+
+	clicks := 0
+	for _, msg := range out.messages() {
+		if isNoteOn(msg) {
+			clicks++
+		}
+	}
+	// Two from the set-up pulses, then every beat from pulse 120 to 312.
+	if clicks != 2+9 {
+		t.Errorf("%d clicks", clicks)
+	}
+}
+
+func TestCountInFakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	rec := NewRecorder()
+	rec.SetClock(clock)
+	src := &manualPulses{}
+	errc := make(chan error)
+	go func() { errc <- rec.StartAfterCountIn(context.Background(), src, nil, 1) }()
+	for src.listeners() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	// One bar of 4/4 at 120 BPM.
+	for p := 0; p < 96; p++ {
+		src.emit(p)
+		clock.Advance(time.Second / 48)
+	}
+	src.emit(96)
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Second)
+	rec.Feed([]byte{0x90, 60, 100})
+	if got := rec.Track(); len(got) != 1 || got[0].Time != time.Second {
+		t.Errorf("recorded %v, want a note at 1s", got)
+	}
+}
+
+func TestCountInCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := CountIn(ctx, nil, &manualPulses{}, nil, 1); err != context.Canceled {
+		t.Errorf("CountIn returned %v", err)
+	}
+}
+
+func TestPlayerPlayFrom(t *testing.T) {
+	smf := &SMF{Tempo: NewTempoMap(96, 600), Tracks: []Track{{
+		{Time: 0, Message: []byte{0x90, 60, 100}},
+		{Time: 200 * time.Millisecond, Message: []byte{0x80, 60, 0}},
+	}}}
+	p := NewPlayer(&fakeOut{}, smf)
+	defer p.Close()
+	start := time.Now()
+	punch := p.PlayFrom(2, 1)
+	if d := punch.Sub(start); d < 90*time.Millisecond || d > 150*time.Millisecond {
+		t.Errorf("punch-in %v after start, want 100ms", d)
+	}
+	if pos := p.Position(); pos < 1 || pos > 2 {
+		t.Errorf("playing from %v, want 1", pos)
+	}
+}
@@ -0,0 +1,203 @@
+package rtmidi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// compiledAPI lists the APIs Diagnose checks, replaced in tests.
+var compiledAPI = CompiledAPI
+
+// diagnoseClient names the clients and virtual port made by Diagnose.
+const diagnoseClient = "RtMidi Diagnose"
+
+// DiagnoseTimeout is how long Diagnose waits for a message to come back
+// through a virtual port when measuring loopback latency.
+var DiagnoseTimeout = time.Second
+
+// Report describes the MIDI environment, as found by Diagnose.
+type Report struct {
+	// At is when the report was made.
+	At time.Time
+	// OS and Arch are those the program was built for.
+	OS, Arch string
+	// APIs are the compiled APIs, in the order of CompiledAPI. There are
+	// none if the package was built without a backend.
+	APIs []APIReport
+}
+
+// APIReport describes what works with an API.
+type APIReport struct {
+	API API
+	// Err is the error creating an input or output client, after which
+	// nothing else is checked.
+	Err error
+	// Inputs and Outputs are the names of the ports listed, and PortErr
+	// the first error listing them.
+	Inputs, Outputs []string
+	PortErr         error
+	// Virtual tells whether a virtual input port could be opened, and
+	// VirtualErr why not.
+	Virtual    bool
+	VirtualErr error
+	// Latency is the time a message took to go from an output to the
+	// virtual input, if it could be measured, and LatencyErr why not.
+	Latency    time.Duration
+	LatencyErr error
+}
+
+// OK reports whether the API found no error.
+func (r APIReport) OK() bool {
+	return r.Err == nil && r.PortErr == nil && r.VirtualErr == nil && r.LatencyErr == nil
+}
+
+// Diagnose checks the MIDI environment, for first-run checks and support
+// requests: for each compiled API it creates an input and an output
+// client, lists their ports, opens a virtual input port and measures the
+// latency of a message sent to it through the output, where the API has
+// virtual ports. It opens no existing port.
+func Diagnose() Report {
+	r := Report{At: time.Now(), OS: runtime.GOOS, Arch: runtime.GOARCH}
+	for _, api := range compiledAPI() {
+		r.APIs = append(r.APIs, diagnoseAPI(api))
+	}
+	return r
+}
+
+func diagnoseAPI(api API) APIReport {
+	r := APIReport{API: api}
+	in, err := newMIDIIn(api, diagnoseClient, DefaultQueueSize)
+	if err != nil {
+		r.Err = fmt.Errorf("rtmidi: creating input: %w", err)
+		return r
+	}
+	defer in.Close()
+	out, err := newMIDIOut(api, diagnoseClient)
+	if err != nil {
+		r.Err = fmt.Errorf("rtmidi: creating output: %w", err)
+		return r
+	}
+	defer out.Close()
+
+	var inErr, outErr error
+	r.Inputs, inErr = portNames(in)
+	r.Outputs, outErr = portNames(out)
+	if r.PortErr = inErr; r.PortErr == nil {
+		r.PortErr = outErr
+	}
+
+	if err := in.OpenVirtualPort(diagnoseClient); err != nil {
+		r.VirtualErr = err
+		return r
+	}
+	r.Virtual = true
+	r.Latency, r.LatencyErr = loopbackLatency(in, out)
+	return r
+}
+
+// portNames lists the names of the ports of m.
+func portNames(m Port) ([]string, error) {
+	n, err := m.PortCount()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		name, err := m.PortName(i)
+		if err != nil {
+			return names, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// loopbackLatency connects out to the virtual port of in and times a
+// message sent through it.
+func loopbackLatency(in MIDIIn, out MIDIOut) (time.Duration, error) {
+	names, err := portNames(out)
+	if err != nil {
+		return 0, err
+	}
+	port := -1
+	for i, name := range names {
+		if strings.Contains(name, diagnoseClient) {
+			port = i
+			break
+		}
+	}
+	if port < 0 {
+		return 0, errors.New("rtmidi: virtual port not listed by the output")
+	}
+	if err := out.OpenPort(port, diagnoseClient); err != nil {
+		return 0, err
+	}
+	// A NoteOff of key 0 on channel 16 is the least likely message to be
+	// heard should it reach an instrument.
+	probe := []byte{0x8f, 0, 0}
+	arrived := make(chan time.Time, 1)
+	err = in.SetCallback(func(_ MIDIIn, msg []byte, _ float64) {
+		if bytes.Equal(msg, probe) {
+			select {
+			case arrived <- time.Now():
+			default:
+			}
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer in.CancelCallback()
+	sent := time.Now()
+	if err := out.SendMessage(probe); err != nil {
+		return 0, err
+	}
+	select {
+	case at := <-arrived:
+		return at.Sub(sent), nil
+	case <-time.After(DiagnoseTimeout):
+		return 0, fmt.Errorf("rtmidi: no loopback message within %v", DiagnoseTimeout)
+	}
+}
+
+// String describes the report on several lines, to be pasted into a
+// support request.
+func (r Report) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "rtmidi diagnosis %s, %s/%s", r.At.Format(time.RFC3339), r.OS, r.Arch)
+	if len(r.APIs) == 0 {
+		b.WriteString("\nno API compiled")
+	}
+	for _, a := range r.APIs {
+		fmt.Fprintf(&b, "\n%v:", a.API)
+		if a.Err != nil {
+			fmt.Fprintf(&b, " %v", a.Err)
+			continue
+		}
+		fmt.Fprintf(&b, "\n  inputs: %s", portList(a.Inputs))
+		fmt.Fprintf(&b, "\n  outputs: %s", portList(a.Outputs))
+		if a.PortErr != nil {
+			fmt.Fprintf(&b, "\n  listing ports: %v", a.PortErr)
+		}
+		switch {
+		case a.VirtualErr != nil:
+			fmt.Fprintf(&b, "\n  virtual ports: %v", a.VirtualErr)
+		case a.LatencyErr != nil:
+			fmt.Fprintf(&b, "\n  virtual ports: ok\n  loopback: %v", a.LatencyErr)
+		default:
+			fmt.Fprintf(&b, "\n  virtual ports: ok\n  loopback latency: %v", a.Latency)
+		}
+	}
+	return b.String()
+}
+
+func portList(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%q", names)
+}
@@ -0,0 +1,81 @@
+package rtmidi
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// nonVirtualIn is an input of an API without virtual ports.
+type nonVirtualIn struct {
+	fakeIn
+}
+
+func (n *nonVirtualIn) OpenVirtualPort(name string) error { return errors.New("not supported") }
+
+func TestDiagnose(t *testing.T) {
+	defer func() { compiledAPI, newMIDIIn, newMIDIOut = CompiledAPI, NewMIDIIn, NewMIDIOut }()
+	compiledAPI = func() []API { return []API{APILinuxALSA, APIUnixJack, APIDummy} }
+	var loopback *fakeIn
+	newMIDIIn = func(api API, client string, size int) (MIDIIn, error) {
+		switch api {
+		case APIUnixJack:
+			return nil, errors.New("JACK server not running")
+		case APIDummy:
+			return &nonVirtualIn{}, nil
+		}
+		loopback = &fakeIn{}
+		return loopback, nil
+	}
+	newMIDIOut = func(api API, client string) (MIDIOut, error) {
+		out := &namedPorts{names: []string{"Midi Through:Midi Through Port-0 14:0"}}
+		if api == APILinuxALSA {
+			out.names = append(out.names, "RtMidi Diagnose:RtMidi Diagnose 128:0")
+			out.onSend = func(msg []byte) { loopback.deliver(msg) }
+		}
+		return out, nil
+	}
+
+	r := Diagnose()
+	if len(r.APIs) != 3 {
+		t.Fatalf("%d APIs reported", len(r.APIs))
+	}
+	alsa, jack, dummy := r.APIs[0], r.APIs[1], r.APIs[2]
+	if !alsa.OK() || !alsa.Virtual || len(alsa.Outputs) != 2 {
+		t.Errorf("alsa %+v", alsa)
+	}
+	if jack.OK() || jack.Err == nil {
+		t.Errorf("jack %+v", jack)
+	}
+	if dummy.Virtual || dummy.VirtualErr == nil || dummy.PortErr != nil {
+		t.Errorf("dummy %+v", dummy)
+	}
+
+	s := r.String()
+	for _, want := range []string{
+		"alsa:\n  inputs: none\n  outputs:",
+		"loopback latency:",
+		"jack: rtmidi: creating input: JACK server not running",
+		"dummy:\n  inputs: none",
+		"virtual ports: not supported",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("report lacks %q:\n%s", want, s)
+		}
+	}
+}
+
+func TestDiagnoseNoLoopback(t *testing.T) {
+	defer func() { newMIDIIn, newMIDIOut = NewMIDIIn, NewMIDIOut }()
+	defer func(d time.Duration) { DiagnoseTimeout = d }(DiagnoseTimeout)
+	DiagnoseTimeout = 10 * time.Millisecond
+	newMIDIIn = func(api API, client string, size int) (MIDIIn, error) { return &fakeIn{}, nil }
+	newMIDIOut = func(api API, client string) (MIDIOut, error) {
+		return &namedPorts{names: []string{"RtMidi Diagnose:RtMidi Diagnose 128:0"}}, nil
+	}
+	r := diagnoseAPI(APILinuxALSA)
+	if !r.Virtual || r.LatencyErr == nil {
+		t.Errorf("report %+v", r)
+	}
+}
@@ -0,0 +1,159 @@
+package rtmidi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DiffOptions control how DiffTracks compares events.
+type DiffOptions struct {
+	// Tolerance is how far apart in time two events may be and still match.
+	Tolerance time.Duration
+	// ReleaseVelocity makes NoteOff velocities count. By default a NoteOff
+	// matches any NoteOff of the same key, and a NoteOn with velocity 0 is
+	// taken as a NoteOff.
+	ReleaseVelocity bool
+}
+
+// EventDiff is an event found in only one of the tracks given to
+// DiffTracks.
+type EventDiff struct {
+	Event
+	// Missing is true for an event of the wanted track that was not
+	// received, false for an unexpected event.
+	Missing bool
+}
+
+// String returns the event as a line of a diff, such as
+// "- 1.5s NoteOn ch=1 C4 vel=100".
+func (d EventDiff) String() string {
+	sign := "+"
+	if d.Missing {
+		sign = "-"
+	}
+	return fmt.Sprintf("%s %v %s", sign, d.Time, FormatMessage(d.Message))
+}
+
+// TrackDiff lists the differences between two tracks in time order.
+type TrackDiff []EventDiff
+
+// String returns the differences one per line, or "" if there are none.
+func (d TrackDiff) String() string {
+	var b strings.Builder
+	for _, e := range d {
+		b.WriteString(e.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// DiffTracks compares the musical content of two tracks, returning the
+// events of want not matched in got and the events of got not matched in
+// want. Messages relying on running status are completed with the status
+// of the message before them, and note offs are compared as described for
+// DiffOptions. Events with the same message are matched in order, each to
+// the nearest unmatched event within the tolerance.
+//
+// It is meant for tests of routers and players, which can then report a
+// readable diff:
+//
+//	if d := DiffTracks(want, got, DiffOptions{Tolerance: 5 * time.Millisecond}); len(d) > 0 {
+//		t.Errorf("unexpected output:\n%v", d)
+//	}
+func DiffTracks(want, got Track, opts DiffOptions) TrackDiff {
+	w, g := normalizeDiffTrack(want, opts), normalizeDiffTrack(got, opts)
+	used := make([]bool, len(g))
+	var diff TrackDiff
+	for _, ev := range w {
+		best := -1
+		for j, cand := range g {
+			if used[j] || string(cand.key) != string(ev.key) {
+				continue
+			}
+			d := cand.Time - ev.Time
+			if d < 0 {
+				d = -d
+			}
+			if d > opts.Tolerance {
+				if cand.Time > ev.Time {
+					break
+				}
+				continue
+			}
+			if best < 0 || d < absDuration(g[best].Time-ev.Time) {
+				best = j
+			}
+		}
+		if best < 0 {
+			diff = append(diff, EventDiff{ev.Event, true})
+		} else {
+			used[best] = true
+		}
+	}
+	for j, ev := range g {
+		if !used[j] {
+			diff = append(diff, EventDiff{ev.Event, false})
+		}
+	}
+	sort.SliceStable(diff, func(i, j int) bool { return diff[i].Time < diff[j].Time })
+	return diff
+}
+
+// DiffMessages is like DiffTracks for messages without times, such as
+// those sent to a fake output: messages are matched in order only.
+func DiffMessages(want, got [][]byte, opts DiffOptions) TrackDiff {
+	opts.Tolerance = 0
+	return DiffTracks(untimedTrack(want), untimedTrack(got), opts)
+}
+
+func untimedTrack(msgs [][]byte) Track {
+	t := make(Track, len(msgs))
+	for i, msg := range msgs {
+		t[i].Message = msg
+	}
+	return t
+}
+
+// diffEvent is an event with the message it is compared by.
+type diffEvent struct {
+	Event
+	key []byte
+}
+
+func normalizeDiffTrack(t Track, opts DiffOptions) []diffEvent {
+	sorted := append(Track(nil), t...)
+	sorted.Sort()
+	events := make([]diffEvent, 0, len(sorted))
+	var running byte
+	for _, ev := range sorted {
+		msg := ev.Message
+		switch {
+		case len(msg) == 0:
+		case msg[0] < 0x80 && running != 0:
+			msg = append([]byte{running}, msg...)
+		case msg[0] < 0xf0:
+			running = msg[0]
+		case msg[0] < 0xf8:
+			// System common messages cancel running status.
+			running = 0
+		}
+		key := msg
+		if isNoteOff(msg) {
+			key = []byte{0x80 | msg[0]&0x0f, msg[1], 0}
+			if opts.ReleaseVelocity {
+				key[2] = msg[2]
+			}
+		}
+		events = append(events, diffEvent{Event{ev.Time, msg}, key})
+	}
+	return events
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,72 @@
+package rtmidi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiffTracks(t *testing.T) {
+	ms := time.Millisecond
+	for _, test := range []struct {
+		name      string
+		want, got Track
+		opts      DiffOptions
+		diff      string
+	}{
+		{
+			name: "within tolerance",
+			want: Track{{0, []byte{0x90, 60, 100}}, {100 * ms, []byte{0x80, 60, 0}}},
+			got:  Track{{2 * ms, []byte{0x90, 60, 100}}, {97 * ms, []byte{0x80, 60, 0}}},
+			opts: DiffOptions{Tolerance: 5 * ms},
+		},
+		{
+			name: "outside tolerance",
+			want: Track{{0, []byte{0x90, 60, 100}}},
+			got:  Track{{10 * ms, []byte{0x90, 60, 100}}},
+			opts: DiffOptions{Tolerance: 5 * ms},
+			diff: "- 0s NoteOn ch=1 C4 vel=100\n+ 10ms NoteOn ch=1 C4 vel=100\n",
+		},
+		{
+			name: "note off conventions",
+			want: Track{{0, []byte{0x80, 60, 64}}},
+			got:  Track{{0, []byte{0x90, 60, 0}}},
+		},
+		{
+			name: "release velocity",
+			want: Track{{0, []byte{0x80, 60, 64}}},
+			got:  Track{{0, []byte{0x90, 60, 0}}},
+			opts: DiffOptions{ReleaseVelocity: true},
+			diff: "- 0s NoteOff ch=1 C4 vel=64\n+ 0s NoteOn ch=1 C4 vel=0\n",
+		},
+		{
+			name: "running status",
+			want: Track{{0, []byte{0x90, 60, 100}}, {ms, []byte{0x90, 64, 100}}},
+			got:  Track{{0, []byte{0x90, 60, 100}}, {ms, []byte{64, 100}}},
+		},
+		{
+			name: "wrong value",
+			want: Track{{0, []byte{0xb0, 7, 100}}, {ms, []byte{0xc0, 5}}},
+			got:  Track{{0, []byte{0xb0, 7, 90}}, {ms, []byte{0xc0, 5}}},
+			diff: "- 0s ControlChange ch=1 cc=7 (Volume) value=100\n+ 0s ControlChange ch=1 cc=7 (Volume) value=90\n",
+		},
+		{
+			name: "nearest match",
+			want: Track{{10 * ms, []byte{0xf8}}, {20 * ms, []byte{0xf8}}},
+			got:  Track{{12 * ms, []byte{0xf8}}, {19 * ms, []byte{0xf8}}},
+			opts: DiffOptions{Tolerance: 10 * ms},
+		},
+	} {
+		if d := DiffTracks(test.want, test.got, test.opts).String(); d != test.diff {
+			t.Errorf("%s: diff\n%swant\n%s", test.name, d, test.diff)
+		}
+	}
+}
+
+func TestDiffMessages(t *testing.T) {
+	want := [][]byte{{0x90, 60, 100}, {0x80, 60, 0}}
+	got := [][]byte{{0x90, 60, 100}, {0x90, 60, 0}, {0xb0, 123, 0}}
+	d := DiffMessages(want, got, DiffOptions{})
+	if len(d) != 1 || d[0].Missing || d[0].Message[1] != 123 {
+		t.Errorf("diff\n%v", d)
+	}
+}
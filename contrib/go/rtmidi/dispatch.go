@@ -0,0 +1,170 @@
+package rtmidi
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+type ringEntry struct {
+	msg []byte
+	ts  float64
+}
+
+// ringBuffer is a bounded single-producer single-consumer queue. The
+// producer and consumer only synchronise through the atomic head and tail
+// indices, so pushing never blocks the backend thread delivering messages.
+type ringBuffer struct {
+	head    uint64 // next slot to read, owned by the consumer
+	tail    uint64 // next slot to write, owned by the producer
+	dropped uint64
+	mask    uint64
+	buf     []ringEntry
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	n := 1
+	for n < size {
+		n <<= 1
+	}
+	return &ringBuffer{mask: uint64(n - 1), buf: make([]ringEntry, n)}
+}
+
+// push adds an entry, dropping it if the buffer is full.
+func (r *ringBuffer) push(msg []byte, ts float64) bool {
+	tail := atomic.LoadUint64(&r.tail)
+	if tail-atomic.LoadUint64(&r.head) > r.mask {
+		atomic.AddUint64(&r.dropped, 1)
+		return false
+	}
+	r.buf[tail&r.mask] = ringEntry{msg: msg, ts: ts}
+	atomic.StoreUint64(&r.tail, tail+1)
+	return true
+}
+
+func (r *ringBuffer) pop() (ringEntry, bool) {
+	head := atomic.LoadUint64(&r.head)
+	if head == atomic.LoadUint64(&r.tail) {
+		return ringEntry{}, false
+	}
+	e := r.buf[head&r.mask]
+	r.buf[head&r.mask] = ringEntry{}
+	atomic.StoreUint64(&r.head, head+1)
+	return e, true
+}
+
+func (r *ringBuffer) len() int {
+	return int(atomic.LoadUint64(&r.tail) - atomic.LoadUint64(&r.head))
+}
+
+// dispatcher delivers messages queued from a backend thread to a handler
+// running on its own goroutine.
+type dispatcher struct {
+	ring  *ringBuffer
+	fn    func(msg []byte, ts float64)
+	wake  chan struct{}
+	drain chan chan struct{}
+	ctl   chan realtimeRequest
+	quit  chan struct{}
+	done  chan struct{}
+}
+
+func newDispatcher(size int, fn func(msg []byte, ts float64)) *dispatcher {
+	d := &dispatcher{
+		ring:  newRingBuffer(size),
+		fn:    fn,
+		wake:  make(chan struct{}, 1),
+		drain: make(chan chan struct{}),
+		ctl:   make(chan realtimeRequest),
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go d.loop()
+	return d
+}
+
+func (d *dispatcher) push(msg []byte, ts float64) {
+	d.ring.push(msg, ts)
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *dispatcher) loop() {
+	defer close(d.done)
+	var thread realtimeThread
+	for {
+		d.deliver()
+		select {
+		case <-d.wake:
+		case reply := <-d.drain:
+			d.deliver()
+			close(reply)
+		case req := <-d.ctl:
+			req.reply <- thread.set(req.rt)
+		case <-d.quit:
+			return
+		}
+	}
+}
+
+func (d *dispatcher) deliver() {
+	for {
+		select {
+		case <-d.quit:
+			return
+		default:
+		}
+		e, ok := d.ring.pop()
+		if !ok {
+			return
+		}
+		d.fn(e.msg, e.ts)
+	}
+}
+
+// flush returns once every message queued so far has been delivered.
+func (d *dispatcher) flush() {
+	reply := make(chan struct{})
+	select {
+	case d.drain <- reply:
+		<-reply
+	case <-d.done:
+	}
+}
+
+// setRealtime applies rt to the dispatch goroutine.
+func (d *dispatcher) setRealtime(rt Realtime) error {
+	return requestRealtime(d.ctl, d.done, rt)
+}
+
+// dropped returns the number of messages dropped because the buffer was
+// full.
+func (d *dispatcher) dropped() uint64 {
+	return atomic.LoadUint64(&d.ring.dropped)
+}
+
+// stop ends the dispatch goroutine, discarding undelivered messages. It
+// does not wait for a message being delivered, as the handler may be the
+// one stopping it, such as a callback replacing itself or closing its
+// port: the handler returns in its own time, and none is called after.
+func (d *dispatcher) stop() {
+	close(d.quit)
+}
+
+// dropCounter is implemented by inputs counting the messages their
+// buffered callbacks drop.
+type dropCounter interface {
+	dropped() uint64
+}
+
+// Dropped returns the number of messages m has dropped because the buffer
+// of its buffered callback, set with SetBufferedCallback, was full. The
+// count covers every buffered callback set on the port.
+func Dropped(m MIDIIn) (uint64, error) {
+	d, ok := m.(dropCounter)
+	if !ok {
+		return 0, fmt.Errorf("rtmidi: port does not count dropped messages")
+	}
+	return d.dropped(), nil
+}
@@ -0,0 +1,115 @@
+package rtmidi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRingBuffer(t *testing.T) {
+	r := newRingBuffer(3)
+	if len(r.buf) != 4 {
+		t.Fatalf("size rounded to %d", len(r.buf))
+	}
+	for i := 0; i < 5; i++ {
+		r.push([]byte{byte(i)}, float64(i))
+	}
+	if r.len() != 4 || r.dropped != 1 {
+		t.Errorf("len %d, dropped %d", r.len(), r.dropped)
+	}
+	for i := 0; i < 4; i++ {
+		e, ok := r.pop()
+		if !ok || e.msg[0] != byte(i) || e.ts != float64(i) {
+			t.Errorf("pop %d = %v, %v", i, e, ok)
+		}
+	}
+	if _, ok := r.pop(); ok {
+		t.Error("pop from empty buffer succeeded")
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	got := make(chan byte, 16)
+	d := newDispatcher(16, func(msg []byte, ts float64) { got <- msg[0] })
+	defer d.stop()
+	for i := 0; i < 10; i++ {
+		d.push([]byte{byte(i)}, 0)
+	}
+	for i := 0; i < 10; i++ {
+		select {
+		case b := <-got:
+			if b != byte(i) {
+				t.Errorf("message %d delivered as %d", i, b)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("message not delivered")
+		}
+	}
+}
+
+func TestDispatcherFlush(t *testing.T) {
+	var n int
+	d := newDispatcher(16, func(msg []byte, ts float64) {
+		time.Sleep(time.Millisecond)
+		n++
+	})
+	defer d.stop()
+	for i := 0; i < 10; i++ {
+		d.ring.push([]byte{byte(i)}, 0)
+	}
+	d.flush()
+	if n != 10 {
+		t.Errorf("flush returned after %d of 10 messages", n)
+	}
+}
+
+func TestDropped(t *testing.T) {
+	defer func() { newMIDIIn = NewMIDIIn }()
+	dev := &fakeIn{}
+	newMIDIIn = func(api API, client string, size int) (MIDIIn, error) { return dev, nil }
+	in, err := OpenSharedIn(PortSpec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+
+	release := make(chan struct{})
+	SetBufferedCallback(in, 2, func(m MIDIIn, msg []byte, ts float64) { <-release })
+	for i := 0; i < 10; i++ {
+		dev.deliver([]byte{0x90, 60, 100})
+	}
+	n, err := Dropped(in)
+	if err != nil || n < 7 {
+		t.Errorf("Dropped = %d, %v; want at least 7", n, err)
+	}
+	close(release)
+	in.SetCallback(nil)
+	if m, _ := Dropped(in); m != n {
+		t.Errorf("Dropped = %d after replacing the callback, want %d", m, n)
+	}
+	if _, err := Dropped(&fakeIn{}); err == nil {
+		t.Error("Dropped of a port without buffered callbacks succeeded")
+	}
+}
+
+func TestBufferedCallbackReplacesItself(t *testing.T) {
+	defer func() { newMIDIIn = NewMIDIIn }()
+	dev := &fakeIn{}
+	newMIDIIn = func(api API, client string, size int) (MIDIIn, error) { return dev, nil }
+	in, err := OpenSharedIn(PortSpec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.IgnoreTypes(false, false, false)
+	done := make(chan struct{})
+	SetBufferedCallback(in, 4, func(m MIDIIn, msg []byte, ts float64) {
+		m.SetCallback(nil)
+		m.Close()
+		close(done)
+	})
+	dev.deliver([]byte{0x90, 60, 100})
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("callback replacing itself deadlocked")
+	}
+}
@@ -0,0 +1,124 @@
+package rtmidi
+
+import (
+	"sync"
+	"time"
+)
+
+// fakeOut is a MIDIOut that records every message sent to it.
+type fakeOut struct {
+	outQueues
+	notes  noteSet
+	timed  timedSender
+	mu     sync.Mutex
+	msgs   [][]byte
+	err    error
+	onSend func([]byte)
+}
+
+func (f *fakeOut) OpenPort(port int, name string) error { return nil }
+func (f *fakeOut) OpenVirtualPort(name string) error    { return nil }
+func (f *fakeOut) ConnectTo(portPattern string) error   { return nil }
+func (f *fakeOut) Close() error                         { f.notes.stopAll(); f.timed.close(); return nil }
+func (f *fakeOut) PortCount() (int, error)              { return 0, nil }
+func (f *fakeOut) PortName(port int) (string, error)    { return "", nil }
+func (f *fakeOut) API() (API, error)                    { return APIDummy, nil }
+func (f *fakeOut) Destroy()                             {}
+
+func (f *fakeOut) PlayNote(ch, key, vel int, d time.Duration) (*Note, error) {
+	return f.notes.play(f, ch, key, vel, d)
+}
+
+func (f *fakeOut) setClock(c Clock) {
+	f.notes.setClock(c)
+	f.timed.setClock(c)
+}
+
+func (f *fakeOut) SendMessageAt(b []byte, at time.Time) error {
+	return f.timed.send(f, b, at, nil)
+}
+
+func (f *fakeOut) SendMessage(b []byte) error {
+	f.mu.Lock()
+	if f.err != nil {
+		f.mu.Unlock()
+		return f.err
+	}
+	msg := append([]byte(nil), b...)
+	f.msgs = append(f.msgs, msg)
+	onSend := f.onSend
+	f.mu.Unlock()
+	if onSend != nil {
+		onSend(msg)
+	}
+	return nil
+}
+
+func (f *fakeOut) messages() [][]byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]byte(nil), f.msgs...)
+}
+
+// fakeIn is a MIDIIn whose messages are injected by the test.
+type fakeIn struct {
+	mu    sync.Mutex
+	cb    func(MIDIIn, []byte, float64)
+	queue [][]byte
+}
+
+func (f *fakeIn) OpenPort(port int, name string) error { return nil }
+func (f *fakeIn) OpenVirtualPort(name string) error    { return nil }
+func (f *fakeIn) ConnectTo(portPattern string) error   { return nil }
+func (f *fakeIn) Close() error                         { return nil }
+func (f *fakeIn) PortCount() (int, error)              { return 0, nil }
+func (f *fakeIn) PortName(port int) (string, error)    { return "", nil }
+func (f *fakeIn) API() (API, error)                    { return APIDummy, nil }
+func (f *fakeIn) Destroy()                             {}
+func (f *fakeIn) Drain() error                         { return nil }
+
+func (f *fakeIn) IgnoreTypes(midiSysex bool, midiTime bool, midiSense bool) error {
+	return nil
+}
+
+func (f *fakeIn) SetCallback(cb func(MIDIIn, []byte, float64)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cb = cb
+	return nil
+}
+
+func (f *fakeIn) SetBufferedCallback(size int, cb func(MIDIIn, []byte, float64)) error {
+	return f.SetCallback(cb)
+}
+
+func (f *fakeIn) CancelCallback() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cb = nil
+	return nil
+}
+
+func (f *fakeIn) Message() ([]byte, float64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.queue) == 0 {
+		return nil, 0, nil
+	}
+	msg := f.queue[0]
+	f.queue = f.queue[1:]
+	return msg, 0, nil
+}
+
+// deliver passes msg to the callback, or queues it if there is none.
+func (f *fakeIn) deliver(msg []byte) {
+	f.mu.Lock()
+	cb := f.cb
+	if cb == nil {
+		f.queue = append(f.queue, msg)
+	}
+	f.mu.Unlock()
+	if cb != nil {
+		cb(f, msg, 0)
+	}
+}
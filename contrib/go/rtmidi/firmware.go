@@ -0,0 +1,270 @@
+package rtmidi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"time"
+)
+
+// MIDIBytesPerSecond is the rate of a MIDI 1.0 DIN connection: 31250 baud
+// at 10 bits a byte.
+const MIDIBytesPerSecond = 3125
+
+// FirmwareReply is what a message received during a firmware update means
+// to it.
+type FirmwareReply int
+
+const (
+	// FirmwareOther is a message that is not a reply, which is ignored.
+	FirmwareOther FirmwareReply = iota
+	// FirmwareACK accepts a chunk, or the image when verifying.
+	FirmwareACK
+	// FirmwareNAK rejects a chunk, which is sent again, or the image when
+	// verifying.
+	FirmwareNAK
+	// FirmwareCancel stops the update.
+	FirmwareCancel
+)
+
+// FirmwareProfile describes how a device takes a firmware update: how the
+// image is cut into SysEx chunks, how fast they may be sent, and how the
+// device acknowledges them and verifies the result. Devices differ in all
+// of these, so they are defined by the profile.
+type FirmwareProfile struct {
+	// Name identifies the device.
+	Name string
+	// Start, if set, is sent before the first chunk, such as to enter the
+	// bootloader, and StartDelay waited after it.
+	Start      []byte
+	StartDelay time.Duration
+	// ChunkSize is the number of bytes of the image each chunk carries.
+	ChunkSize int
+	// Chunk returns the SysEx message carrying chunk n, holding data
+	// encoded in 7-bit bytes as the device expects.
+	Chunk func(n int, data []byte) []byte
+	// Reply tells what msg means as a reply to chunk n. If nil, chunks are
+	// sent without waiting for replies.
+	Reply func(n int, msg []byte) FirmwareReply
+	// BytesPerSecond is the fastest rate chunks are sent at, which slow
+	// devices and DIN connections need however fast the port; zero means
+	// MIDIBytesPerSecond. Delay is added after every chunk.
+	BytesPerSecond int
+	Delay          time.Duration
+	// Timeout is how long to wait for the reply to a chunk; zero means 2
+	// seconds.
+	Timeout time.Duration
+	// Retries is how many times a chunk is sent again after a NAK or a
+	// timeout.
+	Retries int
+	// Verify, if set, returns the request sent after the last chunk to
+	// check the image, such as one holding its checksum, and VerifyReply
+	// tells what msg means as a reply to it: FirmwareACK for an image the
+	// device accepts.
+	Verify      func(image []byte) []byte
+	VerifyReply func(image []byte, msg []byte) FirmwareReply
+}
+
+func (p *FirmwareProfile) chunks(image []byte) int {
+	return (len(image) + p.ChunkSize - 1) / p.ChunkSize
+}
+
+func (p *FirmwareProfile) chunk(image []byte, n int) []byte {
+	end := (n + 1) * p.ChunkSize
+	if end > len(image) {
+		end = len(image)
+	}
+	return p.Chunk(n, image[n*p.ChunkSize:end])
+}
+
+func (p *FirmwareProfile) bytesPerSecond() int {
+	if p.BytesPerSecond > 0 {
+		return p.BytesPerSecond
+	}
+	return MIDIBytesPerSecond
+}
+
+func (p *FirmwareProfile) timeout() time.Duration {
+	if p.Timeout > 0 {
+		return p.Timeout
+	}
+	return 2 * time.Second
+}
+
+// FirmwareError reports a firmware update that stopped at a chunk. The
+// update can be resumed from it with Resume.
+type FirmwareError struct {
+	// Chunk is the chunk that failed, or the number of chunks if the
+	// verification failed.
+	Chunk int
+	Err   error
+}
+
+func (e *FirmwareError) Error() string {
+	return fmt.Sprintf("rtmidi: firmware update stopped at chunk %d: %v", e.Chunk, e.Err)
+}
+
+// FirmwareUpdate sends a firmware image to a device connected to a pair of
+// ports, as described by its FirmwareProfile. Chunks are paced to the rate
+// of the profile and each is sent again until the device acknowledges it,
+// so that a slow or busy device never misses one. While running it takes
+// over the callback of the input.
+type FirmwareUpdate struct {
+	Profile *FirmwareProfile
+	// Progress, if set, is called after each chunk with the number of
+	// chunks done and the total.
+	Progress func(done, total int)
+
+	in    MIDIIn
+	out   MIDIOut
+	done  int
+	image uint32
+	next  time.Time
+}
+
+// NewFirmwareUpdate returns a FirmwareUpdate for the device described by
+// profile.
+func NewFirmwareUpdate(in MIDIIn, out MIDIOut, profile *FirmwareProfile) *FirmwareUpdate {
+	return &FirmwareUpdate{Profile: profile, in: in, out: out}
+}
+
+// Done returns the number of chunks the device has accepted.
+func (u *FirmwareUpdate) Done() int {
+	return u.done
+}
+
+// Send sends image to the device from the start, then verifies it. On
+// failure it returns a *FirmwareError, unless the context is done.
+func (u *FirmwareUpdate) Send(ctx context.Context, image []byte) error {
+	if u.Profile.ChunkSize <= 0 || u.Profile.Chunk == nil {
+		return errors.New("rtmidi: firmware profile without chunks")
+	}
+	if (u.Profile.Verify == nil) != (u.Profile.VerifyReply == nil) {
+		return errors.New("rtmidi: firmware profile with only one of Verify and VerifyReply")
+	}
+	u.done, u.image = 0, crc32.ChecksumIEEE(image)
+	if p := u.Profile; p.Start != nil {
+		if err := u.send(ctx, p.Start); err != nil {
+			return err
+		}
+		if err := sleepCtx(ctx, SystemClock, p.StartDelay); err != nil {
+			return err
+		}
+	}
+	return u.run(ctx, image)
+}
+
+// Resume carries on an update that failed from the chunk it stopped at,
+// without sending Start again. image must be the one given to Send.
+func (u *FirmwareUpdate) Resume(ctx context.Context, image []byte) error {
+	if crc32.ChecksumIEEE(image) != u.image {
+		return errors.New("rtmidi: resuming firmware update with another image")
+	}
+	return u.run(ctx, image)
+}
+
+func (u *FirmwareUpdate) run(ctx context.Context, image []byte) error {
+	p := u.Profile
+	var r *sysexReader
+	if p.Reply != nil || p.Verify != nil {
+		var err error
+		if r, err = newSysexReader(u.in, nil); err != nil {
+			return err
+		}
+		defer r.Close()
+	}
+	total := p.chunks(image)
+	for u.done < total {
+		var err error
+		for try := 0; ; try++ {
+			err = u.sendChunk(ctx, r, image, u.done)
+			if err == nil || err == ErrCancelled || try >= p.Retries || ctx.Err() != nil {
+				break
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return &FirmwareError{Chunk: u.done, Err: err}
+		}
+		u.done++
+		if u.Progress != nil {
+			u.Progress(u.done, total)
+		}
+	}
+	if p.Verify == nil {
+		return nil
+	}
+	if err := u.verify(ctx, r, image); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &FirmwareError{Chunk: total, Err: err}
+	}
+	return nil
+}
+
+// sendChunk sends chunk n and waits for the device to accept it.
+func (u *FirmwareUpdate) sendChunk(ctx context.Context, r *sysexReader, image []byte, n int) error {
+	p := u.Profile
+	if r != nil {
+		r.drain()
+	}
+	if err := u.send(ctx, p.chunk(image, n)); err != nil {
+		return err
+	}
+	if p.Reply == nil {
+		return nil
+	}
+	return u.await(ctx, r, func(msg []byte) FirmwareReply { return p.Reply(n, msg) })
+}
+
+func (u *FirmwareUpdate) verify(ctx context.Context, r *sysexReader, image []byte) error {
+	p := u.Profile
+	r.drain()
+	if err := u.send(ctx, p.Verify(image)); err != nil {
+		return err
+	}
+	err := u.await(ctx, r, func(msg []byte) FirmwareReply { return p.VerifyReply(image, msg) })
+	if err == errNAK {
+		return errVerify
+	}
+	return err
+}
+
+var errNAK = errors.New("rejected by device")
+
+// await waits for the reply to the message just sent, as told by reply.
+func (u *FirmwareUpdate) await(ctx context.Context, r *sysexReader, reply func([]byte) FirmwareReply) error {
+	deadline := time.Now().Add(u.Profile.timeout())
+	for {
+		msg, err := r.next(ctx, time.Until(deadline))
+		if err != nil {
+			return err
+		}
+		switch reply(msg) {
+		case FirmwareACK:
+			return nil
+		case FirmwareNAK:
+			return errNAK
+		case FirmwareCancel:
+			return ErrCancelled
+		}
+	}
+}
+
+// send sends msg once the previous message has had time to go out at the
+// rate of the profile, plus its delay.
+func (u *FirmwareUpdate) send(ctx context.Context, msg []byte) error {
+	if err := sleepCtx(ctx, SystemClock, time.Until(u.next)); err != nil {
+		return err
+	}
+	if err := u.out.SendMessage(msg); err != nil {
+		return err
+	}
+	wire := time.Duration(len(msg)) * time.Second / time.Duration(u.Profile.bytesPerSecond())
+	u.next = time.Now().Add(wire + u.Profile.Delay)
+	return nil
+}
@@ -0,0 +1,151 @@
+package rtmidi
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBootloader takes firmware chunks F0 7D 01 <n> <data> F7, answering
+// F0 7D 02 <n> F7 to accept and F0 7D 03 <n> F7 to reject them, and checks
+// the image on F0 7D 04 <sum> F7, answering F0 7D 05 <ok> F7.
+type fakeBootloader struct {
+	mu     sync.Mutex
+	in     *fakeIn
+	image  []byte
+	reject map[int]int
+	sent   []time.Time
+}
+
+func (b *fakeBootloader) receive(msg []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(msg) < 5 || msg[1] != 0x7d {
+		return
+	}
+	var reply []byte
+	switch msg[2] {
+	case 0x01:
+		b.sent = append(b.sent, time.Now())
+		n := int(msg[3])
+		if b.reject[n] > 0 {
+			b.reject[n]--
+			reply = []byte{0xf0, 0x7d, 0x03, msg[3], 0xf7}
+			break
+		}
+		if len(b.image) == n*4 {
+			b.image = append(b.image, msg[4:len(msg)-1]...)
+		}
+		reply = []byte{0xf0, 0x7d, 0x02, msg[3], 0xf7}
+	case 0x04:
+		ok := byte(0)
+		if checksum7(b.image) == msg[3] {
+			ok = 1
+		}
+		reply = []byte{0xf0, 0x7d, 0x05, ok, 0xf7}
+	}
+	if reply != nil {
+		go b.in.deliver(reply)
+	}
+}
+
+func checksum7(data []byte) byte {
+	var sum byte
+	for _, b := range data {
+		sum += b
+	}
+	return sum & 0x7f
+}
+
+func testFirmwareProfile() *FirmwareProfile {
+	return &FirmwareProfile{
+		Name:      "test",
+		ChunkSize: 4,
+		Chunk: func(n int, data []byte) []byte {
+			return append(append([]byte{0xf0, 0x7d, 0x01, byte(n)}, data...), 0xf7)
+		},
+		Reply: func(n int, msg []byte) FirmwareReply {
+			if len(msg) != 5 || msg[1] != 0x7d || int(msg[3]) != n {
+				return FirmwareOther
+			}
+			switch msg[2] {
+			case 0x02:
+				return FirmwareACK
+			case 0x03:
+				return FirmwareNAK
+			}
+			return FirmwareOther
+		},
+		BytesPerSecond: 1000,
+		Timeout:        200 * time.Millisecond,
+		Retries:        1,
+		Verify: func(image []byte) []byte {
+			return []byte{0xf0, 0x7d, 0x04, checksum7(image), 0xf7}
+		},
+		VerifyReply: func(image []byte, msg []byte) FirmwareReply {
+			if len(msg) != 5 || msg[2] != 0x05 {
+				return FirmwareOther
+			}
+			if msg[3] == 1 {
+				return FirmwareACK
+			}
+			return FirmwareNAK
+		},
+	}
+}
+
+func TestFirmwareUpdate(t *testing.T) {
+	in, out := &fakeIn{}, &fakeOut{}
+	dev := &fakeBootloader{in: in, reject: map[int]int{1: 1, 2: 2}}
+	out.onSend = dev.receive
+	image := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14}
+
+	u := NewFirmwareUpdate(in, out, testFirmwareProfile())
+	var done int
+	u.Progress = func(n, total int) { done = n }
+
+	// Chunk 1 is rejected once and sent again; chunk 2 is rejected more
+	// often than retried, and the update stops there.
+	err := u.Send(context.Background(), image)
+	if fe, ok := err.(*FirmwareError); !ok || fe.Chunk != 2 {
+		t.Fatalf("Send = %v", err)
+	}
+	if done != 2 || u.Done() != 2 {
+		t.Errorf("done %d, %d", done, u.Done())
+	}
+
+	if err := u.Resume(context.Background(), image[:8]); err == nil {
+		t.Error("resumed with another image")
+	}
+	if err := u.Resume(context.Background(), image); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dev.image, image) || done != 4 {
+		t.Errorf("device has %v", dev.image)
+	}
+
+	// Chunks of 9 bytes at 1000 bytes per second are 9ms apart.
+	for i := 1; i < len(dev.sent); i++ {
+		if d := dev.sent[i].Sub(dev.sent[i-1]); d < 8*time.Millisecond {
+			t.Errorf("chunk %d sent %v after the previous", i, d)
+		}
+	}
+}
+
+func TestFirmwareVerifyFails(t *testing.T) {
+	in, out := &fakeIn{}, &fakeOut{}
+	dev := &fakeBootloader{in: in}
+	out.onSend = func(msg []byte) {
+		if msg[2] == 0x01 {
+			msg[4]++ // corrupted on the way
+		}
+		dev.receive(msg)
+	}
+	u := NewFirmwareUpdate(in, out, testFirmwareProfile())
+	err := u.Send(context.Background(), []byte{1, 2, 3, 4, 5})
+	if fe, ok := err.(*FirmwareError); !ok || fe.Chunk != 2 || fe.Err != errVerify {
+		t.Errorf("Send = %v", err)
+	}
+}
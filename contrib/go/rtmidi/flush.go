@@ -0,0 +1,64 @@
+package rtmidi
+
+import (
+	"context"
+	"sync"
+)
+
+// flusher is an output queue feeding a MIDIOut that can be waited on.
+type flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// queueAttacher is implemented by outputs that keep track of the queues
+// feeding them, so that Flush can wait for all of them.
+type queueAttacher interface {
+	attachQueue(q flusher) (detach func())
+}
+
+// attachQueue registers q with out if out keeps track of its queues.
+func attachQueue(out MIDIOut, q flusher) (detach func()) {
+	if a, ok := out.(queueAttacher); ok {
+		return a.attachQueue(q)
+	}
+	return func() {}
+}
+
+// outQueues is the set of queues feeding an output.
+type outQueues struct {
+	mu     sync.Mutex
+	next   int
+	queues map[int]flusher
+}
+
+func (o *outQueues) attachQueue(q flusher) (detach func()) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.queues == nil {
+		o.queues = map[int]flusher{}
+	}
+	id := o.next
+	o.next++
+	o.queues[id] = q
+	return func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		delete(o.queues, id)
+	}
+}
+
+// Flush waits until every attached queue is empty or ctx is done.
+func (o *outQueues) Flush(ctx context.Context) error {
+	o.mu.Lock()
+	queues := make([]flusher, 0, len(o.queues))
+	for _, q := range o.queues {
+		queues = append(queues, q)
+	}
+	o.mu.Unlock()
+	for _, q := range queues {
+		if err := q.Flush(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
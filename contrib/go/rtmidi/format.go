@@ -0,0 +1,107 @@
+package rtmidi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// FormatMessage returns a human readable description of a MIDI message, such
+// as "NoteOn ch=1 C4 vel=100". Channels are shown 1-based as on most devices.
+// Unrecognised data is shown as hex bytes.
+func FormatMessage(msg []byte) string {
+	return FormatMessageNames(msg, nil)
+}
+
+// FormatMessageNames is like FormatMessage, but names control changes using
+// the controller names of a device, see DeviceControllerNames.
+func FormatMessageNames(msg []byte, names ControllerNames) string {
+	if len(msg) == 0 {
+		return ""
+	}
+	status := msg[0]
+	if status < 0xf0 && status >= 0x80 {
+		ch := int(status&0x0f) + 1
+		switch status & 0xf0 {
+		case 0x80:
+			if len(msg) >= 3 {
+				return fmt.Sprintf("NoteOff ch=%d %s vel=%d", ch, NoteName(int(msg[1])), msg[2])
+			}
+		case 0x90:
+			if len(msg) >= 3 {
+				return fmt.Sprintf("NoteOn ch=%d %s vel=%d", ch, NoteName(int(msg[1])), msg[2])
+			}
+		case 0xa0:
+			if len(msg) >= 3 {
+				return fmt.Sprintf("PolyPressure ch=%d %s value=%d", ch, NoteName(int(msg[1])), msg[2])
+			}
+		case 0xb0:
+			if len(msg) >= 3 {
+				if name := names.Name(int(msg[1])); name != "" {
+					return fmt.Sprintf("ControlChange ch=%d cc=%d (%s) value=%d", ch, msg[1], name, msg[2])
+				}
+				return fmt.Sprintf("ControlChange ch=%d cc=%d value=%d", ch, msg[1], msg[2])
+			}
+		case 0xc0:
+			if len(msg) >= 2 {
+				return fmt.Sprintf("ProgramChange ch=%d program=%d", ch, msg[1])
+			}
+		case 0xd0:
+			if len(msg) >= 2 {
+				return fmt.Sprintf("ChannelPressure ch=%d value=%d", ch, msg[1])
+			}
+		case 0xe0:
+			if len(msg) >= 3 {
+				return fmt.Sprintf("PitchBend ch=%d value=%d", ch, int(msg[1])|int(msg[2])<<7)
+			}
+		}
+		return fmt.Sprintf("% X", msg)
+	}
+	switch status {
+	case 0xf0:
+		return fmt.Sprintf("SysEx len=%d % X", len(msg), msg)
+	case 0xf1:
+		if len(msg) >= 2 {
+			return fmt.Sprintf("MTCQuarterFrame type=%d value=%d", msg[1]>>4, msg[1]&0x0f)
+		}
+	case 0xf2:
+		if len(msg) >= 3 {
+			return fmt.Sprintf("SongPosition beats=%d", int(msg[1])|int(msg[2])<<7)
+		}
+	case 0xf3:
+		if len(msg) >= 2 {
+			return fmt.Sprintf("SongSelect song=%d", msg[1])
+		}
+	case 0xf6:
+		return "TuneRequest"
+	case 0xf8:
+		return "Clock"
+	case 0xfa:
+		return "Start"
+	case 0xfb:
+		return "Continue"
+	case 0xfc:
+		return "Stop"
+	case 0xfe:
+		return "ActiveSensing"
+	case 0xff:
+		return "Reset"
+	}
+	return strings.TrimSpace(fmt.Sprintf("% X", msg))
+}
+
+// hexMessage returns the bytes of msg in hex, such as "c0 05", as messages
+// are stored in JSON.
+func hexMessage(msg []byte) string {
+	return fmt.Sprintf("% x", msg)
+}
+
+// parseHexMessage parses a message stored by hexMessage. Spaces are
+// optional and either case is accepted.
+func parseHexMessage(s string) ([]byte, error) {
+	msg, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid data %q", s)
+	}
+	return msg, nil
+}
@@ -0,0 +1,24 @@
+package rtmidi
+
+import "testing"
+
+func TestFormatMessage(t *testing.T) {
+	for _, test := range []struct {
+		msg  []byte
+		want string
+	}{
+		{[]byte{0x90, 60, 100}, "NoteOn ch=1 C4 vel=100"},
+		{[]byte{0x8f, 61, 0}, "NoteOff ch=16 C#4 vel=0"},
+		{[]byte{0xb0, 7, 127}, "ControlChange ch=1 cc=7 (Volume) value=127"},
+		{[]byte{0xb0, 20, 1}, "ControlChange ch=1 cc=20 value=1"},
+		{[]byte{0xe1, 0x00, 0x40}, "PitchBend ch=2 value=8192"},
+		{[]byte{0xf0, 0x7e, 0xf7}, "SysEx len=3 F0 7E F7"},
+		{[]byte{0xf8}, "Clock"},
+		{[]byte{0x90, 60}, "90 3C"},
+		{nil, ""},
+	} {
+		if s := FormatMessage(test.msg); s != test.want {
+			t.Errorf("FormatMessage(% x) = %q, want %q", test.msg, s, test.want)
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package rtmidi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// groupPort is a port opened by a PortGroup, which the group can reopen
+// with another API.
+type groupPort interface {
+	MIDI
+	// prepare opens the port again with api and configures it as the port
+	// is, returning nil if the port is closed.
+	prepare(api API) (MIDI, error)
+	// swap replaces the port with one returned by prepare, returning the
+	// one replaced.
+	swap(m MIDI) MIDI
+}
+
+// portSettings are the settings of a port a PortGroup reapplies when it
+// reopens the port.
+type portSettings struct {
+	warnings bool
+	policy   WarningPolicy
+	warnFn   func(*Warning)
+	connects []string
+}
+
+func (s *portSettings) apply(m MIDI) error {
+	if s.warnings {
+		if err := SetWarningPolicy(m, s.policy, s.warnFn); err != nil {
+			return err
+		}
+	}
+	for _, pattern := range s.connects {
+		if err := ConnectTo(m, pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reopenSpec opens spec again with api.
+func reopenSpec(spec PortSpec, api API, s *portSettings) (MIDI, error) {
+	spec.API = api
+	m, err := openSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(m); err != nil {
+		m.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// groupIn is an input opened by a PortGroup.
+type groupIn struct {
+	group *PortGroup
+	spec  PortSpec
+
+	mu       sync.Mutex
+	in       MIDIIn
+	settings portSettings
+	cb       func(MIDIIn, []byte, float64)
+	size     int
+	ignored  *[3]bool
+	rt       *Realtime
+	lost     uint64
+	closed   bool
+}
+
+func (h *groupIn) port() MIDIIn {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.in
+}
+
+func (h *groupIn) prepare(api API) (MIDI, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, nil
+	}
+	m, err := reopenSpec(h.spec, api, &h.settings)
+	if err != nil {
+		return nil, err
+	}
+	in := m.(MIDIIn)
+	if err := h.configureLocked(in); err != nil {
+		in.Close()
+		return nil, err
+	}
+	return in, nil
+}
+
+// configureLocked sets the filters, realtime settings and callback of the
+// handle on in.
+func (h *groupIn) configureLocked(in MIDIIn) error {
+	if h.ignored != nil {
+		if err := in.IgnoreTypes(h.ignored[0], h.ignored[1], h.ignored[2]); err != nil {
+			return err
+		}
+	}
+	if h.rt != nil {
+		if err := SetRealtime(in, *h.rt); err != nil {
+			return err
+		}
+	}
+	if h.cb == nil {
+		return nil
+	}
+	return h.setCallback(in, h.cb, h.size)
+}
+
+// setCallback sets cb as the callback of in, buffered if size is not zero.
+func (h *groupIn) setCallback(in MIDIIn, cb func(MIDIIn, []byte, float64), size int) error {
+	if cb == nil {
+		return in.CancelCallback()
+	}
+	handle := func(_ MIDIIn, msg []byte, t float64) { cb(h, msg, t) }
+	if size > 0 {
+		return SetBufferedCallback(in, size, handle)
+	}
+	return in.SetCallback(handle)
+}
+
+func (h *groupIn) swap(m MIDI) MIDI {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	old := h.in
+	h.in = m.(MIDIIn)
+	n, _ := Dropped(old)
+	h.lost += n
+	return old
+}
+
+func (h *groupIn) OpenPort(port int, name string) error {
+	return errors.New("rtmidi: port of a group is already open")
+}
+
+func (h *groupIn) OpenVirtualPort(name string) error {
+	return errors.New("rtmidi: port of a group is already open")
+}
+
+// ConnectTo connects the virtual port, and connects it again when the port
+// is reopened with another API.
+func (h *groupIn) ConnectTo(portPattern string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := ConnectTo(h.in, portPattern); err != nil {
+		return err
+	}
+	h.settings.connects = append(h.settings.connects, portPattern)
+	return nil
+}
+
+func (h *groupIn) PortCount() (int, error)           { return h.port().PortCount() }
+func (h *groupIn) PortName(port int) (string, error) { return h.port().PortName(port) }
+func (h *groupIn) API() (API, error)                 { return h.port().API() }
+
+func (h *groupIn) IgnoreTypes(midiSysex bool, midiTime bool, midiSense bool) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ignored = &[3]bool{midiSysex, midiTime, midiSense}
+	return h.in.IgnoreTypes(midiSysex, midiTime, midiSense)
+}
+
+// SetCallback sets cb as the callback of the port, which receives the
+// handle rather than the port it wraps.
+func (h *groupIn) SetCallback(cb func(MIDIIn, []byte, float64)) error {
+	return h.SetBufferedCallback(0, cb)
+}
+
+// SetBufferedCallback is as SetCallback, buffering messages unless size is
+// zero. The lock of the handle is not held while the callback of the port
+// is replaced, as the callback replaced may be running and need it.
+func (h *groupIn) SetBufferedCallback(size int, cb func(MIDIIn, []byte, float64)) error {
+	h.mu.Lock()
+	h.cb, h.size = cb, size
+	in := h.in
+	h.mu.Unlock()
+	return h.setCallback(in, cb, size)
+}
+
+func (h *groupIn) CancelCallback() error {
+	return h.SetCallback(nil)
+}
+
+func (h *groupIn) setRealtime(rt Realtime) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.rt = &rt
+	return SetRealtime(h.in, rt)
+}
+
+func (h *groupIn) setWarningPolicy(policy WarningPolicy, fn func(*Warning)) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := SetWarningPolicy(h.in, policy, fn); err != nil {
+		return err
+	}
+	h.settings.warnings, h.settings.policy, h.settings.warnFn = true, policy, fn
+	return nil
+}
+
+// dropped counts the messages dropped by every port the handle has had.
+func (h *groupIn) dropped() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n, _ := Dropped(h.in)
+	return h.lost + n
+}
+
+func (h *groupIn) Drain() error                      { return Drain(h.port()) }
+func (h *groupIn) Message() ([]byte, float64, error) { return h.port().Message() }
+
+// Close closes the port and removes it from its group.
+func (h *groupIn) Close() error {
+	h.mu.Lock()
+	in := h.in
+	h.closed = true
+	h.mu.Unlock()
+	h.group.remove(h)
+	return in.Close()
+}
+
+func (h *groupIn) Destroy() {
+	h.Close()
+}
+
+// groupOut is an output opened by a PortGroup. It keeps its own scheduler
+// and the controller state sent, so that neither is lost when the port is
+// reopened.
+type groupOut struct {
+	outQueues
+	notes noteSet
+	timed timedSender
+	group *PortGroup
+	spec  PortSpec
+
+	mu       sync.Mutex
+	out      MIDIOut
+	settings portSettings
+	state    *controllerState
+	closed   bool
+}
+
+func (h *groupOut) port() MIDIOut {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.out
+}
+
+func (h *groupOut) prepare(api API) (MIDI, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, nil
+	}
+	return reopenSpec(h.spec, api, &h.settings)
+}
+
+// swap replaces the output, then sends it the controller values, programs
+// and pitch bends sent so far, so that the device behind it is set as it
+// was.
+func (h *groupOut) swap(m MIDI) MIDI {
+	out := m.(MIDIOut)
+	h.mu.Lock()
+	old := h.out
+	h.out = out
+	var msgs [][]byte
+	for ch := 0; ch < 16; ch++ {
+		msgs = append(msgs, h.state.messages(ch)...)
+	}
+	h.mu.Unlock()
+	for _, msg := range msgs {
+		out.SendMessage(msg)
+	}
+	return old
+}
+
+func (h *groupOut) OpenPort(port int, name string) error {
+	return errors.New("rtmidi: port of a group is already open")
+}
+
+func (h *groupOut) OpenVirtualPort(name string) error {
+	return errors.New("rtmidi: port of a group is already open")
+}
+
+// ConnectTo connects the virtual port, and connects it again when the port
+// is reopened with another API.
+func (h *groupOut) ConnectTo(portPattern string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := ConnectTo(h.out, portPattern); err != nil {
+		return err
+	}
+	h.settings.connects = append(h.settings.connects, portPattern)
+	return nil
+}
+
+func (h *groupOut) PortCount() (int, error)           { return h.port().PortCount() }
+func (h *groupOut) PortName(port int) (string, error) { return h.port().PortName(port) }
+func (h *groupOut) API() (API, error)                 { return h.port().API() }
+
+func (h *groupOut) setWarningPolicy(policy WarningPolicy, fn func(*Warning)) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := SetWarningPolicy(h.out, policy, fn); err != nil {
+		return err
+	}
+	h.settings.warnings, h.settings.policy, h.settings.warnFn = true, policy, fn
+	return nil
+}
+
+// SendMessage sends msg, then records the controller state it sets, so that
+// a message the port failed to send is not sent to the next one.
+func (h *groupOut) SendMessage(msg []byte) error {
+	if err := h.port().SendMessage(msg); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	h.state.apply(msg)
+	h.mu.Unlock()
+	return nil
+}
+
+// SendMessageAt schedules msg with a scheduler of the handle rather than
+// of the backend, so that it is sent even if the port is reopened before
+// then.
+func (h *groupOut) SendMessageAt(msg []byte, at time.Time) error {
+	return h.timed.send(h, msg, at, nil)
+}
+
+func (h *groupOut) PlayNote(ch, key, vel int, d time.Duration) (*Note, error) {
+	return h.notes.play(h, ch, key, vel, d)
+}
+
+func (h *groupOut) setClock(c Clock) {
+	h.notes.setClock(c)
+	h.timed.setClock(c)
+}
+
+func (h *groupOut) Flush(ctx context.Context) error {
+	if err := h.notes.Flush(ctx); err != nil {
+		return err
+	}
+	if err := h.outQueues.Flush(ctx); err != nil {
+		return err
+	}
+	return Flush(ctx, h.port())
+}
+
+func (h *groupOut) Close() error {
+	h.notes.stopAll()
+	h.timed.close()
+	h.mu.Lock()
+	out := h.out
+	h.closed = true
+	h.mu.Unlock()
+	h.group.remove(h)
+	return out.Close()
+}
+
+func (h *groupOut) Destroy() {
+	h.Close()
+}
@@ -0,0 +1,101 @@
+package rtmidi
+
+import (
+	"sync"
+	"time"
+)
+
+// IdleDetector reports when an input has received no messages for a while,
+// and when traffic resumes, e.g. to show a controller as asleep.
+type IdleDetector struct {
+	// IgnoreRealtime stops system realtime messages such as clock and active
+	// sensing from counting as activity.
+	IgnoreRealtime bool
+
+	timeout  time.Duration
+	onIdle   func()
+	onActive func()
+
+	mu      sync.Mutex
+	timer   funcTimer
+	idle    bool
+	stopped bool
+}
+
+// NewIdleDetector returns an IdleDetector calling onIdle once no message has
+// been fed to it for timeout, and onActive when the next one arrives. Either
+// function may be nil. The timeout starts counting immediately.
+func NewIdleDetector(timeout time.Duration, onIdle, onActive func()) *IdleDetector {
+	d := &IdleDetector{timeout: timeout, onIdle: onIdle, onActive: onActive}
+	d.timer = afterFunc(SystemClock, timeout, d.expire)
+	return d
+}
+
+// SetClock sets the clock the timeout is counted on, SystemClock by
+// default. The timeout starts counting again on the new clock.
+func (d *IdleDetector) SetClock(c Clock) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.timer.Stop()
+	if !d.stopped {
+		d.timer = afterFunc(clockOr(c), d.timeout, d.expire)
+	}
+}
+
+func (d *IdleDetector) expire() {
+	d.mu.Lock()
+	if d.idle || d.stopped {
+		d.mu.Unlock()
+		return
+	}
+	d.idle = true
+	d.mu.Unlock()
+	if d.onIdle != nil {
+		d.onIdle()
+	}
+}
+
+// Feed records activity from a received message.
+func (d *IdleDetector) Feed(msg []byte) {
+	if d.IgnoreRealtime && len(msg) > 0 && msg[0] >= 0xf8 {
+		return
+	}
+	d.mu.Lock()
+	if d.stopped {
+		d.mu.Unlock()
+		return
+	}
+	wasIdle := d.idle
+	d.idle = false
+	d.timer.Reset(d.timeout)
+	d.mu.Unlock()
+	if wasIdle && d.onActive != nil {
+		d.onActive()
+	}
+}
+
+// Wrap returns a callback for MIDIIn.SetCallback that feeds the detector
+// before passing each message on to cb, which may be nil.
+func (d *IdleDetector) Wrap(cb func(MIDIIn, []byte, float64)) func(MIDIIn, []byte, float64) {
+	return func(m MIDIIn, msg []byte, t float64) {
+		d.Feed(msg)
+		if cb != nil {
+			cb(m, msg, t)
+		}
+	}
+}
+
+// Idle reports whether the input is currently idle.
+func (d *IdleDetector) Idle() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.idle
+}
+
+// Stop stops the detector; no more callbacks are made.
+func (d *IdleDetector) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopped = true
+	d.timer.Stop()
+}
@@ -0,0 +1,32 @@
+package rtmidi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdleDetector(t *testing.T) {
+	events := make(chan string, 4)
+	d := NewIdleDetector(20*time.Millisecond,
+		func() { events <- "idle" },
+		func() { events <- "active" })
+	defer d.Stop()
+	d.IgnoreRealtime = true
+	if ev := <-events; ev != "idle" || !d.Idle() {
+		t.Fatalf("got %q, idle %v", ev, d.Idle())
+	}
+	d.Feed([]byte{0xfe})
+	if !d.Idle() {
+		t.Error("active sensing counted as activity")
+	}
+	in := &fakeIn{}
+	var got []byte
+	in.SetCallback(d.Wrap(func(m MIDIIn, msg []byte, ts float64) { got = msg }))
+	in.deliver([]byte{0x90, 60, 100})
+	if ev := <-events; ev != "active" || d.Idle() || got == nil {
+		t.Errorf("got %q, idle %v, message %v", ev, d.Idle(), got)
+	}
+	if ev := <-events; ev != "idle" {
+		t.Errorf("got %q, want idle", ev)
+	}
+}
@@ -0,0 +1,204 @@
+package rtmidi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Layer is a zone of a keyboard layering: the notes played in the key
+// range of the zone are sent on the layer's channel, transposed and with
+// their velocity offset.
+type Layer struct {
+	// Channel is the channel (0-15) the layer plays on.
+	Channel int
+	// Transpose is added to the keys, in semitones. Notes transposed out of
+	// the MIDI range are left out.
+	Transpose int
+	// Velocity is added to the velocity of NoteOns, which stays within 1
+	// to 127.
+	Velocity int
+	// Low and High are the incoming keys the layer plays, inclusive. A
+	// zero High means up to 127.
+	Low, High int
+}
+
+// key returns the key the layer plays for key, or false if it does not
+// play it.
+func (l Layer) key(key int) (int, bool) {
+	high := l.High
+	if high == 0 {
+		high = 127
+	}
+	if key < l.Low || key > high {
+		return 0, false
+	}
+	key += l.Transpose
+	if key < 0 || key > 127 {
+		return 0, false
+	}
+	return key, true
+}
+
+func (l Layer) velocity(vel int) int {
+	vel += l.Velocity
+	switch {
+	case vel < 1:
+		return 1
+	case vel > 127:
+		return 127
+	}
+	return vel
+}
+
+// layeredNote is a note sent for an incoming note.
+type layeredNote struct {
+	ch, key byte
+}
+
+// Layering wraps a MIDIOut and plays the notes sent to it on several
+// layers at once, as a keyboard layers or splits its sounds. It is meant as
+// the output of a Router rule, such as:
+//
+//	When(Channel(0)).SendTo(NewLayering(synth, piano, strings))
+//
+// Other channel voice messages, such as controllers and pitch bend, are
+// sent on the channel of every layer, except program changes, which are
+// sent as they are. System messages are sent as they are.
+//
+// The layers can be changed while notes are held: a NoteOff always ends
+// the notes its NoteOn started.
+type Layering struct {
+	MIDIOut
+
+	mu     sync.Mutex
+	layers []Layer
+	held   map[uint16][]layeredNote
+	notes  noteSet
+}
+
+// NewLayering returns a Layering sending to out with the given layers.
+func NewLayering(out MIDIOut, layers ...Layer) *Layering {
+	return &Layering{MIDIOut: out, layers: layers, held: map[uint16][]layeredNote{}}
+}
+
+// SetLayers replaces the layers.
+func (l *Layering) SetLayers(layers ...Layer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.layers = append([]Layer(nil), layers...)
+}
+
+// Layers returns the layers.
+func (l *Layering) Layers() []Layer {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Layer(nil), l.layers...)
+}
+
+// Apply returns the messages msg is sent as.
+func (l *Layering) Apply(msg []byte) [][]byte {
+	if len(msg) == 0 || msg[0] < 0x80 || msg[0] >= 0xf0 || msg[0]&0xf0 == 0xc0 {
+		return [][]byte{msg}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	status := msg[0] & 0xf0
+	if len(msg) < 2 || status > 0xa0 {
+		return l.perChannelLocked(msg)
+	}
+	id := uint16(msg[0]&0x0f)<<7 | uint16(msg[1]&0x7f)
+	held, isHeld := l.held[id]
+	switch {
+	case isNoteOn(msg):
+		var out [][]byte
+		for _, layer := range l.layers {
+			key, ok := layer.key(int(msg[1]))
+			if !ok {
+				continue
+			}
+			n := layeredNote{byte(layer.Channel & 0x0f), byte(key)}
+			held = append(held, n)
+			out = append(out, []byte{0x90 | n.ch, n.key, byte(layer.velocity(int(msg[2])))})
+		}
+		if len(held) > 0 {
+			l.held[id] = held
+		}
+		return out
+	case isNoteOff(msg):
+		delete(l.held, id)
+		fallthrough
+	case isHeld:
+		var out [][]byte
+		for _, n := range held {
+			out = append(out, append([]byte{status | n.ch, n.key}, msg[2:]...))
+		}
+		return out
+	}
+	return nil
+}
+
+// perChannelLocked sends msg on the channel of every layer.
+func (l *Layering) perChannelLocked(msg []byte) [][]byte {
+	var out [][]byte
+	var sent uint16
+	for _, layer := range l.layers {
+		ch := byte(layer.Channel & 0x0f)
+		if sent&(1<<ch) != 0 {
+			continue
+		}
+		sent |= 1 << ch
+		m := append([]byte(nil), msg...)
+		m[0] = msg[0]&0xf0 | ch
+		out = append(out, m)
+	}
+	return out
+}
+
+// SendMessage sends msg on the layers.
+func (l *Layering) SendMessage(msg []byte) error {
+	var first error
+	for _, m := range l.Apply(msg) {
+		if err := l.MIDIOut.SendMessage(m); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// SendMessageAt sends msg on the layers at the given time.
+func (l *Layering) SendMessageAt(msg []byte, at time.Time) error {
+	var first error
+	for _, m := range l.Apply(msg) {
+		if err := SendMessageAt(l.MIDIOut, m, at); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// PlayNote plays a note on the layers.
+func (l *Layering) PlayNote(ch, key, vel int, d time.Duration) (*Note, error) {
+	return l.notes.play(l, ch, key, vel, d)
+}
+
+// Flush waits for the notes started with PlayNote to end, then for the
+// queues of the wrapped output.
+func (l *Layering) Flush(ctx context.Context) error {
+	if err := l.notes.Flush(ctx); err != nil {
+		return err
+	}
+	return Flush(ctx, l.MIDIOut)
+}
+
+// setClock also sets the clock of the wrapped output, where it can be set.
+func (l *Layering) setClock(c Clock) {
+	l.notes.setClock(c)
+	SetClock(l.MIDIOut, c)
+}
+
+// Close ends the notes started with PlayNote and closes the wrapped output.
+func (l *Layering) Close() error {
+	l.notes.stopAll()
+	return l.MIDIOut.Close()
+}
@@ -0,0 +1,63 @@
+package rtmidi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLayering(t *testing.T) {
+	out := &fakeOut{}
+	piano := Layer{Channel: 0}
+	strings := Layer{Channel: 1, Transpose: -12, Velocity: -30, Low: 60}
+	l := NewLayering(out, piano, strings)
+	for _, tt := range []struct {
+		name string
+		msg  []byte
+		want [][]byte
+	}{
+		{"layered note", []byte{0x90, 64, 100}, [][]byte{{0x90, 64, 100}, {0x91, 52, 70}}},
+		{"split note", []byte{0x90, 48, 20}, [][]byte{{0x90, 48, 20}}},
+		{"aftertouch", []byte{0xa0, 64, 50}, [][]byte{{0xa0, 64, 50}, {0xa1, 52, 50}}},
+		{"controller", []byte{0xb0, 64, 127}, [][]byte{{0xb0, 64, 127}, {0xb1, 64, 127}}},
+		{"program", []byte{0xc0, 5}, [][]byte{{0xc0, 5}}},
+		{"clock", []byte{0xf8}, [][]byte{{0xf8}}},
+	} {
+		if got := l.Apply(tt.msg); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: % x, want % x", tt.name, got, tt.want)
+		}
+	}
+
+	// Changing the layers while notes are held still ends them.
+	l.SetLayers(Layer{Channel: 2, Transpose: 12})
+	if err := l.SendMessage([]byte{0x80, 64, 0}); err != nil {
+		t.Fatal(err)
+	}
+	l.SendMessage([]byte{0x90, 48, 0})
+	l.SendMessage([]byte{0x90, 60, 1})
+	want := [][]byte{{0x80, 64, 0}, {0x81, 52, 0}, {0x90, 48, 0}, {0x92, 72, 1}}
+	if got := out.messages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("sent % x, want % x", got, want)
+	}
+	if got := l.Apply([]byte{0x80, 61, 0}); got != nil {
+		t.Errorf("NoteOff of an unheld note sent as % x", got)
+	}
+}
+
+func TestLayerKey(t *testing.T) {
+	for _, tt := range []struct {
+		layer Layer
+		key   int
+		want  int
+		ok    bool
+	}{
+		{Layer{}, 0, 0, true},
+		{Layer{}, 127, 127, true},
+		{Layer{Low: 36, High: 59}, 60, 0, false},
+		{Layer{Transpose: 12}, 120, 0, false},
+		{Layer{Transpose: -24}, 30, 6, true},
+	} {
+		if got, ok := tt.layer.key(tt.key); got != tt.want || ok != tt.ok {
+			t.Errorf("%+v.key(%d) = %d, %v, want %d, %v", tt.layer, tt.key, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package rtmidi
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Merger merges the messages of several inputs into a single stream of
+// MessageEvents, which tell the port each message came from. Passing
+// Router.Receive as the function routes the merged inputs with their
+// origin, so that rules can tell them apart with RuleBuilder.From.
+type Merger struct {
+	fn func(MessageEvent)
+
+	mu     sync.Mutex
+	inputs []mergedInput
+}
+
+type mergedInput struct {
+	in    MIDIIn
+	ref   PortRef
+	owned bool
+}
+
+// NewMerger returns a Merger calling fn with the messages of its inputs.
+// fn is called from the callbacks of the inputs, possibly from several
+// threads at once.
+func NewMerger(fn func(MessageEvent)) *Merger {
+	return &Merger{fn: fn}
+}
+
+// Add merges the messages of in, an input opened on the port ref. It sets
+// the callback of in.
+func (m *Merger) Add(in MIDIIn, ref PortRef) error {
+	return m.add(in, ref, false)
+}
+
+func (m *Merger) add(in MIDIIn, ref PortRef, owned bool) error {
+	if err := SetEventCallback(in, ref, m.fn); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inputs = append(m.inputs, mergedInput{in, ref, owned})
+	return nil
+}
+
+// Open opens the input described by spec and merges its messages. The
+// merger closes it when it is removed or the merger is closed.
+func (m *Merger) Open(spec PortSpec) (PortRef, error) {
+	spec.Output = false
+	d, err := openSpec(spec)
+	if err != nil {
+		return PortRef{}, fmt.Errorf("rtmidi: opening %v: %w", spec, err)
+	}
+	in := d.(MIDIIn)
+	var ref PortRef
+	if spec.Virtual {
+		var api API
+		if api, err = in.API(); err == nil {
+			ref = VirtualPortRef(api, spec.Name)
+		}
+	} else {
+		var index int
+		if index, err = spec.portIndex(in); err == nil {
+			ref, err = NewPortRef(in, index)
+		}
+	}
+	if err == nil {
+		err = m.add(in, ref, true)
+	}
+	if err != nil {
+		in.Close()
+		return PortRef{}, err
+	}
+	return ref, nil
+}
+
+// Ports returns the ports merged, in the order they were added.
+func (m *Merger) Ports() []PortRef {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	refs := make([]PortRef, len(m.inputs))
+	for i, x := range m.inputs {
+		refs[i] = x.ref
+	}
+	return refs
+}
+
+// Remove stops merging the messages of the port with the ID of ref,
+// cancelling the callback of its input, and closing it if it was opened
+// with Open.
+func (m *Merger) Remove(ref PortRef) error {
+	m.mu.Lock()
+	var found *mergedInput
+	for i, x := range m.inputs {
+		if x.ref.ID == ref.ID {
+			found = &x
+			m.inputs = append(m.inputs[:i:i], m.inputs[i+1:]...)
+			break
+		}
+	}
+	m.mu.Unlock()
+	if found == nil {
+		return fmt.Errorf("rtmidi: port %v is not merged", ref)
+	}
+	return found.release()
+}
+
+// Close stops merging every input, as Remove does, returning the first
+// error.
+func (m *Merger) Close() error {
+	m.mu.Lock()
+	inputs := m.inputs
+	m.inputs = nil
+	m.mu.Unlock()
+	var first error
+	for _, x := range inputs {
+		if err := x.release(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (x mergedInput) release() error {
+	if err := x.in.CancelCallback(); err != nil {
+		return err
+	}
+	if x.owned {
+		return x.in.Close()
+	}
+	return nil
+}
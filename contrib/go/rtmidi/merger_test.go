@@ -0,0 +1,115 @@
+package rtmidi
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMerger(t *testing.T) {
+	keysIn, padsIn := &fakeIn{}, &fakeIn{}
+	keys := VirtualPortRef(APIDummy, "keys")
+	pads := VirtualPortRef(APIDummy, "pads")
+
+	var mu sync.Mutex
+	var events []MessageEvent
+	m := NewMerger(func(ev MessageEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, ev)
+	})
+	if err := m.Add(keysIn, keys); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add(padsIn, pads); err != nil {
+		t.Fatal(err)
+	}
+	keysIn.deliver([]byte{0x90, 60, 100})
+	padsIn.deliver([]byte{0x99, 36, 90})
+	if len(events) != 2 || events[0].Port != keys || events[1].Port != pads {
+		t.Fatalf("events %+v", events)
+	}
+
+	if err := m.Remove(keys); err != nil {
+		t.Fatal(err)
+	}
+	keysIn.deliver([]byte{0x80, 60, 0})
+	if len(events) != 2 {
+		t.Errorf("removed input still merged: %+v", events[2:])
+	}
+	if ports := m.Ports(); len(ports) != 1 || ports[0] != pads {
+		t.Errorf("Ports = %v", ports)
+	}
+	if err := m.Remove(keys); err == nil {
+		t.Error("removed twice")
+	}
+	m.Close()
+	padsIn.deliver([]byte{0x89, 36, 0})
+	if len(events) != 2 {
+		t.Errorf("closed merger still merging: %+v", events[2:])
+	}
+}
+
+func TestMergerOpen(t *testing.T) {
+	defer func() { newMIDIIn = NewMIDIIn }()
+	in := &fakeIn{}
+	newMIDIIn = func(api API, client string, size int) (MIDIIn, error) { return in, nil }
+
+	var got []MessageEvent
+	m := NewMerger(func(ev MessageEvent) { got = append(got, ev) })
+	ref, err := m.Open(PortSpec{Virtual: true, Name: "Sequencer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.ID != "dummy:virtual:Sequencer" {
+		t.Errorf("ref %+v", ref)
+	}
+	in.deliver([]byte{0xb0, 7, 100})
+	if len(got) != 1 || got[0].Port != ref {
+		t.Errorf("events %+v", got)
+	}
+	m.Close()
+}
+
+func TestRouterFrom(t *testing.T) {
+	keysIn, padsIn := &fakeIn{}, &fakeIn{}
+	keys := VirtualPortRef(APIDummy, "keys")
+	pads := VirtualPortRef(APIDummy, "pads")
+	synth, drums := &fakeOut{}, &fakeOut{}
+
+	r := NewRouter(
+		When(Notes()).From(keys).SendTo(synth),
+		When(Notes()).From(pads).SendTo(drums).Named("pads"),
+	)
+	r.SetTracing(10)
+	m := NewMerger(r.Receive)
+	m.Add(keysIn, keys)
+	m.Add(padsIn, pads)
+
+	keysIn.deliver([]byte{0x90, 60, 100})
+	padsIn.deliver([]byte{0x90, 60, 90})
+	// The NoteOffs end the note of their own port.
+	padsIn.deliver([]byte{0x80, 60, 0})
+	keysIn.deliver([]byte{0x80, 60, 0})
+
+	if got := synth.messages(); len(got) != 2 || got[0][2] != 100 || got[1][0] != 0x80 {
+		t.Errorf("synth got %v", got)
+	}
+	if got := drums.messages(); len(got) != 2 || got[0][2] != 90 || got[1][0] != 0x80 {
+		t.Errorf("drums got %v", got)
+	}
+
+	// Messages without an origin match no rule narrowed to ports.
+	r.Route([]byte{0x90, 61, 100})
+	if n := len(synth.messages()) + len(drums.messages()); n != 4 {
+		t.Errorf("message without origin routed")
+	}
+
+	tr, ok := r.Cause(drums, []byte{0x90, 60, 90})
+	if !ok || tr.Port != pads {
+		t.Fatalf("Cause = %+v, %v", tr, ok)
+	}
+	if s := tr.String(); !strings.Contains(s, "from dummy:virtual:pads NoteOn") {
+		t.Errorf("trace %q", s)
+	}
+}
@@ -0,0 +1,221 @@
+package rtmidi
+
+// Message is a MIDI message decoded into its fields by DecodeMessage.
+// Encode returns its bytes, with every field cut to its range: channels
+// and data bytes keep their low 4 and 7 bits, and 14-bit values are
+// clamped to 0-16383. Decoding the bytes
+// of a message and encoding the result gives the same bytes.
+type Message interface {
+	Encode() []byte
+}
+
+// NoteOff releases a key.
+type NoteOff struct {
+	Channel, Key, Velocity int
+}
+
+// NoteOn strikes a key. A velocity of zero releases it, as NoteOff does.
+type NoteOn struct {
+	Channel, Key, Velocity int
+}
+
+// PolyAftertouch is the pressure on a held key.
+type PolyAftertouch struct {
+	Channel, Key, Pressure int
+}
+
+// ControlChange sets a controller.
+type ControlChange struct {
+	Channel, Controller, Value int
+}
+
+// ProgramChange selects a program.
+type ProgramChange struct {
+	Channel, Program int
+}
+
+// ChannelAftertouch is the pressure on the keys of a channel.
+type ChannelAftertouch struct {
+	Channel, Pressure int
+}
+
+// PitchBend bends the pitch of a channel. Value runs from 0 to 16383,
+// PitchBendCenter leaving the pitch as it is.
+type PitchBend struct {
+	Channel, Value int
+}
+
+// SysEx is a system exclusive message. Data is what comes between F0 and
+// F7.
+type SysEx struct {
+	Data []byte
+}
+
+// TimeCodeQuarterFrame carries one piece of MIDI Time Code.
+type TimeCodeQuarterFrame struct {
+	Piece, Value int
+}
+
+// SongPosition locates the song, in sixteenth notes.
+type SongPosition struct {
+	Sixteenths int
+}
+
+// SongSelect selects a song.
+type SongSelect struct {
+	Song int
+}
+
+// TuneRequest asks analog synthesizers to tune themselves.
+type TuneRequest struct{}
+
+// RealtimeMessage is a one-byte system realtime message.
+type RealtimeMessage byte
+
+// The system realtime messages.
+const (
+	RealtimeClock         RealtimeMessage = 0xf8
+	RealtimeStart         RealtimeMessage = 0xfa
+	RealtimeContinue      RealtimeMessage = 0xfb
+	RealtimeStop          RealtimeMessage = 0xfc
+	RealtimeActiveSensing RealtimeMessage = 0xfe
+	RealtimeReset         RealtimeMessage = 0xff
+)
+
+func channelBytes(status byte, ch int, data ...int) []byte {
+	msg := []byte{status | byte(ch&0x0f)}
+	for _, d := range data {
+		msg = append(msg, byte(d&0x7f))
+	}
+	return msg
+}
+
+// Encode returns the bytes of the message.
+func (m NoteOff) Encode() []byte { return channelBytes(0x80, m.Channel, m.Key, m.Velocity) }
+
+// Encode returns the bytes of the message.
+func (m NoteOn) Encode() []byte { return channelBytes(0x90, m.Channel, m.Key, m.Velocity) }
+
+// Encode returns the bytes of the message.
+func (m PolyAftertouch) Encode() []byte { return channelBytes(0xa0, m.Channel, m.Key, m.Pressure) }
+
+// Encode returns the bytes of the message.
+func (m ControlChange) Encode() []byte {
+	return channelBytes(0xb0, m.Channel, m.Controller, m.Value)
+}
+
+// Encode returns the bytes of the message.
+func (m ProgramChange) Encode() []byte { return channelBytes(0xc0, m.Channel, m.Program) }
+
+// Encode returns the bytes of the message.
+func (m ChannelAftertouch) Encode() []byte { return channelBytes(0xd0, m.Channel, m.Pressure) }
+
+// Encode returns the bytes of the message.
+func (m PitchBend) Encode() []byte {
+	v := clampPitchBend(m.Value)
+	return channelBytes(0xe0, m.Channel, v, v>>7)
+}
+
+// Encode returns the bytes of the message. Bytes of Data with the top bit
+// set are cut to 7 bits.
+func (m SysEx) Encode() []byte {
+	msg := make([]byte, 0, len(m.Data)+2)
+	msg = append(msg, 0xf0)
+	for _, b := range m.Data {
+		msg = append(msg, b&0x7f)
+	}
+	return append(msg, 0xf7)
+}
+
+// Encode returns the bytes of the message.
+func (m TimeCodeQuarterFrame) Encode() []byte {
+	return []byte{0xf1, byte(m.Piece&0x07)<<4 | byte(m.Value&0x0f)}
+}
+
+// Encode returns the bytes of the message.
+func (m SongPosition) Encode() []byte {
+	v := m.Sixteenths
+	switch {
+	case v < 0:
+		v = 0
+	case v > 0x3fff:
+		v = 0x3fff
+	}
+	return []byte{0xf2, byte(v & 0x7f), byte(v >> 7)}
+}
+
+// Encode returns the bytes of the message.
+func (m SongSelect) Encode() []byte { return []byte{0xf3, byte(m.Song & 0x7f)} }
+
+// Encode returns the bytes of the message.
+func (TuneRequest) Encode() []byte { return []byte{0xf6} }
+
+// Encode returns the bytes of the message.
+func (m RealtimeMessage) Encode() []byte { return []byte{byte(m)} }
+
+// DecodeMessage decodes a single complete message, returning an error if
+// msg fails ValidateMessage. The Data of a SysEx is a copy.
+func DecodeMessage(msg []byte) (Message, error) {
+	if err := ValidateMessage(msg); err != nil {
+		return nil, err
+	}
+	status := msg[0]
+	if status < 0xf0 {
+		ch := int(status & 0x0f)
+		switch status & 0xf0 {
+		case 0x80:
+			return NoteOff{ch, int(msg[1]), int(msg[2])}, nil
+		case 0x90:
+			return NoteOn{ch, int(msg[1]), int(msg[2])}, nil
+		case 0xa0:
+			return PolyAftertouch{ch, int(msg[1]), int(msg[2])}, nil
+		case 0xb0:
+			return ControlChange{ch, int(msg[1]), int(msg[2])}, nil
+		case 0xc0:
+			return ProgramChange{ch, int(msg[1])}, nil
+		case 0xd0:
+			return ChannelAftertouch{ch, int(msg[1])}, nil
+		}
+		return PitchBend{ch, int(msg[1]) | int(msg[2])<<7}, nil
+	}
+	switch status {
+	case 0xf0:
+		return SysEx{append([]byte(nil), msg[1:len(msg)-1]...)}, nil
+	case 0xf1:
+		return TimeCodeQuarterFrame{int(msg[1] >> 4), int(msg[1] & 0x0f)}, nil
+	case 0xf2:
+		return SongPosition{int(msg[1]) | int(msg[2])<<7}, nil
+	case 0xf3:
+		return SongSelect{int(msg[1])}, nil
+	case 0xf6:
+		return TuneRequest{}, nil
+	}
+	return RealtimeMessage(status), nil
+}
+
+// DecodeMessages is like Decode, returning the messages decoded.
+func (d *Decoder) DecodeMessages(b []byte) []Message {
+	raw := d.Decode(b)
+	msgs := make([]Message, 0, len(raw))
+	for _, msg := range raw {
+		// Decode only returns valid messages.
+		m, _ := DecodeMessage(msg)
+		msgs = append(msgs, m)
+	}
+	return msgs
+}
+
+// ValidCallback returns a callback passing cb only the messages that pass
+// ValidateMessage, for inputs from devices known to send malformed data.
+// The others are passed to invalid, if not nil, with the error.
+func ValidCallback(cb func(MIDIIn, []byte, float64), invalid func(msg []byte, err error)) func(MIDIIn, []byte, float64) {
+	return func(m MIDIIn, msg []byte, t float64) {
+		if err := ValidateMessage(msg); err != nil {
+			if invalid != nil {
+				invalid(msg, err)
+			}
+			return
+		}
+		cb(m, msg, t)
+	}
+}
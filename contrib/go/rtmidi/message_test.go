@@ -0,0 +1,107 @@
+package rtmidi
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeMessage(t *testing.T) {
+	for _, test := range []struct {
+		msg  []byte
+		want Message
+	}{
+		{[]byte{0x81, 60, 64}, NoteOff{1, 60, 64}},
+		{[]byte{0x90, 60, 100}, NoteOn{0, 60, 100}},
+		{[]byte{0xa2, 60, 10}, PolyAftertouch{2, 60, 10}},
+		{[]byte{0xbf, 7, 100}, ControlChange{15, 7, 100}},
+		{[]byte{0xc0, 5}, ProgramChange{0, 5}},
+		{[]byte{0xd3, 90}, ChannelAftertouch{3, 90}},
+		{[]byte{0xe0, 0x00, 0x40}, PitchBend{0, PitchBendCenter}},
+		{[]byte{0xf0, 0x7e, 0x7f, 0xf7}, SysEx{[]byte{0x7e, 0x7f}}},
+		{[]byte{0xf1, 0x35}, TimeCodeQuarterFrame{3, 5}},
+		{[]byte{0xf2, 0x01, 0x02}, SongPosition{257}},
+		{[]byte{0xf3, 4}, SongSelect{4}},
+		{[]byte{0xf6}, TuneRequest{}},
+		{[]byte{0xf8}, RealtimeClock},
+		{[]byte{0xfc}, RealtimeStop},
+	} {
+		got, err := DecodeMessage(test.msg)
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("DecodeMessage(% X) = %#v, %v, want %#v", test.msg, got, err, test.want)
+			continue
+		}
+		if enc := got.Encode(); !bytes.Equal(enc, test.msg) {
+			t.Errorf("%#v encoded as % X, want % X", got, enc, test.msg)
+		}
+	}
+	if _, err := DecodeMessage([]byte{0x90, 60}); err == nil {
+		t.Error("short message decoded")
+	}
+	if enc := (ControlChange{17, 135, 200}).Encode(); !bytes.Equal(enc, []byte{0xb1, 7, 72}) {
+		t.Errorf("out of range fields encoded as % X", enc)
+	}
+}
+
+func TestDecodeMessages(t *testing.T) {
+	var d Decoder
+	got := d.DecodeMessages([]byte{0x90, 60, 100, 62, 0xf8, 100, 0x60})
+	want := []Message{NoteOn{0, 60, 100}, RealtimeClock, NoteOn{0, 62, 100}}
+	if !reflect.DeepEqual(got, want) || d.Dropped() != 0 {
+		t.Errorf("DecodeMessages = %#v", got)
+	}
+}
+
+func TestValidCallback(t *testing.T) {
+	in := &fakeIn{}
+	var got, bad [][]byte
+	in.SetCallback(ValidCallback(func(m MIDIIn, msg []byte, ts float64) {
+		got = append(got, msg)
+	}, func(msg []byte, err error) {
+		bad = append(bad, msg)
+	}))
+	in.deliver([]byte{0x90, 60, 100})
+	in.deliver([]byte{0x90, 60})
+	in.deliver([]byte{0x3c, 0x64})
+	if len(got) != 1 || len(bad) != 2 {
+		t.Errorf("passed % X, rejected % X", got, bad)
+	}
+}
+
+// checkMessage checks that decoding and encoding a valid message gives it
+// back.
+func checkMessage(t *testing.T, msg []byte) {
+	m, err := DecodeMessage(msg)
+	if err != nil {
+		return
+	}
+	if enc := m.Encode(); !bytes.Equal(enc, msg) {
+		t.Fatalf("% X decoded as %#v, encoded as % X", msg, m, enc)
+	}
+}
+
+func FuzzDecodeMessage(f *testing.F) {
+	for _, stream := range CodecSeedCorpus() {
+		var d Decoder
+		for _, msg := range d.Decode(stream) {
+			f.Add(msg)
+		}
+	}
+	f.Fuzz(checkMessage)
+}
+
+func TestEncodeClamps(t *testing.T) {
+	for _, tt := range []struct {
+		msg  Message
+		want []byte
+	}{
+		{PitchBend{0, 20000}, []byte{0xe0, 0x7f, 0x7f}},
+		{PitchBend{1, -5}, []byte{0xe1, 0x00, 0x00}},
+		{SongPosition{1 << 14}, []byte{0xf2, 0x7f, 0x7f}},
+		{SongPosition{-1}, []byte{0xf2, 0x00, 0x00}},
+	} {
+		if got := tt.msg.Encode(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%#v.Encode() = % X, want % X", tt.msg, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package rtmidi
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Metronome plays a click on a MIDIOut, driven by the pulses of a
+// PulseSource such as a ClockMaster or ClockFollower. The first beat of each
+// bar is played with a different note and velocity.
+type Metronome struct {
+	// Channel is the MIDI channel (0-15) of the click; 9 is the GM drum channel.
+	Channel int
+	// DownbeatNote and BeatNote are played on the first and other beats.
+	DownbeatNote, BeatNote int
+	// DownbeatVelocity and BeatVelocity are their velocities.
+	DownbeatVelocity, BeatVelocity int
+	// Gate is the length of a click in clock pulses.
+	Gate int
+
+	out      MIDIOut
+	mu       sync.Mutex
+	num, den int
+	pending  [2]int
+	sigStart int
+	playing  int
+	offAt    int
+	cancel   func()
+}
+
+// NewMetronome returns a Metronome in 4/4 playing the General MIDI wood
+// blocks on the drum channel of out.
+func NewMetronome(out MIDIOut) *Metronome {
+	return &Metronome{
+		Channel:          9,
+		DownbeatNote:     76,
+		BeatNote:         77,
+		DownbeatVelocity: 127,
+		BeatVelocity:     90,
+		Gate:             2,
+		out:              out,
+		num:              4,
+		den:              4,
+		playing:          -1,
+	}
+}
+
+// SetTimeSignature changes the time signature from the next bar. The
+// denominator must be 1, 2, 4, 8 or 16.
+func (m *Metronome) SetTimeSignature(num, den int) error {
+	switch den {
+	case 1, 2, 4, 8, 16:
+	default:
+		return fmt.Errorf("rtmidi: invalid time signature %d/%d", num, den)
+	}
+	if num <= 0 {
+		return fmt.Errorf("rtmidi: invalid time signature %d/%d", num, den)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending = [2]int{num, den}
+	return nil
+}
+
+// TimeSignature returns the time signature in effect.
+func (m *Metronome) TimeSignature() (num, den int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.num, m.den
+}
+
+// Attach makes the metronome follow src, detaching it from any previous
+// source.
+func (m *Metronome) Attach(src PulseSource) {
+	m.Detach()
+	cancel := src.OnPulse(m.Pulse)
+	m.mu.Lock()
+	m.cancel = cancel
+	m.mu.Unlock()
+}
+
+// Detach stops following the current source and silences any click playing.
+func (m *Metronome) Detach() {
+	m.mu.Lock()
+	cancel := m.cancel
+	m.cancel = nil
+	m.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.noteOff()
+}
+
+// Pulse advances the metronome to the given clock pulse, counted from the
+// start of the song.
+func (m *Metronome) Pulse(pulse int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.playing >= 0 && (pulse >= m.offAt || pulse < m.offAt-m.Gate) {
+		m.noteOff()
+	}
+	if pulse < m.sigStart {
+		m.sigStart = 0
+	}
+	perBeat := 4 * ClocksPerQuarter / m.den
+	rel := pulse - m.sigStart
+	if rel > 0 && rel%(perBeat*m.num) == 0 && m.pending[0] != 0 {
+		m.num, m.den = m.pending[0], m.pending[1]
+		m.pending = [2]int{}
+		m.sigStart, rel = pulse, 0
+		perBeat = 4 * ClocksPerQuarter / m.den
+	}
+	if rel%perBeat != 0 {
+		return
+	}
+	note, vel := m.BeatNote, m.BeatVelocity
+	if rel/perBeat%m.num == 0 {
+		note, vel = m.DownbeatNote, m.DownbeatVelocity
+	}
+	m.noteOff()
+	if m.out.SendMessage([]byte{0x90 | byte(m.Channel&0x0f), byte(note & 0x7f), byte(vel & 0x7f)}) == nil {
+		m.playing, m.offAt = note, pulse+m.Gate
+	}
+}
+
+func (m *Metronome) noteOff() {
+	if m.playing < 0 {
+		return
+	}
+	m.out.SendMessage([]byte{0x80 | byte(m.Channel&0x0f), byte(m.playing), 0})
+	m.playing = -1
+}
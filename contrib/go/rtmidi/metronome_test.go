@@ -0,0 +1,78 @@
+package rtmidi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMetronome(t *testing.T) {
+	out := &fakeOut{}
+	m := NewMetronome(out)
+	m.SetTimeSignature(3, 8)
+	// The first bar is 4/4, the signature changes at the second.
+	for p := 0; p < 4*24+3*12; p++ {
+		m.Pulse(p)
+	}
+	var notes []int
+	for _, msg := range out.messages() {
+		if msg[0] == 0x99 {
+			notes = append(notes, int(msg[1]))
+		}
+	}
+	want := []int{76, 77, 77, 77, 76, 77, 77}
+	if !reflect.DeepEqual(notes, want) {
+		t.Errorf("clicks %v, want %v", notes, want)
+	}
+	if num, den := m.TimeSignature(); num != 3 || den != 8 {
+		t.Errorf("TimeSignature() = %d/%d", num, den)
+	}
+	msgs := out.messages()
+	if !reflect.DeepEqual(msgs[1], []byte{0x89, 76, 0}) {
+		t.Errorf("click not released: % x", msgs[1])
+	}
+	if err := m.SetTimeSignature(4, 3); err == nil {
+		t.Error("expected error for invalid denominator")
+	}
+}
+
+func TestClockFollower(t *testing.T) {
+	f := NewClockFollower(4)
+	var pulses []int
+	f.OnPulse(func(p int) { pulses = append(pulses, p) })
+	now := time.Now()
+	f.feedAt([]byte{0xfa}, now)
+	for i := 0; i < 24; i++ {
+		f.feedAt([]byte{0xf8}, now.Add(time.Duration(i)*time.Second/48))
+	}
+	if len(pulses) != 24 || pulses[0] != 0 || pulses[23] != 23 {
+		t.Errorf("pulses %v", pulses)
+	}
+	if bpm := f.Tempo(); bpm < 119.9 || bpm > 120.1 {
+		t.Errorf("Tempo() = %v, want 120", bpm)
+	}
+}
+
+func TestClockMaster(t *testing.T) {
+	out := &fakeOut{}
+	c := NewClockMaster(out, 600)
+	got := make(chan int, 100)
+	cancel := c.OnPulse(func(p int) { got <- p })
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	for want := 0; want < 3; want++ {
+		if p := <-got; p != want {
+			t.Errorf("pulse %d, want %d", p, want)
+		}
+	}
+	c.Stop()
+	cancel()
+	if c.Running() {
+		t.Error("still running after Stop")
+	}
+	msgs := out.messages()
+	if msgs[0][0] != 0xfa || msgs[1][0] != 0xf8 || msgs[len(msgs)-1][0] != 0xfc {
+		t.Errorf("unexpected messages %v", msgs)
+	}
+}
@@ -0,0 +1,221 @@
+package rtmidi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// MirrorPolicy says what a MirrorOut does when sending to one of its
+// targets fails.
+type MirrorPolicy int
+
+const (
+	// MirrorReport returns the error, after sending to the other targets.
+	MirrorReport MirrorPolicy = iota
+	// MirrorIgnore ignores the error, as suits a monitor or logger that
+	// should not disturb the main output.
+	MirrorIgnore
+	// MirrorDetach returns the error and stops sending to the target.
+	MirrorDetach
+)
+
+func (p MirrorPolicy) String() string {
+	switch p {
+	case MirrorReport:
+		return "report"
+	case MirrorIgnore:
+		return "ignore"
+	case MirrorDetach:
+		return "detach"
+	}
+	return "?"
+}
+
+type mirrorTarget struct {
+	out    MIDIOut
+	policy MirrorPolicy
+}
+
+// MirrorOut is a MIDIOut sending every message to several outputs, such as
+// a synth and a monitor, in the order they were given. Ports are listed by
+// the first target, and cannot be opened through the mirror.
+type MirrorOut struct {
+	mu      sync.Mutex
+	targets []mirrorTarget
+	notes   noteSet
+}
+
+// Mirror returns a MirrorOut sending to outs, reporting errors from every
+// target until set otherwise with SetPolicy.
+func Mirror(outs ...MIDIOut) *MirrorOut {
+	m := &MirrorOut{}
+	for _, out := range outs {
+		m.targets = append(m.targets, mirrorTarget{out: out})
+	}
+	return m
+}
+
+// SetPolicy sets what the mirror does when sending to out fails.
+func (m *MirrorOut) SetPolicy(out MIDIOut, policy MirrorPolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.targets {
+		if m.targets[i].out == out {
+			m.targets[i].policy = policy
+		}
+	}
+}
+
+// Targets returns the outputs the mirror sends to, leaving out those
+// detached after an error.
+func (m *MirrorOut) Targets() []MIDIOut {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	outs := make([]MIDIOut, len(m.targets))
+	for i, t := range m.targets {
+		outs[i] = t.out
+	}
+	return outs
+}
+
+// each calls fn with every target, applying their policies to the errors
+// returned. It returns the first error reported.
+func (m *MirrorOut) each(fn func(out MIDIOut) error) error {
+	m.mu.Lock()
+	targets := m.targets
+	m.mu.Unlock()
+	var first error
+	for _, t := range targets {
+		err := fn(t.out)
+		if err == nil || t.policy == MirrorIgnore {
+			continue
+		}
+		if t.policy == MirrorDetach {
+			m.detach(t.out)
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (m *MirrorOut) detach(out MIDIOut) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, t := range m.targets {
+		if t.out == out {
+			m.targets = append(m.targets[:i:i], m.targets[i+1:]...)
+			return
+		}
+	}
+}
+
+func (m *MirrorOut) first() (MIDIOut, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.targets) == 0 {
+		return nil, errors.New("rtmidi: mirror has no targets")
+	}
+	return m.targets[0].out, nil
+}
+
+func (m *MirrorOut) OpenPort(port int, name string) error {
+	return errors.New("rtmidi: cannot open ports through a mirror")
+}
+
+func (m *MirrorOut) OpenVirtualPort(name string) error {
+	return errors.New("rtmidi: cannot open ports through a mirror")
+}
+
+func (m *MirrorOut) ConnectTo(portPattern string) error {
+	return errors.New("rtmidi: cannot connect ports through a mirror")
+}
+
+func (m *MirrorOut) PortCount() (int, error) {
+	out, err := m.first()
+	if err != nil {
+		return 0, err
+	}
+	return out.PortCount()
+}
+
+func (m *MirrorOut) PortName(port int) (string, error) {
+	out, err := m.first()
+	if err != nil {
+		return "", err
+	}
+	return out.PortName(port)
+}
+
+func (m *MirrorOut) API() (API, error) {
+	out, err := m.first()
+	if err != nil {
+		return APIUnspecified, err
+	}
+	return out.API()
+}
+
+func (m *MirrorOut) SendMessage(msg []byte) error {
+	return m.each(func(out MIDIOut) error { return out.SendMessage(msg) })
+}
+
+func (m *MirrorOut) SendMessageAt(msg []byte, at time.Time) error {
+	return m.each(func(out MIDIOut) error { return SendMessageAt(out, msg, at) })
+}
+
+// PlayNote plays a note on every target; stopping it sends the NoteOff to
+// every target.
+func (m *MirrorOut) PlayNote(ch, key, vel int, d time.Duration) (*Note, error) {
+	return m.notes.play(m, ch, key, vel, d)
+}
+
+// Flush waits for the notes played through the mirror, then flushes every
+// target.
+func (m *MirrorOut) Flush(ctx context.Context) error {
+	if err := m.notes.Flush(ctx); err != nil {
+		return err
+	}
+	return m.each(func(out MIDIOut) error { return Flush(ctx, out) })
+}
+
+// setClock also sets the clock of the targets, where it can be set.
+func (m *MirrorOut) setClock(c Clock) {
+	m.notes.setClock(c)
+	m.mu.Lock()
+	targets := m.targets
+	m.mu.Unlock()
+	for _, t := range targets {
+		SetClock(t.out, c)
+	}
+}
+
+// Close ends the notes played through the mirror and closes every target,
+// returning the first error.
+func (m *MirrorOut) Close() error {
+	m.notes.stopAll()
+	m.mu.Lock()
+	targets := m.targets
+	m.targets = nil
+	m.mu.Unlock()
+	var first error
+	for _, t := range targets {
+		if err := t.out.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Destroy destroys every target.
+func (m *MirrorOut) Destroy() {
+	m.mu.Lock()
+	targets := m.targets
+	m.targets = nil
+	m.mu.Unlock()
+	for _, t := range targets {
+		t.out.Destroy()
+	}
+}
@@ -0,0 +1,53 @@
+package rtmidi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMirror(t *testing.T) {
+	for _, tt := range []struct {
+		policy  MirrorPolicy
+		wantErr bool
+		targets int
+	}{
+		{MirrorReport, true, 2},
+		{MirrorIgnore, false, 2},
+		{MirrorDetach, true, 1},
+	} {
+		synth, monitor := &fakeOut{}, &fakeOut{err: errors.New("monitor gone")}
+		m := Mirror(monitor, synth)
+		m.SetPolicy(monitor, tt.policy)
+		err := m.SendMessage([]byte{0x90, 60, 100})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%v: SendMessage returned %v", tt.policy, err)
+		}
+		if n := len(m.Targets()); n != tt.targets {
+			t.Errorf("%v: %d targets left, want %d", tt.policy, n, tt.targets)
+		}
+		monitor.err = nil
+		m.SendMessage([]byte{0x80, 60, 0})
+		if got := synth.messages(); len(got) != 2 {
+			t.Errorf("%v: synth got % x", tt.policy, got)
+		}
+		if n := len(monitor.messages()); (n == 1) != (tt.policy != MirrorDetach) {
+			t.Errorf("%v: monitor got %d messages after recovering", tt.policy, n)
+		}
+	}
+}
+
+func TestMirrorPlayNote(t *testing.T) {
+	a, b := &fakeOut{}, &fakeOut{}
+	m := Mirror(a, b)
+	if _, err := m.PlayNote(0, 60, 100, 0); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+	want := [][]byte{{0x90, 60, 100}, {0x80, 60, 0}}
+	for i, out := range []*fakeOut{a, b} {
+		if got := out.messages(); !reflect.DeepEqual(got, want) {
+			t.Errorf("target %d got % x, want % x", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package rtmidi
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MTSAllDevices is the SysEx device ID addressing every device.
+const MTSAllDevices = 0x7f
+
+// NoteTuning is the tuning of a single key, given as a pitch in fractional
+// MIDI note numbers (60.5 is a quarter tone above middle C).
+type NoteTuning struct {
+	Key   int
+	Pitch float64
+}
+
+// TuningTable is a complete keyboard tuning as carried by an MTS bulk tuning
+// dump.
+type TuningTable struct {
+	Program int
+	Name    string
+	Pitches [128]float64
+}
+
+// EqualTuning returns a TuningTable with every key at its 12-tone equal
+// temperament pitch.
+func EqualTuning(program int, name string) *TuningTable {
+	t := &TuningTable{Program: program, Name: name}
+	for k := range t.Pitches {
+		t.Pitches[k] = float64(k)
+	}
+	return t
+}
+
+// EncodeMTSFrequency encodes a pitch as the three byte MTS frequency data
+// format: a semitone and a 14-bit fraction of a semitone above it.
+func EncodeMTSFrequency(pitch float64) [3]byte {
+	if pitch < 0 {
+		pitch = 0
+	}
+	semi := math.Floor(pitch)
+	frac := int(math.Round((pitch - semi) * 16384))
+	if frac == 16384 {
+		semi, frac = semi+1, 0
+	}
+	if semi > 127 || semi == 127 && frac > 16382 {
+		return [3]byte{0x7f, 0x7f, 0x7e}
+	}
+	return [3]byte{byte(semi), byte(frac >> 7), byte(frac & 0x7f)}
+}
+
+// DecodeMTSFrequency decodes MTS frequency data. ok is false for the reserved
+// value 7F 7F 7F meaning "no change".
+func DecodeMTSFrequency(b [3]byte) (pitch float64, ok bool) {
+	if b == [3]byte{0x7f, 0x7f, 0x7f} {
+		return 0, false
+	}
+	return float64(b[0]&0x7f) + float64(int(b[1]&0x7f)<<7|int(b[2]&0x7f))/16384, true
+}
+
+// MTSBulkDumpRequest returns a request for the bulk tuning dump of program.
+func MTSBulkDumpRequest(device int, program int) []byte {
+	return []byte{0xf0, 0x7e, byte(device & 0x7f), 0x08, 0x00, byte(program & 0x7f), 0xf7}
+}
+
+// MTSBulkDump encodes t as a bulk tuning dump for device.
+func MTSBulkDump(device int, t *TuningTable) []byte {
+	msg := make([]byte, 0, 408)
+	msg = append(msg, 0xf0, 0x7e, byte(device&0x7f), 0x08, 0x01, byte(t.Program&0x7f))
+	name := []byte(t.Name)
+	for i := 0; i < 16; i++ {
+		c := byte(' ')
+		if i < len(name) && name[i] >= 0x20 && name[i] < 0x7f {
+			c = name[i]
+		}
+		msg = append(msg, c)
+	}
+	for _, p := range t.Pitches {
+		f := EncodeMTSFrequency(p)
+		msg = append(msg, f[:]...)
+	}
+	var sum byte
+	for _, b := range msg[1:] {
+		sum ^= b
+	}
+	return append(msg, sum&0x7f, 0xf7)
+}
+
+// ParseMTSBulkDump decodes a bulk tuning dump. Keys marked "no change" keep
+// their equal temperament pitch.
+func ParseMTSBulkDump(msg []byte) (device int, t *TuningTable, err error) {
+	if len(msg) != 408 || msg[0] != 0xf0 || msg[1] != 0x7e || msg[3] != 0x08 || msg[4] != 0x01 || msg[407] != 0xf7 {
+		return 0, nil, errors.New("rtmidi: not an MTS bulk tuning dump")
+	}
+	var sum byte
+	for _, b := range msg[1:406] {
+		sum ^= b
+	}
+	if sum&0x7f != msg[406] {
+		return 0, nil, errors.New("rtmidi: MTS bulk tuning dump checksum mismatch")
+	}
+	t = EqualTuning(int(msg[5]), strings.TrimRight(string(msg[6:22]), " "))
+	for k := range t.Pitches {
+		i := 22 + 3*k
+		if p, ok := DecodeMTSFrequency([3]byte{msg[i], msg[i+1], msg[i+2]}); ok {
+			t.Pitches[k] = p
+		}
+	}
+	return int(msg[2]), t, nil
+}
+
+// MTSNoteChange encodes a realtime single note tuning change for device and
+// tuning program. At most 127 keys can be changed in one message.
+func MTSNoteChange(device int, program int, changes []NoteTuning) ([]byte, error) {
+	if len(changes) > 127 {
+		return nil, fmt.Errorf("rtmidi: too many tuning changes (%d)", len(changes))
+	}
+	msg := []byte{0xf0, 0x7f, byte(device & 0x7f), 0x08, 0x02, byte(program & 0x7f), byte(len(changes))}
+	for _, c := range changes {
+		f := EncodeMTSFrequency(c.Pitch)
+		msg = append(msg, byte(c.Key&0x7f), f[0], f[1], f[2])
+	}
+	return append(msg, 0xf7), nil
+}
+
+// ParseMTSNoteChange decodes a realtime single note tuning change.
+func ParseMTSNoteChange(msg []byte) (device int, program int, changes []NoteTuning, err error) {
+	if len(msg) < 8 || msg[0] != 0xf0 || msg[1] != 0x7f || msg[3] != 0x08 || msg[4] != 0x02 || msg[len(msg)-1] != 0xf7 {
+		return 0, 0, nil, errors.New("rtmidi: not an MTS single note tuning change")
+	}
+	n := int(msg[6])
+	if len(msg) != 8+4*n {
+		return 0, 0, nil, errors.New("rtmidi: malformed MTS single note tuning change")
+	}
+	for i := 0; i < n; i++ {
+		b := msg[7+4*i:]
+		if p, ok := DecodeMTSFrequency([3]byte{b[1], b[2], b[3]}); ok {
+			changes = append(changes, NoteTuning{Key: int(b[0]), Pitch: p})
+		}
+	}
+	return int(msg[2]), int(msg[5]), changes, nil
+}
+
+// ScaleTuning maps a Scala-style scale onto the keyboard. cents holds the
+// pitch of scale degrees 1 to n above the root, the last being the period
+// (usually 1200 for an octave). baseKey is tuned to basePitch and is the root
+// of the scale; the other keys follow the scale degrees up and down from it.
+func ScaleTuning(cents []float64, baseKey int, basePitch float64) [128]float64 {
+	var p [128]float64
+	n := len(cents)
+	if n == 0 {
+		for k := range p {
+			p[k] = basePitch + float64(k-baseKey)
+		}
+		return p
+	}
+	period := cents[n-1]
+	for k := range p {
+		d := k - baseKey
+		oct := int(math.Floor(float64(d) / float64(n)))
+		deg := d - oct*n
+		c := float64(oct) * period
+		if deg > 0 {
+			c += cents[deg-1]
+		}
+		p[k] = basePitch + c/100
+	}
+	return p
+}
+
+// ParseScala reads a Scala scale file (.scl), returning its description and
+// the pitch of each degree in cents. Degrees written as ratios, such as 3/2,
+// are converted to cents.
+func ParseScala(r io.Reader) (description string, cents []float64, err error) {
+	s := bufio.NewScanner(r)
+	var lines []string
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "!") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := s.Err(); err != nil {
+		return "", nil, err
+	}
+	if len(lines) < 2 {
+		return "", nil, errors.New("rtmidi: truncated scala file")
+	}
+	count, err := strconv.Atoi(strings.Fields(lines[1] + " x")[0])
+	if err != nil {
+		return "", nil, fmt.Errorf("rtmidi: bad scala note count %q", lines[1])
+	}
+	if len(lines)-2 < count {
+		return "", nil, errors.New("rtmidi: truncated scala file")
+	}
+	for _, line := range lines[2 : 2+count] {
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			return "", nil, errors.New("rtmidi: empty scala pitch")
+		}
+		c, err := parseScalaPitch(f[0])
+		if err != nil {
+			return "", nil, err
+		}
+		cents = append(cents, c)
+	}
+	return lines[0], cents, nil
+}
+
+func parseScalaPitch(s string) (float64, error) {
+	if strings.Contains(s, ".") {
+		return strconv.ParseFloat(s, 64)
+	}
+	num, den := s, "1"
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		num, den = s[:i], s[i+1:]
+	}
+	n, err1 := strconv.ParseFloat(num, 64)
+	d, err2 := strconv.ParseFloat(den, 64)
+	if err1 != nil || err2 != nil || n <= 0 || d <= 0 {
+		return 0, fmt.Errorf("rtmidi: bad scala pitch %q", s)
+	}
+	return 1200 * math.Log2(n/d), nil
+}
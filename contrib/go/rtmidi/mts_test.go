@@ -0,0 +1,88 @@
+package rtmidi
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMTSFrequency(t *testing.T) {
+	for _, test := range []struct {
+		pitch float64
+		data  [3]byte
+	}{
+		{60, [3]byte{60, 0, 0}},
+		{60.5, [3]byte{60, 0x40, 0}},
+		{0, [3]byte{0, 0, 0}},
+		{127 + 16382.0/16384, [3]byte{0x7f, 0x7f, 0x7e}},
+	} {
+		if d := EncodeMTSFrequency(test.pitch); d != test.data {
+			t.Errorf("EncodeMTSFrequency(%v) = % x, want % x", test.pitch, d, test.data)
+		}
+		if p, ok := DecodeMTSFrequency(test.data); !ok || p != test.pitch {
+			t.Errorf("DecodeMTSFrequency(% x) = %v, %v", test.data, p, ok)
+		}
+	}
+	if _, ok := DecodeMTSFrequency([3]byte{0x7f, 0x7f, 0x7f}); ok {
+		t.Error("no change value decoded")
+	}
+}
+
+func TestMTSBulkDump(t *testing.T) {
+	tt := EqualTuning(5, "Quarter")
+	tt.Pitches[61] = 60.5
+	msg := MTSBulkDump(0x10, tt)
+	if len(msg) != 408 {
+		t.Fatalf("dump is %d bytes", len(msg))
+	}
+	dev, got, err := ParseMTSBulkDump(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dev != 0x10 || !reflect.DeepEqual(got, tt) {
+		t.Errorf("round trip gave %d %+v", dev, got)
+	}
+	msg[100] ^= 1
+	if _, _, err := ParseMTSBulkDump(msg); err == nil {
+		t.Error("corrupted dump accepted")
+	}
+}
+
+func TestMTSNoteChange(t *testing.T) {
+	changes := []NoteTuning{{Key: 69, Pitch: 69.25}, {Key: 70, Pitch: 69.75}}
+	msg, err := MTSNoteChange(MTSAllDevices, 0, changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev, prog, got, err := ParseMTSNoteChange(msg)
+	if err != nil || dev != MTSAllDevices || prog != 0 || !reflect.DeepEqual(got, changes) {
+		t.Errorf("round trip gave %d %d %v %v", dev, prog, got, err)
+	}
+}
+
+func TestScala(t *testing.T) {
+	scl := `! meantone.scl
+!
+Pythagorean fifths and a tempered third
+ 3
+!
+ 3/2
+ 386.3137
+ 2/1
+`
+	desc, cents, err := ParseScala(strings.NewReader(scl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc != "Pythagorean fifths and a tempered third" || len(cents) != 3 {
+		t.Fatalf("ParseScala = %q %v", desc, cents)
+	}
+	if math.Abs(cents[0]-701.955) > 1e-3 || cents[2] != 1200 {
+		t.Errorf("cents %v", cents)
+	}
+	p := ScaleTuning(cents, 60, 60)
+	if p[60] != 60 || p[63] != 72 || p[57] != 48 || math.Abs(p[61]-67.01955) > 1e-5 {
+		t.Errorf("ScaleTuning gave %v %v %v %v", p[60], p[61], p[63], p[57])
+	}
+}
@@ -0,0 +1,33 @@
+package rtmidi
+
+/*
+#include "rtmidi_stub.h"
+*/
+import "C"
+import "errors"
+
+// Unwrap returns the native handles of the backend behind m, which must
+// have been created by this package, so that platform APIs the binding does
+// not cover can be called on it. The handles remain owned by m and are only
+// valid until m is closed or destroyed. What Native holds depends on the
+// platform.
+func Unwrap(m MIDI) (*Native, error) {
+	var p C.RtMidiPtr
+	switch m := m.(type) {
+	case *midiIn:
+		p = m.midi.midi
+	case *midiOut:
+		p = m.midi.midi
+	default:
+		return nil, errors.New("rtmidi: Unwrap of a port not created by rtmidi")
+	}
+	if p == nil {
+		return nil, errors.New("rtmidi: Unwrap of a closed port")
+	}
+	var n C.struct_RtMidiNative
+	C.rtmidi_get_native(p, &n)
+	if !p.ok {
+		return nil, errors.New(C.GoString(p.msg))
+	}
+	return newNative(&n), nil
+}
@@ -0,0 +1,25 @@
+package rtmidi
+
+/*
+#include "rtmidi_stub.h"
+*/
+import "C"
+
+// Native holds the CoreMIDI object references of a port on macOS.
+type Native struct {
+	// Client and Port are the MIDIClientRef and MIDIPortRef of the port.
+	Client, Port uint32
+	// Endpoint is the MIDIEndpointRef of a virtual port.
+	Endpoint uint32
+	// Destination is the MIDIEndpointRef an output is connected to.
+	Destination uint32
+}
+
+func newNative(n *C.struct_RtMidiNative) *Native {
+	return &Native{
+		Client:      uint32(n.coremidi_client),
+		Port:        uint32(n.coremidi_port),
+		Endpoint:    uint32(n.coremidi_endpoint),
+		Destination: uint32(n.coremidi_destination),
+	}
+}
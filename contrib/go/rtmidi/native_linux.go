@@ -0,0 +1,30 @@
+package rtmidi
+
+/*
+#include "rtmidi_stub.h"
+*/
+import "C"
+import "unsafe"
+
+// Native holds the native handles of a port on Linux.
+type Native struct {
+	// Seq is the snd_seq_t* of an ALSA port.
+	Seq unsafe.Pointer
+	// Client is the ALSA client id of Seq, and Port the ALSA port of our
+	// end of the connection, or -1 if none is open.
+	Client, Port int
+
+	// JackClient and JackPort are the jack_client_t* and jack_port_t* of a
+	// JACK port, when built with JACK support.
+	JackClient, JackPort unsafe.Pointer
+}
+
+func newNative(n *C.struct_RtMidiNative) *Native {
+	native := &Native{Port: -1}
+	if n.client != 0 {
+		native.Seq, native.Client, native.Port = n.handle, int(n.client), int(n.vport)
+	} else {
+		native.JackClient, native.JackPort = n.handle, n.port
+	}
+	return native
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !windows
+
+package rtmidi
+
+/*
+#include "rtmidi_stub.h"
+*/
+import "C"
+
+// Native holds no handles on this platform.
+type Native struct{}
+
+func newNative(n *C.struct_RtMidiNative) *Native {
+	return &Native{}
+}
@@ -0,0 +1,16 @@
+//go:build cgo
+
+package rtmidi
+
+import "testing"
+
+func TestUnwrapClosed(t *testing.T) {
+	out, err := NewMIDIOut(APIDummy, "RtMidi Test")
+	if err != nil {
+		t.Skip(err)
+	}
+	out.Close()
+	if _, err := Unwrap(out); err == nil {
+		t.Error("Unwrap of a closed port succeeded")
+	}
+}
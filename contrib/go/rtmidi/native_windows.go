@@ -0,0 +1,16 @@
+package rtmidi
+
+/*
+#include "rtmidi_stub.h"
+*/
+import "C"
+
+// Native holds the handle of a port on Windows.
+type Native struct {
+	// Handle is the HMIDIIN of an input or the HMIDIOUT of an output.
+	Handle uintptr
+}
+
+func newNative(n *C.struct_RtMidiNative) *Native {
+	return &Native{Handle: uintptr(n.handle)}
+}
@@ -0,0 +1,94 @@
+package rtmidi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	sharpNames = [12]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+	flatNames  = [12]string{"C", "Db", "D", "Eb", "E", "F", "Gb", "G", "Ab", "A", "Bb", "B"}
+	letterPC   = map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11}
+)
+
+// NoteNaming describes how MIDI note numbers are spelled. Manufacturers
+// disagree on the octave of middle C (note 60): Yamaha calls it C3 while most
+// others, and scientific pitch notation, call it C4.
+type NoteNaming struct {
+	// MiddleCOctave is the octave number given to note 60, usually 3 or 4.
+	MiddleCOctave int
+	// Flats spells accidentals as flats instead of sharps.
+	Flats bool
+}
+
+// DefaultNoteNaming is the convention used by NoteName and NoteNumber.
+var DefaultNoteNaming = NoteNaming{MiddleCOctave: 4}
+
+// NoteName returns the name of MIDI note num, e.g. "C4" for 60, using
+// DefaultNoteNaming.
+func NoteName(num int) string {
+	return DefaultNoteNaming.Name(num)
+}
+
+// NoteNumber parses a note name such as "C4", "F#2" or "Bb-1" into a MIDI note
+// number using DefaultNoteNaming.
+func NoteNumber(name string) (int, error) {
+	return DefaultNoteNaming.Number(name)
+}
+
+// Name returns the name of MIDI note num.
+func (n NoteNaming) Name(num int) string {
+	pc := ((num % 12) + 12) % 12
+	octave := (num-pc)/12 - 5 + n.MiddleCOctave
+	names := sharpNames
+	if n.Flats {
+		names = flatNames
+	}
+	return names[pc] + strconv.Itoa(octave)
+}
+
+// Number parses a note name into a MIDI note number. The letter is case
+// insensitive and may be followed by any number of sharps ('#' or '♯') or
+// flats ('b' or '♭') before the octave.
+func (n NoteNaming) Number(name string) (int, error) {
+	s := strings.TrimSpace(name)
+	if s == "" {
+		return 0, fmt.Errorf("rtmidi: invalid note name %q", name)
+	}
+	pc, ok := letterPC[strings.ToUpper(s[:1])[0]]
+	if !ok {
+		return 0, fmt.Errorf("rtmidi: invalid note name %q", name)
+	}
+	s = s[1:]
+	for {
+		switch {
+		case strings.HasPrefix(s, "#"):
+			pc++
+			s = s[1:]
+			continue
+		case strings.HasPrefix(s, "♯"):
+			pc++
+			s = s[len("♯"):]
+			continue
+		case strings.HasPrefix(s, "b"):
+			pc--
+			s = s[1:]
+			continue
+		case strings.HasPrefix(s, "♭"):
+			pc--
+			s = s[len("♭"):]
+			continue
+		}
+		break
+	}
+	octave, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("rtmidi: invalid note name %q", name)
+	}
+	num := (octave-n.MiddleCOctave+5)*12 + pc
+	if num < 0 || num > 127 {
+		return 0, fmt.Errorf("rtmidi: note %q out of range", name)
+	}
+	return num, nil
+}
@@ -0,0 +1,41 @@
+package rtmidi
+
+import "testing"
+
+func TestNoteName(t *testing.T) {
+	for _, test := range []struct {
+		naming NoteNaming
+		num    int
+		name   string
+	}{
+		{DefaultNoteNaming, 60, "C4"},
+		{DefaultNoteNaming, 0, "C-1"},
+		{DefaultNoteNaming, 127, "G9"},
+		{DefaultNoteNaming, 61, "C#4"},
+		{NoteNaming{MiddleCOctave: 4, Flats: true}, 70, "Bb4"},
+		{NoteNaming{MiddleCOctave: 3}, 60, "C3"},
+		{NoteNaming{MiddleCOctave: 3}, 0, "C-2"},
+	} {
+		if name := test.naming.Name(test.num); name != test.name {
+			t.Errorf("%+v.Name(%d) = %q, want %q", test.naming, test.num, name, test.name)
+		}
+		if num, err := test.naming.Number(test.name); err != nil || num != test.num {
+			t.Errorf("%+v.Number(%q) = %d, %v, want %d", test.naming, test.name, num, err, test.num)
+		}
+	}
+}
+
+func TestNoteNumber(t *testing.T) {
+	for name, want := range map[string]int{
+		"c4": 60, "Db4": 61, "C♯4": 61, "E♭4": 63, "B#3": 60, "Cb4": 59, " A4 ": 69,
+	} {
+		if num, err := NoteNumber(name); err != nil || num != want {
+			t.Errorf("NoteNumber(%q) = %d, %v, want %d", name, num, err, want)
+		}
+	}
+	for _, name := range []string{"", "H4", "C", "C#x", "G#9", "C-2"} {
+		if _, err := NoteNumber(name); err == nil {
+			t.Errorf("NoteNumber(%q) succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package rtmidi
+
+import (
+	"context"
+	"time"
+)
+
+// NoteOffStyle is a convention for ending notes. Devices variously send a
+// NoteOff or a NoteOn with velocity 0; normalizing to one style spares
+// code downstream from handling both.
+type NoteOffStyle int
+
+const (
+	// NoteOffAsIs leaves note offs as they are.
+	NoteOffAsIs NoteOffStyle = iota
+	// NoteOffStatus ends notes with NoteOff messages. A NoteOn with
+	// velocity 0 becomes a NoteOff with velocity 64, the release velocity
+	// the MIDI specification recommends for devices that do not sense it.
+	NoteOffStatus
+	// NoteOffZeroVelocity ends notes with NoteOn messages with velocity 0,
+	// which lets running status shorten streams of notes. Release velocity
+	// is lost.
+	NoteOffZeroVelocity
+)
+
+func (s NoteOffStyle) String() string {
+	switch s {
+	case NoteOffAsIs:
+		return "as is"
+	case NoteOffStatus:
+		return "NoteOff"
+	case NoteOffZeroVelocity:
+		return "NoteOn velocity 0"
+	}
+	return "?"
+}
+
+// Apply returns msg with a note off rewritten in style s. Other messages,
+// and note offs already in style s, are returned as they are; a rewritten
+// message is a new slice.
+func (s NoteOffStyle) Apply(msg []byte) []byte {
+	if !isNoteOff(msg) {
+		return msg
+	}
+	switch {
+	case s == NoteOffStatus && msg[0]&0xf0 == 0x90:
+		return []byte{0x80 | msg[0]&0x0f, msg[1], 64}
+	case s == NoteOffZeroVelocity && msg[0]&0xf0 == 0x80:
+		return []byte{0x90 | msg[0]&0x0f, msg[1], 0}
+	}
+	return msg
+}
+
+// Wrap returns a callback for MIDIIn.SetCallback that passes messages to cb
+// with their note offs in style s.
+func (s NoteOffStyle) Wrap(cb func(MIDIIn, []byte, float64)) func(MIDIIn, []byte, float64) {
+	if s == NoteOffAsIs {
+		return cb
+	}
+	return func(m MIDIIn, msg []byte, t float64) {
+		cb(m, s.Apply(msg), t)
+	}
+}
+
+// NoteOffNormalizer wraps a MIDIOut and sends every note off in one style,
+// including those of notes started with PlayNote.
+type NoteOffNormalizer struct {
+	MIDIOut
+	// Style is the style note offs are sent in.
+	Style NoteOffStyle
+
+	notes noteSet
+}
+
+// NewNoteOffNormalizer returns a NoteOffNormalizer sending to out in the
+// given style.
+func NewNoteOffNormalizer(out MIDIOut, style NoteOffStyle) *NoteOffNormalizer {
+	return &NoteOffNormalizer{MIDIOut: out, Style: style}
+}
+
+// SendMessage sends msg with its note off in the normalizer's style.
+func (n *NoteOffNormalizer) SendMessage(msg []byte) error {
+	return n.MIDIOut.SendMessage(n.Style.Apply(msg))
+}
+
+// SendMessageAt sends msg at the given time with its note off in the
+// normalizer's style.
+func (n *NoteOffNormalizer) SendMessageAt(msg []byte, at time.Time) error {
+	return SendMessageAt(n.MIDIOut, n.Style.Apply(msg), at)
+}
+
+// PlayNote plays a note through the normalizer, so that its NoteOff is
+// sent in the normalizer's style.
+func (n *NoteOffNormalizer) PlayNote(ch, key, vel int, d time.Duration) (*Note, error) {
+	return n.notes.play(n, ch, key, vel, d)
+}
+
+// Flush waits for the notes started with PlayNote to end, then for the
+// queues of the wrapped output.
+func (n *NoteOffNormalizer) Flush(ctx context.Context) error {
+	if err := n.notes.Flush(ctx); err != nil {
+		return err
+	}
+	return Flush(ctx, n.MIDIOut)
+}
+
+// setClock also sets the clock of the wrapped output, where it can be set.
+func (n *NoteOffNormalizer) setClock(c Clock) {
+	n.notes.setClock(c)
+	SetClock(n.MIDIOut, c)
+}
+
+// Close ends the notes started with PlayNote and closes the wrapped output.
+func (n *NoteOffNormalizer) Close() error {
+	n.notes.stopAll()
+	return n.MIDIOut.Close()
+}
@@ -0,0 +1,56 @@
+package rtmidi
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestNoteOffStyleApply(t *testing.T) {
+	for _, test := range []struct {
+		style    NoteOffStyle
+		msg, out []byte
+	}{
+		{NoteOffAsIs, []byte{0x91, 60, 0}, []byte{0x91, 60, 0}},
+		{NoteOffStatus, []byte{0x91, 60, 0}, []byte{0x81, 60, 64}},
+		{NoteOffStatus, []byte{0x81, 60, 10}, []byte{0x81, 60, 10}},
+		{NoteOffZeroVelocity, []byte{0x82, 60, 10}, []byte{0x92, 60, 0}},
+		{NoteOffZeroVelocity, []byte{0x90, 60, 100}, []byte{0x90, 60, 100}},
+		{NoteOffStatus, []byte{0xb0, 7, 0}, []byte{0xb0, 7, 0}},
+	} {
+		if out := test.style.Apply(test.msg); !bytes.Equal(out, test.out) {
+			t.Errorf("%v: Apply(% X) = % X, want % X", test.style, test.msg, out, test.out)
+		}
+	}
+}
+
+func TestNoteOffStyleWrap(t *testing.T) {
+	var got [][]byte
+	in := &fakeIn{}
+	in.SetCallback(NoteOffStatus.Wrap(func(m MIDIIn, msg []byte, ts float64) {
+		got = append(got, msg)
+	}))
+	in.deliver([]byte{0x90, 60, 100})
+	in.deliver([]byte{0x90, 60, 0})
+	want := [][]byte{{0x90, 60, 100}, {0x80, 60, 64}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got % X, want % X", got, want)
+	}
+}
+
+func TestNoteOffNormalizer(t *testing.T) {
+	out := &fakeOut{}
+	n := NewNoteOffNormalizer(out, NoteOffZeroVelocity)
+	n.SendMessage([]byte{0x80, 60, 0})
+	note, err := n.PlayNote(1, 62, 100, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	note.Stop()
+	n.PlayNote(2, 64, 100, 0)
+	n.Close()
+	want := [][]byte{{0x90, 60, 0}, {0x91, 62, 100}, {0x91, 62, 0}, {0x92, 64, 100}, {0x92, 64, 0}}
+	if got := out.messages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("sent % X, want % X", got, want)
+	}
+}
@@ -0,0 +1,158 @@
+package rtmidi
+
+import (
+	"fmt"
+)
+
+// Default client names and input queue size, as used by RtMidi.
+const (
+	DefaultInputClient  = "RtMidi Input Client"
+	DefaultOutputClient = "RtMidi Output Client"
+	DefaultQueueSize    = 100
+)
+
+// The constructors used by OpenAll, replaced in tests.
+var (
+	newMIDIIn  = NewMIDIIn
+	newMIDIOut = NewMIDIOut
+)
+
+// PortSpec describes a port to open with OpenAll.
+type PortSpec struct {
+	// Output selects an output port; the default is an input.
+	Output bool
+	// API is the MIDI API to use.
+	API API
+	// Client is the client name; empty means the RtMidi default.
+	Client string
+	// Port is the name of the port to connect to, matched with Aliases or
+	// FindPort. If empty, Index is used instead.
+	Port  string
+	Index int
+	// Virtual opens a virtual port called Name instead of connecting to an
+	// existing one.
+	Virtual bool
+	// Name is the name of our end of the connection.
+	Name string
+	// QueueSize is the input queue size; zero means DefaultQueueSize.
+	QueueSize int
+	// Aliases, if set, is used to resolve Port.
+	Aliases *PortAliases
+}
+
+func (s PortSpec) String() string {
+	dir := "input"
+	if s.Output {
+		dir = "output"
+	}
+	switch {
+	case s.Virtual:
+		return fmt.Sprintf("virtual %s %q", dir, s.Name)
+	case s.Port != "":
+		return fmt.Sprintf("%s %q", dir, s.Port)
+	}
+	return fmt.Sprintf("%s %d", dir, s.Index)
+}
+
+// Ports is a set of ports opened together by OpenAll, indexed like the
+// specs they were opened from.
+type Ports []MIDI
+
+// In returns the input opened from spec i, or nil if it is an output.
+func (p Ports) In(i int) MIDIIn {
+	in, _ := p[i].(MIDIIn)
+	return in
+}
+
+// Out returns the output opened from spec i, or nil if it is an input.
+func (p Ports) Out(i int) MIDIOut {
+	out, _ := p[i].(MIDIOut)
+	return out
+}
+
+// Close closes every port, in the reverse order they were opened,
+// returning the first error.
+func (p Ports) Close() error {
+	var first error
+	for i := len(p) - 1; i >= 0; i-- {
+		if err := p[i].Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// OpenAll opens every port described by specs. Either all of them are
+// opened, or none: if one fails, the ports already opened are closed again
+// and the error says which spec failed.
+func OpenAll(specs ...PortSpec) (Ports, error) {
+	p := make(Ports, 0, len(specs))
+	for i, spec := range specs {
+		m, err := openSpec(spec)
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("rtmidi: opening port %d (%v): %w", i, spec, err)
+		}
+		p = append(p, m)
+	}
+	return p, nil
+}
+
+// destroyer is the part of MIDIIn and MIDIOut releasing a port that was
+// never opened.
+type destroyer interface {
+	MIDI
+	Destroy()
+}
+
+func openSpec(spec PortSpec) (MIDI, error) {
+	m, err := createSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Virtual {
+		if err := m.OpenVirtualPort(spec.Name); err != nil {
+			m.Destroy()
+			return nil, err
+		}
+		return m, nil
+	}
+	index, err := spec.portIndex(m)
+	if err != nil {
+		m.Destroy()
+		return nil, err
+	}
+	if err := m.OpenPort(index, spec.Name); err != nil {
+		m.Destroy()
+		return nil, err
+	}
+	return m, nil
+}
+
+// createSpec creates the MIDIIn or MIDIOut for spec, without opening a
+// port.
+func createSpec(spec PortSpec) (destroyer, error) {
+	if spec.Output {
+		client := spec.Client
+		if client == "" {
+			client = DefaultOutputClient
+		}
+		return newMIDIOut(spec.API, client)
+	}
+	client, size := spec.Client, spec.QueueSize
+	if client == "" {
+		client = DefaultInputClient
+	}
+	if size == 0 {
+		size = DefaultQueueSize
+	}
+	return newMIDIIn(spec.API, client, size)
+}
+
+// portIndex returns the index of the port spec connects to, as listed by m.
+func (s PortSpec) portIndex(m Port) (int, error) {
+	if s.Port == "" {
+		return s.Index, nil
+	}
+	return s.Aliases.Find(m, s.Port)
+}
@@ -0,0 +1,68 @@
+package rtmidi
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// trackedOut is an output that records whether it was opened and closed.
+type trackedOut struct {
+	namedPorts
+	opened, closed, destroyed bool
+	fail                      bool
+}
+
+func (o *trackedOut) OpenPort(port int, name string) error {
+	if o.fail {
+		return errors.New("busy")
+	}
+	o.opened = true
+	return nil
+}
+
+func (o *trackedOut) Close() error { o.closed = true; return nil }
+func (o *trackedOut) Destroy()     { o.destroyed = true }
+
+func TestOpenAll(t *testing.T) {
+	defer func() { newMIDIOut = NewMIDIOut }()
+	var created []*trackedOut
+	newMIDIOut = func(api API, name string) (MIDIOut, error) {
+		o := &trackedOut{namedPorts: namedPorts{names: []string{"Synth:Synth MIDI 1 20:0", "Drums:Drums MIDI 1 24:0"}}}
+		o.fail = len(created) == 2
+		created = append(created, o)
+		return o, nil
+	}
+
+	p, err := OpenAll(PortSpec{Output: true, Port: "synth"}, PortSpec{Output: true, Port: "drums"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p) != 2 || p.Out(1) != created[1] || p.In(0) != nil {
+		t.Errorf("unexpected ports")
+	}
+	p.Close()
+
+	created = nil
+	_, err = OpenAll(
+		PortSpec{Output: true, Index: 0},
+		PortSpec{Output: true, Port: "drums"},
+		PortSpec{Output: true, Index: 1},
+	)
+	if err == nil || !strings.Contains(err.Error(), "port 2") {
+		t.Fatalf("OpenAll returned %v", err)
+	}
+	for i, o := range created[:2] {
+		if !o.opened || !o.closed {
+			t.Errorf("port %d not rolled back", i)
+		}
+	}
+	if !created[2].destroyed {
+		t.Error("failed port not destroyed")
+	}
+
+	created = nil
+	if _, err := OpenAll(PortSpec{Output: true, Port: "piano"}); err == nil || !created[0].destroyed {
+		t.Errorf("unmatched port: %v", err)
+	}
+}
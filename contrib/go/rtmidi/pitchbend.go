@@ -0,0 +1,199 @@
+package rtmidi
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+const (
+	// PitchBendMin is the lowest 14-bit pitch bend value.
+	PitchBendMin = 0
+	// PitchBendCenter is the 14-bit pitch bend value meaning no bend.
+	PitchBendCenter = 8192
+	// PitchBendMax is the highest 14-bit pitch bend value.
+	PitchBendMax = 16383
+
+	// DefaultPitchBendRange is the pitch bend sensitivity in semitones that
+	// General MIDI devices assume until told otherwise.
+	DefaultPitchBendRange = 2.0
+)
+
+// PitchBendToSemitones converts a 14-bit pitch bend value into an offset in
+// semitones for a device configured with the given bend range.
+func PitchBendToSemitones(bend int, bendRange float64) float64 {
+	d := bend - PitchBendCenter
+	if d >= 0 {
+		return float64(d) / float64(PitchBendMax-PitchBendCenter) * bendRange
+	}
+	return float64(d) / float64(PitchBendCenter) * bendRange
+}
+
+// SemitonesToPitchBend converts an offset in semitones into the 14-bit pitch
+// bend value for a device configured with the given bend range. Offsets beyond
+// the range are clamped.
+func SemitonesToPitchBend(semitones float64, bendRange float64) int {
+	if bendRange <= 0 {
+		return PitchBendCenter
+	}
+	r := semitones / bendRange
+	var v float64
+	if r >= 0 {
+		v = PitchBendCenter + r*float64(PitchBendMax-PitchBendCenter)
+	} else {
+		v = PitchBendCenter + r*float64(PitchBendCenter)
+	}
+	return clampPitchBend(int(math.Round(v)))
+}
+
+// PitchBendToCents converts a 14-bit pitch bend value into an offset in cents.
+func PitchBendToCents(bend int, bendRange float64) float64 {
+	return PitchBendToSemitones(bend, bendRange) * 100
+}
+
+// CentsToPitchBend converts an offset in cents into a 14-bit pitch bend value.
+func CentsToPitchBend(cents float64, bendRange float64) int {
+	return SemitonesToPitchBend(cents/100, bendRange)
+}
+
+// PitchBendMessage returns the pitch bend message for channel ch (0-15) with
+// the given 14-bit value.
+func PitchBendMessage(ch int, bend int) []byte {
+	bend = clampPitchBend(bend)
+	return []byte{0xe0 | byte(ch&0x0f), byte(bend & 0x7f), byte(bend >> 7 & 0x7f)}
+}
+
+func clampPitchBend(v int) int {
+	if v < PitchBendMin {
+		return PitchBendMin
+	}
+	if v > PitchBendMax {
+		return PitchBendMax
+	}
+	return v
+}
+
+// PitchBendRange keeps track of the pitch bend sensitivity of every MIDI
+// channel of a device and converts between bend values and pitch offsets
+// accordingly. A zero PitchBendRange assumes DefaultPitchBendRange on all
+// channels.
+type PitchBendRange struct {
+	mu     sync.Mutex
+	set    [16]bool
+	ranges [16]float64
+	rpnSel [16]bool
+	rpn    [16]uint16
+}
+
+// NewPitchBendRange returns a PitchBendRange with every channel set to
+// DefaultPitchBendRange.
+func NewPitchBendRange() *PitchBendRange {
+	return &PitchBendRange{}
+}
+
+// Range returns the bend range in semitones of channel ch (0-15).
+func (r *PitchBendRange) Range(ch int) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ch &= 0x0f
+	if !r.set[ch] {
+		return DefaultPitchBendRange
+	}
+	return r.ranges[ch]
+}
+
+// SetRange records the bend range of channel ch without sending anything.
+func (r *PitchBendRange) SetRange(ch int, semitones float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.set[ch&0x0f] = true
+	r.ranges[ch&0x0f] = semitones
+}
+
+// Send configures the bend range of channel ch on out using RPN 0 and
+// records it. The RPN is reset to null afterwards so that stray data entry
+// messages do not change the setting.
+func (r *PitchBendRange) Send(out MIDIOut, ch int, semitones int, cents int) error {
+	if ch < 0 || ch > 15 {
+		return fmt.Errorf("rtmidi: invalid channel %d", ch)
+	}
+	if semitones < 0 || semitones > 127 || cents < 0 || cents > 99 {
+		return fmt.Errorf("rtmidi: invalid pitch bend range %d semitones %d cents", semitones, cents)
+	}
+	status := 0xb0 | byte(ch)
+	for _, msg := range [][]byte{
+		{status, 101, 0},
+		{status, 100, 0},
+		{status, 6, byte(semitones)},
+		{status, 38, byte(cents)},
+		{status, 101, 127},
+		{status, 100, 127},
+	} {
+		if err := out.SendMessage(msg); err != nil {
+			return err
+		}
+	}
+	r.SetRange(ch, float64(semitones)+float64(cents)/100)
+	return nil
+}
+
+// Observe updates the tracked ranges from an RPN 0 exchange seen in a stream
+// of messages, such as those received on a MIDIIn.
+func (r *PitchBendRange) Observe(msg []byte) {
+	if len(msg) < 3 || msg[0]&0xf0 != 0xb0 {
+		return
+	}
+	ch := int(msg[0] & 0x0f)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch msg[1] {
+	case 101:
+		r.rpnSel[ch] = true
+		r.rpn[ch] = uint16(msg[2])<<7 | r.rpn[ch]&0x7f
+	case 100:
+		r.rpnSel[ch] = true
+		r.rpn[ch] = r.rpn[ch]&^0x7f | uint16(msg[2])
+	case 98, 99:
+		r.rpnSel[ch] = false
+	case 6, 38:
+		if !r.rpnSel[ch] || r.rpn[ch] != 0 {
+			return
+		}
+		cur := DefaultPitchBendRange
+		if r.set[ch] {
+			cur = r.ranges[ch]
+		}
+		semis, cents := math.Floor(cur), math.Round((cur-math.Floor(cur))*100)
+		if msg[1] == 6 {
+			semis = float64(msg[2])
+		} else {
+			cents = float64(msg[2])
+		}
+		r.set[ch] = true
+		r.ranges[ch] = semis + cents/100
+	}
+}
+
+// ToSemitones converts a 14-bit bend value received on channel ch into an
+// offset in semitones.
+func (r *PitchBendRange) ToSemitones(ch int, bend int) float64 {
+	return PitchBendToSemitones(bend, r.Range(ch))
+}
+
+// FromSemitones converts an offset in semitones into the 14-bit bend value
+// for channel ch.
+func (r *PitchBendRange) FromSemitones(ch int, semitones float64) int {
+	return SemitonesToPitchBend(semitones, r.Range(ch))
+}
+
+// ToCents converts a 14-bit bend value received on channel ch into an offset
+// in cents.
+func (r *PitchBendRange) ToCents(ch int, bend int) float64 {
+	return PitchBendToCents(bend, r.Range(ch))
+}
+
+// FromCents converts an offset in cents into the 14-bit bend value for
+// channel ch.
+func (r *PitchBendRange) FromCents(ch int, cents float64) int {
+	return CentsToPitchBend(cents, r.Range(ch))
+}
@@ -0,0 +1,82 @@
+package rtmidi
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestPitchBendConversion(t *testing.T) {
+	for _, test := range []struct {
+		bend      int
+		bendRange float64
+		semitones float64
+	}{
+		{PitchBendCenter, 2, 0},
+		{PitchBendMax, 2, 2},
+		{PitchBendMin, 2, -2},
+		{PitchBendMax, 12, 12},
+		{4096, 2, -1},
+		{PitchBendMin, 48, -48},
+	} {
+		if s := PitchBendToSemitones(test.bend, test.bendRange); math.Abs(s-test.semitones) > 1e-9 {
+			t.Errorf("PitchBendToSemitones(%d, %v) = %v, want %v", test.bend, test.bendRange, s, test.semitones)
+		}
+		if b := SemitonesToPitchBend(test.semitones, test.bendRange); b != test.bend {
+			t.Errorf("SemitonesToPitchBend(%v, %v) = %d, want %d", test.semitones, test.bendRange, b, test.bend)
+		}
+	}
+	if b := CentsToPitchBend(500, 2); b != PitchBendMax {
+		t.Errorf("out of range bend not clamped: %d", b)
+	}
+	if c := PitchBendToCents(4096, 2); c != -100 {
+		t.Errorf("PitchBendToCents(4096, 2) = %v, want -100", c)
+	}
+}
+
+func TestPitchBendMessage(t *testing.T) {
+	if m := PitchBendMessage(3, PitchBendCenter); !reflect.DeepEqual(m, []byte{0xe3, 0x00, 0x40}) {
+		t.Errorf("PitchBendMessage = % x", m)
+	}
+}
+
+func TestPitchBendRangeSend(t *testing.T) {
+	out := &fakeOut{}
+	r := NewPitchBendRange()
+	if err := r.Send(out, 1, 12, 50); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]byte{
+		{0xb1, 101, 0}, {0xb1, 100, 0}, {0xb1, 6, 12}, {0xb1, 38, 50},
+		{0xb1, 101, 127}, {0xb1, 100, 127},
+	}
+	if got := out.messages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("sent %v, want %v", got, want)
+	}
+	if rng := r.Range(1); rng != 12.5 {
+		t.Errorf("Range(1) = %v, want 12.5", rng)
+	}
+	if rng := r.Range(0); rng != DefaultPitchBendRange {
+		t.Errorf("Range(0) = %v, want default", rng)
+	}
+	if err := r.Send(out, 16, 2, 0); err == nil {
+		t.Error("expected error for invalid channel")
+	}
+}
+
+func TestPitchBendRangeObserve(t *testing.T) {
+	r := NewPitchBendRange()
+	r.Observe([]byte{0xb2, 6, 24})
+	if rng := r.Range(2); rng != DefaultPitchBendRange {
+		t.Errorf("data entry without RPN changed range to %v", rng)
+	}
+	for _, m := range [][]byte{{0xb2, 101, 0}, {0xb2, 100, 0}, {0xb2, 6, 24}} {
+		r.Observe(m)
+	}
+	if rng := r.Range(2); rng != 24 {
+		t.Errorf("Range(2) = %v, want 24", rng)
+	}
+	if b := r.FromSemitones(2, 24); b != PitchBendMax {
+		t.Errorf("FromSemitones(2, 24) = %d", b)
+	}
+}
@@ -0,0 +1,360 @@
+package rtmidi
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type playerEvent struct {
+	tick int
+	msg  []byte
+}
+
+// Player plays a Standard MIDI File to a MIDIOut, either at the tempo of
+// the file or following an external MIDI clock, see Follow.
+//
+// Stopping or locating sends a NoteOff for every note left sounding, and
+// locating chases the program changes, controllers and pitch bend set
+// before the new position.
+type Player struct {
+	out    MIDIOut
+	tempo  *TempoMap
+	events []playerEvent
+
+	mu        sync.Mutex
+	sendMu    sync.Mutex
+	running   bool
+	pos       int
+	startTick float64
+	startTime time.Time
+	held      map[uint16]bool
+	err       func(error)
+
+	following bool
+	base      float64
+	pulseAt   time.Time
+	interval  time.Duration
+
+	clock   Clock
+	clockID int
+
+	wake   chan struct{}
+	quit   chan struct{}
+	done   chan struct{}
+	closed bool
+}
+
+// NewPlayer returns a stopped Player for the tracks of smf, positioned at
+// the start.
+func NewPlayer(out MIDIOut, smf *SMF) *Player {
+	p := &Player{
+		out:   out,
+		tempo: smf.Tempo,
+		held:  map[uint16]bool{},
+		clock: SystemClock,
+		wake:  make(chan struct{}, 1),
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	for _, t := range smf.Tracks {
+		for _, ev := range t {
+			if len(ev.Message) == 0 || ev.Message[0] == 0xff {
+				continue
+			}
+			p.events = append(p.events, playerEvent{smf.Tempo.Tick(ev.Time), ev.Message})
+		}
+	}
+	sort.SliceStable(p.events, func(i, j int) bool { return p.events[i].tick < p.events[j].tick })
+	go p.loop()
+	return p
+}
+
+// SetErrorHandler sets a function called with errors from sending
+// messages, which are otherwise ignored.
+func (p *Player) SetErrorHandler(fn func(error)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = fn
+}
+
+// SetClock sets the clock the player plays by, SystemClock by default. The
+// position is kept.
+func (p *Player) SetClock(c Clock) {
+	p.mu.Lock()
+	tick := p.tickLocked(p.clock.Now())
+	p.clock = clockOr(c)
+	p.clockID++
+	p.startTick, p.startTime = tick, p.clock.Now()
+	p.base, p.pulseAt = tick, time.Time{}
+	p.mu.Unlock()
+	p.kick()
+}
+
+// Play starts playing from the current position. While following a clock,
+// playback starts with the clock instead.
+func (p *Player) Play() {
+	p.mu.Lock()
+	if !p.running && !p.following {
+		p.running = true
+		p.startTime = p.clock.Now()
+	}
+	p.mu.Unlock()
+	p.kick()
+}
+
+// Stop stops playing, keeping the position.
+func (p *Player) Stop() {
+	p.mu.Lock()
+	msgs := p.haltLocked(p.clock.Now())
+	p.sendLocked(msgs)
+}
+
+// Locate moves to a position in quarter notes from the start.
+func (p *Player) Locate(beats float64) {
+	p.mu.Lock()
+	msgs := p.locateLocked(beats*float64(p.tempo.PPQN), p.clock.Now())
+	p.sendLocked(msgs)
+	p.kick()
+}
+
+// Position returns the current position in quarter notes from the start.
+func (p *Player) Position() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.tickLocked(p.clock.Now()) / float64(p.tempo.PPQN)
+}
+
+// Playing reports whether the player is playing.
+func (p *Player) Playing() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.running
+}
+
+// Follow makes the player follow a ClockFollower instead of its own clock:
+// it starts, stops and continues with the transport, chases Song Position
+// Pointer locates and plays at the rate of the incoming clock, placing
+// events between clock pulses using the follower's smoothed tempo.
+// Calling the returned function stops following and stops playback.
+func (p *Player) Follow(f *ClockFollower) (cancel func()) {
+	p.mu.Lock()
+	p.following = true
+	p.mu.Unlock()
+	cancelPulse := f.OnPulse(func(pulse int) { p.pulse(pulse, f.Tempo()) })
+	cancelTransport := f.OnTransport(p.transport)
+	return func() {
+		cancelPulse()
+		cancelTransport()
+		p.mu.Lock()
+		p.following = false
+		msgs := p.haltLocked(p.clock.Now())
+		p.sendLocked(msgs)
+	}
+}
+
+func (p *Player) ticksPerPulse() float64 {
+	return float64(p.tempo.PPQN) / ClocksPerQuarter
+}
+
+func (p *Player) pulse(n int, bpm float64) {
+	p.mu.Lock()
+	now := p.clock.Now()
+	tick := float64(n) * p.ticksPerPulse()
+	var msgs [][]byte
+	if d := tick - p.tickLocked(now); !p.running || d > p.ticksPerPulse() || d < -p.ticksPerPulse() {
+		// Joined a running clock or missed pulses: chase the new position.
+		msgs = p.locateLocked(tick, now)
+		p.running = true
+	}
+	p.base, p.pulseAt = tick, now
+	if bpm > 0 {
+		p.interval = time.Duration(float64(time.Minute) / (bpm * ClocksPerQuarter))
+	}
+	p.sendLocked(msgs)
+	p.kick()
+}
+
+func (p *Player) transport(ev TransportEvent) {
+	p.mu.Lock()
+	now := p.clock.Now()
+	tick := ev.Position * float64(p.tempo.PPQN)
+	var msgs [][]byte
+	if ev.State == TransportPlaying {
+		if tick != p.tickLocked(now) {
+			msgs = p.locateLocked(tick, now)
+		}
+		if !p.running {
+			p.running = true
+			p.base, p.pulseAt = tick, time.Time{}
+		}
+	} else if p.running {
+		msgs = p.haltLocked(now)
+		p.startTick = tick
+	} else if tick != p.startTick {
+		// Song Position Pointer while stopped.
+		msgs = p.locateLocked(tick, now)
+	}
+	p.sendLocked(msgs)
+	p.kick()
+}
+
+// tickLocked returns the playback position in ticks at now.
+func (p *Player) tickLocked(now time.Time) float64 {
+	if !p.running {
+		return p.startTick
+	}
+	if p.following {
+		tick := p.base
+		if !p.pulseAt.IsZero() && p.interval > 0 {
+			frac := float64(now.Sub(p.pulseAt)) / float64(p.interval)
+			if frac > 0.999 {
+				// Never run ahead of the next pulse.
+				frac = 0.999
+			}
+			tick += frac * p.ticksPerPulse()
+		}
+		return tick
+	}
+	start := p.tempo.Duration(int(p.startTick))
+	return float64(p.tempo.Tick(start + now.Sub(p.startTime)))
+}
+
+// wakeLocked returns when the event at tick is due, or false if that is not
+// known yet.
+func (p *Player) wakeLocked(tick int) (time.Time, bool) {
+	if p.following {
+		frac := (float64(tick) - p.base) / p.ticksPerPulse()
+		if p.pulseAt.IsZero() || p.interval <= 0 || frac >= 1 {
+			return time.Time{}, false
+		}
+		return p.pulseAt.Add(time.Duration(frac * float64(p.interval))), true
+	}
+	d := p.tempo.Duration(tick) - p.tempo.Duration(int(p.startTick))
+	return p.startTime.Add(d), true
+}
+
+// haltLocked stops playback and returns the NoteOffs of the sounding notes.
+func (p *Player) haltLocked(now time.Time) [][]byte {
+	if p.running {
+		p.startTick = p.tickLocked(now)
+		p.running = false
+	}
+	return p.notesOffLocked()
+}
+
+func (p *Player) notesOffLocked() [][]byte {
+	var msgs [][]byte
+	for k := range p.held {
+		msgs = append(msgs, []byte{0x80 | byte(k>>7), byte(k & 0x7f), 0})
+	}
+	sort.Slice(msgs, func(i, j int) bool {
+		return msgs[i][0] < msgs[j][0] || msgs[i][0] == msgs[j][0] && msgs[i][1] < msgs[j][1]
+	})
+	p.held = map[uint16]bool{}
+	return msgs
+}
+
+// locateLocked moves to tick and returns the NoteOffs of the sounding notes
+// followed by the messages chasing the state at tick.
+func (p *Player) locateLocked(tick float64, now time.Time) [][]byte {
+	msgs := p.notesOffLocked()
+	pos := sort.Search(len(p.events), func(i int) bool { return float64(p.events[i].tick) >= tick })
+	state := newControllerState()
+	for _, ev := range p.events[:pos] {
+		state.apply(ev.msg)
+	}
+	for ch := 0; ch < 16; ch++ {
+		msgs = append(msgs, state.messages(ch)...)
+	}
+	p.pos, p.startTick, p.startTime = pos, tick, now
+	p.base, p.pulseAt = tick, time.Time{}
+	return msgs
+}
+
+// sendLocked sends msgs and unlocks p.mu. Sends are serialized in the order
+// their messages were taken under p.mu, so a NoteOff from stopping cannot
+// overtake the NoteOn it ends.
+func (p *Player) sendLocked(msgs [][]byte) {
+	errFn := p.err
+	p.sendMu.Lock()
+	p.mu.Unlock()
+	defer p.sendMu.Unlock()
+	for _, msg := range msgs {
+		if err := p.out.SendMessage(msg); err != nil && errFn != nil {
+			errFn(err)
+		}
+	}
+}
+
+func (p *Player) kick() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops the player, sending NoteOffs for any notes left sounding.
+func (p *Player) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	msgs := p.haltLocked(p.clock.Now())
+	p.sendLocked(msgs)
+	close(p.quit)
+	<-p.done
+}
+
+func (p *Player) loop() {
+	defer close(p.done)
+	var timer Timer
+	timerClock := -1
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		wait := time.Hour
+		p.mu.Lock()
+		clock, clockID := p.clock, p.clockID
+		now := clock.Now()
+		var msgs [][]byte
+		if p.running {
+			cur := p.tickLocked(now)
+			for p.pos < len(p.events) && float64(p.events[p.pos].tick) <= cur {
+				msg := p.events[p.pos].msg
+				p.pos++
+				if isNoteOn(msg) {
+					p.held[uint16(msg[0]&0x0f)<<7|uint16(msg[1]&0x7f)] = true
+				} else if isNoteOff(msg) {
+					delete(p.held, uint16(msg[0]&0x0f)<<7|uint16(msg[1]&0x7f))
+				}
+				msgs = append(msgs, msg)
+			}
+			if p.pos < len(p.events) {
+				if at, ok := p.wakeLocked(p.events[p.pos].tick); ok {
+					wait = at.Sub(now)
+				}
+			} else if !p.following {
+				p.running = false
+				p.startTick = cur
+			}
+		}
+		p.sendLocked(msgs)
+
+		if wait < 0 {
+			wait = 0
+		}
+		timer, timerClock = resetTimer(timer, timerClock, clock, clockID, wait)
+		select {
+		case <-p.wake:
+		case <-timer.C():
+		case <-p.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package rtmidi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// waitMessages waits until out has received n messages.
+func waitMessages(t *testing.T, out *fakeOut, n int) [][]byte {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		msgs := out.messages()
+		if len(msgs) >= n || time.Now().After(deadline) {
+			return msgs
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPlayer(t *testing.T) {
+	smf := &SMF{Tempo: NewTempoMap(96, 600), Tracks: []Track{{
+		{Time: 0, Message: []byte{0xc0, 5}},
+		{Time: 0, Message: []byte{0x90, 60, 100}},
+		{Time: 50 * time.Millisecond, Message: []byte{0x80, 60, 0}},
+		{Time: 100 * time.Millisecond, Message: []byte{0x90, 62, 100}},
+		{Time: time.Second, Message: []byte{0x80, 62, 0}},
+	}}}
+	out := &fakeOut{}
+	p := NewPlayer(out, smf)
+	defer p.Close()
+
+	start := time.Now()
+	p.Play()
+	got := waitMessages(t, out, 4)
+	if time.Since(start) < 100*time.Millisecond {
+		t.Error("events played early")
+	}
+	p.Stop()
+	want := [][]byte{{0xc0, 5}, {0x90, 60, 100}, {0x80, 60, 0}, {0x90, 62, 100}, {0x80, 62, 0}}
+	if got = out.messages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("played % x\nwant % x", got, want)
+	}
+	if p.Playing() {
+		t.Error("still playing after Stop")
+	}
+
+	// Locating chases the program change.
+	n := len(out.messages())
+	p.Locate(1)
+	if got := out.messages()[n:]; !reflect.DeepEqual(got, [][]byte{{0xc0, 5}}) {
+		t.Errorf("Locate sent % x", got)
+	}
+	if pos := p.Position(); pos != 1 {
+		t.Errorf("Position() = %v, want 1", pos)
+	}
+}
+
+func TestPlayerFollow(t *testing.T) {
+	// 24 PPQN makes a tick per clock pulse.
+	smf := &SMF{Tempo: NewTempoMap(24, 120), Tracks: []Track{{
+		{Time: 0, Message: []byte{0xc0, 7}},
+		{Time: 0, Message: []byte{0x90, 60, 100}},
+		{Time: TicksToDuration(2, 24, 120), Message: []byte{0x80, 60, 0}},
+		{Time: TicksToDuration(48, 24, 120), Message: []byte{0x90, 64, 100}},
+	}}}
+	out := &fakeOut{}
+	p := NewPlayer(out, smf)
+	defer p.Close()
+	f := NewClockFollower(0)
+	cancel := p.Follow(f)
+	defer cancel()
+
+	f.Feed([]byte{statusStart})
+	if len(out.messages()) != 0 {
+		t.Error("events played before the first clock")
+	}
+	f.Feed([]byte{statusClock})
+	waitMessages(t, out, 2)
+	f.Feed([]byte{statusClock})
+	f.Feed([]byte{statusClock})
+	got := waitMessages(t, out, 3)
+	want := [][]byte{{0xc0, 7}, {0x90, 60, 100}, {0x80, 60, 0}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("played % x\nwant % x", got, want)
+	}
+
+	// Stop, then locate to beat 2 with a Song Position Pointer: the
+	// program change is chased, and the next clock plays the note there.
+	f.Feed([]byte{statusStop})
+	f.Feed(SongPositionMessage(8))
+	f.Feed([]byte{statusContinue})
+	f.Feed([]byte{statusClock})
+	got = waitMessages(t, out, 5)
+	if !reflect.DeepEqual(got[3:], [][]byte{{0xc0, 7}, {0x90, 64, 100}}) {
+		t.Errorf("after locate played % x", got[3:])
+	}
+	f.Feed([]byte{statusStop})
+	if got := out.messages(); !reflect.DeepEqual(got[len(got)-1], []byte{0x80, 64, 0}) {
+		t.Errorf("Stop did not end the sounding note: % x", got)
+	}
+}
@@ -0,0 +1,136 @@
+package rtmidi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Note is a note started with PlayNote. Its NoteOff is sent exactly
+// once: when Stop is called, when its duration elapses or when the port is
+// closed, whichever comes first.
+type Note struct {
+	Channel, Key int
+
+	out  MIDIOut
+	set  *noteSet
+	once sync.Once
+	err  error
+	done chan struct{}
+
+	// timer and stopped are guarded by the mutex of set.
+	timer   funcTimer
+	stopped bool
+}
+
+// Stop sends the NoteOff if it has not been sent yet. It returns the error
+// from sending it, if any.
+func (n *Note) Stop() error {
+	n.once.Do(func() {
+		n.set.mu.Lock()
+		n.stopped = true
+		timer := n.timer
+		n.set.mu.Unlock()
+		if timer != nil {
+			timer.Stop()
+		}
+		n.err = n.out.SendMessage([]byte{0x80 | byte(n.Channel), byte(n.Key), 0})
+		n.set.remove(n)
+		close(n.done)
+	})
+	return n.err
+}
+
+// Done returns a channel that is closed once the NoteOff has been sent.
+func (n *Note) Done() <-chan struct{} {
+	return n.done
+}
+
+// noteSet keeps track of the notes playing on an output.
+type noteSet struct {
+	mu      sync.Mutex
+	notes   map[*Note]struct{}
+	emptied chan struct{}
+	detach  func()
+	clock   Clock
+}
+
+// setClock sets the clock timing the durations of notes played later.
+func (s *noteSet) setClock(c Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+// play sends a NoteOn to out and returns its handle. A zero duration holds
+// the note until it is stopped or the port closed.
+func (s *noteSet) play(out MIDIOut, ch, key, vel int, d time.Duration) (*Note, error) {
+	if ch < 0 || ch > 15 || key < 0 || key > 127 || vel < 1 || vel > 127 {
+		return nil, fmt.Errorf("rtmidi: invalid note channel %d key %d velocity %d", ch, key, vel)
+	}
+	n := &Note{Channel: ch, Key: key, out: out, set: s, done: make(chan struct{})}
+	s.mu.Lock()
+	if s.notes == nil {
+		s.notes = map[*Note]struct{}{}
+		s.detach = attachQueue(out, s)
+	}
+	s.notes[n] = struct{}{}
+	clock := clockOr(s.clock)
+	s.mu.Unlock()
+	if err := out.SendMessage([]byte{0x90 | byte(ch), byte(key), byte(vel)}); err != nil {
+		s.remove(n)
+		return nil, err
+	}
+	if d > 0 {
+		s.mu.Lock()
+		if !n.stopped {
+			n.timer = afterFunc(clock, d, func() { n.Stop() })
+		}
+		s.mu.Unlock()
+	}
+	return n, nil
+}
+
+func (s *noteSet) remove(n *Note) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.notes, n)
+	if len(s.notes) == 0 && s.emptied != nil {
+		close(s.emptied)
+		s.emptied = nil
+	}
+}
+
+// Flush waits until every playing note has ended or ctx is done.
+func (s *noteSet) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	if len(s.notes) == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	if s.emptied == nil {
+		s.emptied = make(chan struct{})
+	}
+	emptied := s.emptied
+	s.mu.Unlock()
+	select {
+	case <-emptied:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stopAll sends the NoteOff of every playing note.
+func (s *noteSet) stopAll() {
+	s.mu.Lock()
+	notes := make([]*Note, 0, len(s.notes))
+	for n := range s.notes {
+		notes = append(notes, n)
+	}
+	s.mu.Unlock()
+	for _, n := range notes {
+		n.Stop()
+	}
+}
@@ -0,0 +1,78 @@
+package rtmidi
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPlayNote(t *testing.T) {
+	out := &fakeOut{}
+	n, err := out.PlayNote(2, 60, 100, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Stop()
+	n.Stop()
+	<-n.Done()
+	want := [][]byte{{0x92, 60, 100}, {0x82, 60, 0}}
+	if got := out.messages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("sent %v, want %v", got, want)
+	}
+	if _, err := out.PlayNote(0, 60, 0, 0); err == nil {
+		t.Error("zero velocity accepted")
+	}
+}
+
+func TestPlayNoteDuration(t *testing.T) {
+	out := &fakeOut{}
+	start := time.Now()
+	out.PlayNote(0, 60, 100, 20*time.Millisecond)
+	out.PlayNote(0, 64, 100, 10*time.Millisecond)
+	if err := out.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Error("Flush returned before the notes ended")
+	}
+	if n := len(out.messages()); n != 4 {
+		t.Errorf("sent %d messages, want 4", n)
+	}
+}
+
+func TestPlayNoteClose(t *testing.T) {
+	out := &fakeOut{}
+	n, _ := out.PlayNote(0, 60, 100, time.Hour)
+	out.Close()
+	select {
+	case <-n.Done():
+	default:
+		t.Fatal("note still playing after Close")
+	}
+	if err := n.Stop(); err != nil {
+		t.Error(err)
+	}
+	if n := len(out.messages()); n != 2 {
+		t.Errorf("sent %d messages, want 2", n)
+	}
+}
+
+func TestPlayNoteStoppedWhileStarting(t *testing.T) {
+	out := &fakeOut{}
+	out.onSend = func(msg []byte) {
+		if isNoteOn(msg) {
+			out.notes.stopAll()
+		}
+	}
+	n, err := out.PlayNote(0, 60, 100, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-n.Done()
+	out.notes.mu.Lock()
+	defer out.notes.mu.Unlock()
+	if n.timer != nil {
+		t.Error("timer armed for a note already stopped")
+	}
+}
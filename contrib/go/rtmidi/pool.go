@@ -0,0 +1,207 @@
+package rtmidi
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// portRenamer is implemented by ports that can be renamed while open.
+type portRenamer interface {
+	SetPortName(name string) error
+}
+
+// PortPool is a set of virtual ports of one direction opened under a
+// single client name, such as the busses "MyApp Bus 1" to "MyApp Bus 8" an
+// application offers to a DAW. Ports are numbered from 0 in the order they
+// were added; removing a port renumbers the ports after it.
+type PortPool struct {
+	mu     sync.Mutex
+	spec   PortSpec
+	prefix string
+	ports  []MIDI
+	names  []string
+	cb     func(int, MIDIIn, []byte, float64)
+}
+
+// NewPortPool opens n virtual ports called prefix followed by their number
+// from 1, such as "MyApp Bus 1". The ports are outputs if output is set,
+// inputs otherwise, and are opened with the given API and client name, as
+// for a PortSpec. If a port fails to open the ones already opened are
+// closed again.
+func NewPortPool(api API, client, prefix string, output bool, n int) (*PortPool, error) {
+	p := &PortPool{
+		spec:   PortSpec{Output: output, API: api, Client: client, Virtual: true},
+		prefix: prefix,
+	}
+	for i := 0; i < n; i++ {
+		if _, err := p.Add(""); err != nil {
+			p.Close()
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Len returns the number of ports.
+func (p *PortPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.ports)
+}
+
+// Name returns the name of port i.
+func (p *PortPool) Name(i int) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.names[i]
+}
+
+// In returns port i of a pool of inputs, or nil for a pool of outputs.
+func (p *PortPool) In(i int) MIDIIn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	in, _ := p.ports[i].(MIDIIn)
+	return in
+}
+
+// Out returns port i of a pool of outputs, or nil for a pool of inputs.
+func (p *PortPool) Out(i int) MIDIOut {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out, _ := p.ports[i].(MIDIOut)
+	return out
+}
+
+// SetCallback sets a callback receiving the messages of every input of the
+// pool, along with the number of the port they arrived on. It is kept for
+// ports added or reopened later.
+func (p *PortPool) SetCallback(cb func(port int, m MIDIIn, msg []byte, t float64)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.spec.Output {
+		return errors.New("rtmidi: SetCallback on a pool of outputs")
+	}
+	p.cb = cb
+	for _, m := range p.ports {
+		if err := p.setCallbackLocked(m.(MIDIIn)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setCallbackLocked makes in deliver to the pool's callback. The port
+// number is looked up on delivery, as removing ports renumbers them.
+func (p *PortPool) setCallbackLocked(in MIDIIn) error {
+	if p.cb == nil {
+		return nil
+	}
+	return in.SetCallback(func(m MIDIIn, msg []byte, t float64) {
+		p.mu.Lock()
+		i, cb := p.indexLocked(in), p.cb
+		p.mu.Unlock()
+		if i >= 0 && cb != nil {
+			cb(i, m, msg, t)
+		}
+	})
+}
+
+func (p *PortPool) indexLocked(m MIDI) int {
+	for i, x := range p.ports {
+		if x == m {
+			return i
+		}
+	}
+	return -1
+}
+
+// Add opens a port called name at the end of the pool and returns its
+// number. An empty name means the pool's prefix followed by the port's
+// number from 1.
+func (p *PortPool) Add(name string) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if name == "" {
+		name = fmt.Sprintf("%s %d", p.prefix, len(p.ports)+1)
+	}
+	m, err := p.openLocked(name)
+	if err != nil {
+		return -1, err
+	}
+	p.ports = append(p.ports, m)
+	p.names = append(p.names, name)
+	return len(p.ports) - 1, nil
+}
+
+func (p *PortPool) openLocked(name string) (MIDI, error) {
+	spec := p.spec
+	spec.Name = name
+	m, err := openSpec(spec)
+	if err != nil {
+		return nil, fmt.Errorf("rtmidi: opening %v: %w", spec, err)
+	}
+	if in, ok := m.(MIDIIn); ok {
+		if err := p.setCallbackLocked(in); err != nil {
+			m.Close()
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Remove closes port i and removes it from the pool.
+func (p *PortPool) Remove(i int) error {
+	p.mu.Lock()
+	if i < 0 || i >= len(p.ports) {
+		p.mu.Unlock()
+		return fmt.Errorf("rtmidi: no port %d in pool", i)
+	}
+	m := p.ports[i]
+	p.ports = append(p.ports[:i:i], p.ports[i+1:]...)
+	p.names = append(p.names[:i:i], p.names[i+1:]...)
+	p.mu.Unlock()
+	return m.Close()
+}
+
+// Rename renames port i. Where the API cannot rename an open port the port
+// is closed and opened again under the new name, which drops the
+// connections made to it; the Out or In of the port then changes, while a
+// callback set with SetCallback carries over.
+func (p *PortPool) Rename(i int, name string) error {
+	p.mu.Lock()
+	if i < 0 || i >= len(p.ports) {
+		p.mu.Unlock()
+		return fmt.Errorf("rtmidi: no port %d in pool", i)
+	}
+	if r, ok := p.ports[i].(portRenamer); ok && r.SetPortName(name) == nil {
+		p.names[i] = name
+		p.mu.Unlock()
+		return nil
+	}
+	m, err := p.openLocked(name)
+	if err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	old := p.ports[i]
+	p.ports[i], p.names[i] = m, name
+	p.mu.Unlock()
+	// Closed unlocked, as closing an input may wait for its callback.
+	return old.Close()
+}
+
+// Close closes every port of the pool, returning the first error.
+func (p *PortPool) Close() error {
+	p.mu.Lock()
+	ports := p.ports
+	p.ports, p.names = nil, nil
+	p.mu.Unlock()
+	var first error
+	for i := len(ports) - 1; i >= 0; i-- {
+		if err := ports[i].Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,110 @@
+package rtmidi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// virtualIn is an input recording the name of its virtual port.
+type virtualIn struct {
+	fakeIn
+	name   string
+	closed bool
+}
+
+func (v *virtualIn) OpenVirtualPort(name string) error { v.name = name; return nil }
+func (v *virtualIn) Close() error                      { v.closed = true; return nil }
+
+// renamableIn is an input whose port can be renamed while open.
+type renamableIn struct {
+	virtualIn
+}
+
+func (r *renamableIn) SetPortName(name string) error { r.name = name; return nil }
+
+func TestPortPool(t *testing.T) {
+	defer func() { newMIDIIn = NewMIDIIn }()
+	var created []*virtualIn
+	newMIDIIn = func(api API, client string, size int) (MIDIIn, error) {
+		if client != "MyApp" {
+			t.Errorf("client %q", client)
+		}
+		v := &virtualIn{}
+		created = append(created, v)
+		return v, nil
+	}
+
+	p, err := NewPortPool(APIDummy, "MyApp", "MyApp Bus", false, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Len() != 3 || created[2].name != "MyApp Bus 3" || p.Out(0) != nil {
+		t.Fatalf("pool of %d, last port %q", p.Len(), created[2].name)
+	}
+
+	var got []int
+	p.SetCallback(func(port int, m MIDIIn, msg []byte, ts float64) { got = append(got, port) })
+	created[1].deliver([]byte{0xf8})
+
+	if err := p.Remove(0); err != nil || !created[0].closed || p.Name(0) != "MyApp Bus 2" {
+		t.Fatalf("Remove: %v, names %q", err, p.Name(0))
+	}
+	created[1].deliver([]byte{0xf8})
+
+	// The fake cannot rename an open port, so it is reopened.
+	if err := p.Rename(0, "Drums"); err != nil {
+		t.Fatal(err)
+	}
+	if !created[1].closed || created[3].name != "Drums" || p.In(0) != created[3] {
+		t.Fatalf("port not reopened")
+	}
+	created[3].deliver([]byte{0xf8})
+
+	if i, err := p.Add(""); err != nil || i != 2 || created[4].name != "MyApp Bus 3" {
+		t.Fatalf("Add: %d, %v", i, err)
+	}
+	created[4].deliver([]byte{0xf8})
+
+	if want := []int{1, 0, 0, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("callback ports %v, want %v", got, want)
+	}
+	p.Close()
+	if !created[2].closed || !created[4].closed {
+		t.Error("ports left open")
+	}
+}
+
+func TestPortPoolRenameInPlace(t *testing.T) {
+	defer func() { newMIDIIn = NewMIDIIn }()
+	r := &renamableIn{}
+	newMIDIIn = func(api API, client string, size int) (MIDIIn, error) { return r, nil }
+	p, err := NewPortPool(APIDummy, "MyApp", "Bus", false, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Rename(0, "Keys"); err != nil || r.name != "Keys" || r.closed || p.Name(0) != "Keys" {
+		t.Errorf("Rename: %v, port %q closed %v", err, r.name, r.closed)
+	}
+}
+
+func TestPortPoolRollback(t *testing.T) {
+	defer func() { newMIDIOut = NewMIDIOut }()
+	var created []*trackedOut
+	newMIDIOut = func(api API, name string) (MIDIOut, error) {
+		if len(created) == 2 {
+			return nil, errors.New("out of ports")
+		}
+		o := &trackedOut{}
+		created = append(created, o)
+		return o, nil
+	}
+	if _, err := NewPortPool(APIDummy, "MyApp", "Bus", true, 4); err == nil {
+		t.Fatal("no error")
+	}
+	for i, o := range created {
+		if !o.closed {
+			t.Errorf("port %d not closed", i)
+		}
+	}
+}
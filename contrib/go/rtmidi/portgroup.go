@@ -0,0 +1,162 @@
+package rtmidi
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// PortGroup opens ports under one client name and API, and closes them
+// together.
+//
+// A group does not share a backend client between its ports: on ALSA and
+// JACK, RtMidi makes every port a client of its own, and the ports of a
+// group are listed as separate clients of the same name.
+//
+// The ports of a group can be moved to another API while the program runs
+// with SetAPI, such as from ALSA to JACK once a JACK server starts.
+type PortGroup struct {
+	api  API
+	name string
+
+	mu     sync.Mutex
+	ports  []groupPort
+	closed bool
+}
+
+// NewPortGroup returns a group opening ports with the given API under the
+// given client name.
+func NewPortGroup(api API, name string) *PortGroup {
+	return &PortGroup{api: api, name: name}
+}
+
+// API returns the API the group opens ports with.
+func (c *PortGroup) API() API {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.api
+}
+
+// Name returns the client name.
+func (c *PortGroup) Name() string {
+	return c.name
+}
+
+// OpenIn opens the input described by spec in the group. The API and
+// Client of spec are replaced by those of the group.
+//
+// The input returned keeps its callback, the types it ignores, its
+// realtime and warning settings and the ports it is connected to when
+// SetAPI reopens it. Its callback is passed the input returned rather than
+// the port of the backend.
+func (c *PortGroup) OpenIn(spec PortSpec) (MIDIIn, error) {
+	spec.Output = false
+	m, err := c.open(spec, func(spec PortSpec, m MIDI) groupPort {
+		return &groupIn{group: c, spec: spec, in: m.(MIDIIn)}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m.(MIDIIn), nil
+}
+
+// OpenOut opens the output described by spec in the group, see OpenIn.
+//
+// The output returned keeps its warning settings and the ports it is
+// connected to when SetAPI reopens it. It also keeps the messages given to
+// SendMessageAt that are still to be sent, and sends the new port the last
+// controller, program change and pitch bend values sent on each channel.
+func (c *PortGroup) OpenOut(spec PortSpec) (MIDIOut, error) {
+	spec.Output = true
+	m, err := c.open(spec, func(spec PortSpec, m MIDI) groupPort {
+		return &groupOut{group: c, spec: spec, out: m.(MIDIOut), state: newControllerState()}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m.(MIDIOut), nil
+}
+
+func (c *PortGroup) open(spec PortSpec, handle func(PortSpec, MIDI) groupPort) (groupPort, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	spec.API, spec.Client = c.api, c.name
+	if c.closed {
+		return nil, errors.New("rtmidi: port group is closed")
+	}
+	m, err := openSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	p := handle(spec, m)
+	c.ports = append(c.ports, p)
+	return p, nil
+}
+
+// SetAPI moves the ports of the group to api: each port is opened again
+// with api, by the name or index given in its spec, and the port of the
+// old API closed. If a port cannot be opened with api, the ports are left
+// as they were and the error returned.
+//
+// Ports given by name are found under the new API as long as their names
+// match, as they do through aliases. Messages arriving while the ports are
+// switched may be lost or, on inputs, delivered twice.
+func (c *PortGroup) SetAPI(api API) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errors.New("rtmidi: port group is closed")
+	}
+	opened := make([]MIDI, len(c.ports))
+	for i, p := range c.ports {
+		m, err := p.prepare(api)
+		if err != nil {
+			for _, m := range opened[:i] {
+				if m != nil {
+					m.Close()
+				}
+			}
+			return fmt.Errorf("rtmidi: reopening port %d with %v: %w", i, api, err)
+		}
+		opened[i] = m
+	}
+	var first error
+	for i, p := range c.ports {
+		if opened[i] == nil {
+			continue
+		}
+		if err := p.swap(opened[i]).Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	c.api = api
+	return first
+}
+
+// remove forgets p, which has been closed.
+func (c *PortGroup) remove(p groupPort) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, q := range c.ports {
+		if q == p {
+			c.ports = append(c.ports[:i:i], c.ports[i+1:]...)
+			return
+		}
+	}
+}
+
+// Close closes the ports opened in the group that are still open,
+// returning the first error. The group cannot open ports afterwards.
+func (c *PortGroup) Close() error {
+	c.mu.Lock()
+	ports := c.ports
+	c.ports, c.closed = nil, true
+	c.mu.Unlock()
+	var first error
+	for i := len(ports) - 1; i >= 0; i-- {
+		if err := ports[i].Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
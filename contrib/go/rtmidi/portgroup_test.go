@@ -0,0 +1,208 @@
+package rtmidi
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPortGroup(t *testing.T) {
+	defer func() { newMIDIOut = NewMIDIOut }()
+	var created []*trackedOut
+	var clients []string
+	newMIDIOut = func(api API, name string) (MIDIOut, error) {
+		if api != APIDummy {
+			t.Errorf("port created with API %v", api)
+		}
+		o := &trackedOut{namedPorts: namedPorts{names: []string{"Synth:Synth MIDI 1 20:0"}}}
+		created = append(created, o)
+		clients = append(clients, name)
+		return o, nil
+	}
+
+	c := NewPortGroup(APIDummy, "MyApp")
+	a, err := c.OpenOut(PortSpec{Port: "synth", Client: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.OpenOut(PortSpec{Virtual: true, Name: "Out 2"}); err != nil {
+		t.Fatal(err)
+	}
+	if a.(*groupOut).port() != created[0] || !created[0].opened {
+		t.Error("port not opened")
+	}
+	for i, name := range clients {
+		if name != "MyApp" {
+			t.Errorf("port %d opened under client %q", i, name)
+		}
+	}
+	if _, err := c.OpenIn(PortSpec{Port: "piano"}); err == nil {
+		t.Error("opening an unknown port succeeded")
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for i, o := range created {
+		if !o.closed {
+			t.Errorf("port %d not closed", i)
+		}
+	}
+	if _, err := c.OpenOut(PortSpec{}); err == nil {
+		t.Error("closed group opened a port")
+	}
+}
+
+// apiIn is an input of a given API recording the types it ignores.
+type apiIn struct {
+	fakeIn
+	api     API
+	ignored [3]bool
+	closed  bool
+}
+
+func (a *apiIn) API() (API, error) { return a.api, nil }
+func (a *apiIn) Close() error      { a.closed = true; return nil }
+func (a *apiIn) IgnoreTypes(midiSysex bool, midiTime bool, midiSense bool) error {
+	a.ignored = [3]bool{midiSysex, midiTime, midiSense}
+	return nil
+}
+
+// apiOut is an output of a given API.
+type apiOut struct {
+	trackedOut
+	api API
+}
+
+func (a *apiOut) API() (API, error) { return a.api, nil }
+
+func TestPortGroupSetAPI(t *testing.T) {
+	defer func() { newMIDIIn, newMIDIOut = NewMIDIIn, NewMIDIOut }()
+	var ins []*apiIn
+	var outs []*apiOut
+	newMIDIIn = func(api API, client string, size int) (MIDIIn, error) {
+		in := &apiIn{api: api}
+		ins = append(ins, in)
+		return in, nil
+	}
+	newMIDIOut = func(api API, client string) (MIDIOut, error) {
+		if api == APIWindowsMM {
+			return nil, ErrUnsupportedPlatform
+		}
+		out := &apiOut{api: api}
+		outs = append(outs, out)
+		return out, nil
+	}
+
+	c := NewPortGroup(APILinuxALSA, "MyApp")
+	in, err := c.OpenIn(PortSpec{Index: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := c.OpenOut(PortSpec{Index: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []MIDIIn
+	in.SetCallback(func(m MIDIIn, msg []byte, ts float64) { got = append(got, m) })
+	in.IgnoreTypes(false, true, true)
+	out.SendMessage([]byte{0xb0, 7, 90})
+	SendMessageAt(out, []byte{0x90, 60, 100}, time.Now().Add(20*time.Millisecond))
+
+	if err := c.SetAPI(APIUnixJack); err != nil {
+		t.Fatal(err)
+	}
+	if c.API() != APIUnixJack || len(ins) != 2 || len(outs) != 2 {
+		t.Fatalf("API %v, %d inputs, %d outputs", c.API(), len(ins), len(outs))
+	}
+	if !ins[0].closed || !outs[0].closed {
+		t.Error("old ports not closed")
+	}
+	if api, _ := in.API(); api != APIUnixJack {
+		t.Errorf("input API %v", api)
+	}
+	if ins[1].ignored != [3]bool{false, true, true} {
+		t.Errorf("ignored types %v", ins[1].ignored)
+	}
+	ins[1].deliver([]byte{0x90, 60, 100})
+	if len(got) != 1 || got[0] != in {
+		t.Errorf("callback got %v", got)
+	}
+
+	// The controller value is sent again, and the scheduled note to the
+	// new port.
+	time.Sleep(50 * time.Millisecond)
+	if msgs := outs[1].messages(); len(msgs) != 2 || msgs[0][1] != 7 || msgs[1][1] != 60 {
+		t.Errorf("new output got %v", msgs)
+	}
+
+	// An API that cannot open a port leaves every port as it was.
+	if err := c.SetAPI(APIWindowsMM); err == nil {
+		t.Fatal("switched to an API without ports")
+	}
+	if c.API() != APIUnixJack || ins[2].closed != true || ins[1].closed {
+		t.Error("failed switch changed the ports")
+	}
+	c.Close()
+}
+
+// deliveringIn delivers a message as it is closed, as a port whose callback
+// is still running does.
+type deliveringIn struct {
+	apiIn
+}
+
+func (d *deliveringIn) Close() error {
+	d.deliver([]byte{0xf8})
+	return d.apiIn.Close()
+}
+
+func TestPortGroupClosePort(t *testing.T) {
+	defer func() { newMIDIIn, newMIDIOut = NewMIDIIn, NewMIDIOut }()
+	var outs []*apiOut
+	newMIDIIn = func(api API, client string, size int) (MIDIIn, error) {
+		return &deliveringIn{apiIn{api: api}}, nil
+	}
+	newMIDIOut = func(api API, client string) (MIDIOut, error) {
+		out := &apiOut{api: api}
+		outs = append(outs, out)
+		return out, nil
+	}
+
+	c := NewPortGroup(APILinuxALSA, "MyApp")
+	in, err := c.OpenIn(PortSpec{Index: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := c.OpenOut(PortSpec{Index: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.SetCallback(func(m MIDIIn, msg []byte, ts float64) { m.API() })
+	done := make(chan error)
+	go func() { done <- in.Close() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close deadlocked with the callback")
+	}
+	if len(c.ports) != 1 {
+		t.Errorf("%d ports in the group after closing one", len(c.ports))
+	}
+
+	// A controller the port failed to send is not sent to the next one.
+	outs[0].err = errors.New("unplugged")
+	if err := out.SendMessage([]byte{0xb0, 7, 90}); err == nil {
+		t.Fatal("send to a failing port succeeded")
+	}
+	if err := c.SetAPI(APIUnixJack); err != nil {
+		t.Fatal(err)
+	}
+	if msgs := outs[1].messages(); len(msgs) != 0 {
+		t.Errorf("new output got %v", msgs)
+	}
+	c.Close()
+}
@@ -0,0 +1,124 @@
+package rtmidi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	// ALSA appends the client and port numbers, e.g. " 20:0".
+	alsaAddrSuffix = regexp.MustCompile(`\s+\d+:\d+$`)
+	// Backends and Windows append port indices, e.g. " 1" or " [2]".
+	indexSuffix = regexp.MustCompile(`\s+(\d+|\[\d+\]|\(\d+\))$`)
+	nonAlnum    = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// NormalizePortName reduces a backend port name to a canonical form so that
+// the same device is recognised across platforms: it drops the ALSA client
+// prefix and address, trailing port indices and words like "MIDI" and
+// "Port", lowercases the rest and collapses punctuation into single spaces.
+// "Launchkey Mini:Launchkey Mini MIDI 1 20:0" and "Launchkey Mini MK3 MIDI 1"
+// become "launchkey mini" and "launchkey mini mk3".
+func NormalizePortName(name string) string {
+	s := alsaAddrSuffix.ReplaceAllString(strings.TrimSpace(name), "")
+	if i := strings.Index(s, ":"); i >= 0 && strings.HasPrefix(strings.TrimSpace(s[i+1:]), strings.TrimSpace(s[:i])) {
+		s = s[i+1:]
+	}
+	for {
+		t := indexSuffix.ReplaceAllString(s, "")
+		if t == s {
+			break
+		}
+		s = t
+	}
+	s = nonAlnum.ReplaceAllString(strings.ToLower(s), " ")
+	var words []string
+	for _, w := range strings.Fields(s) {
+		switch w {
+		case "midi", "port", "in", "out", "input", "output":
+			continue
+		}
+		words = append(words, w)
+	}
+	return strings.Join(words, " ")
+}
+
+// PortAliases maps friendly names chosen by the application, such as
+// "launchkey", to the ports they stand for.
+type PortAliases struct {
+	mu      sync.Mutex
+	aliases map[string][]string
+}
+
+// NewPortAliases returns an empty alias table.
+func NewPortAliases() *PortAliases {
+	return &PortAliases{aliases: map[string][]string{}}
+}
+
+// Add makes alias refer to ports whose normalized name contains any of the
+// given fragments, which are normalized too. Later fragments are tried only
+// if earlier ones match nothing.
+func (a *PortAliases) Add(alias string, fragments ...string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := strings.ToLower(alias)
+	for _, f := range fragments {
+		a.aliases[key] = append(a.aliases[key], NormalizePortName(f))
+	}
+}
+
+// Remove deletes alias.
+func (a *PortAliases) Remove(alias string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.aliases, strings.ToLower(alias))
+}
+
+// Find returns the number of the first port of m matching name. name is
+// looked up in the alias table first; a name that is not an alias is
+// matched as a fragment of the normalized port names.
+func (a *PortAliases) Find(m Port, name string) (int, error) {
+	var fragments []string
+	if a != nil {
+		a.mu.Lock()
+		fragments = append(fragments, a.aliases[strings.ToLower(name)]...)
+		a.mu.Unlock()
+	}
+	if len(fragments) == 0 {
+		fragments = []string{NormalizePortName(name)}
+	}
+	n, err := m.PortCount()
+	if err != nil {
+		return -1, err
+	}
+	names := make([]string, n)
+	for i := range names {
+		pn, err := m.PortName(i)
+		if err != nil {
+			return -1, err
+		}
+		names[i] = NormalizePortName(pn)
+	}
+	for _, f := range fragments {
+		for i, pn := range names {
+			if pn == f {
+				return i, nil
+			}
+		}
+		for i, pn := range names {
+			if f != "" && strings.Contains(pn, f) {
+				return i, nil
+			}
+		}
+	}
+	return -1, fmt.Errorf("rtmidi: no port matches %q", name)
+}
+
+// FindPort returns the number of the first port of m whose normalized name
+// matches name, without consulting any alias table.
+func FindPort(m Port, name string) (int, error) {
+	var a *PortAliases
+	return a.Find(m, name)
+}
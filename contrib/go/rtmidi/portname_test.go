@@ -0,0 +1,53 @@
+package rtmidi
+
+import "testing"
+
+// namedPorts is a MIDI listing a fixed set of port names.
+type namedPorts struct {
+	fakeOut
+	names []string
+}
+
+func (p *namedPorts) PortCount() (int, error) { return len(p.names), nil }
+func (p *namedPorts) PortName(port int) (string, error) {
+	return p.names[port], nil
+}
+
+func TestNormalizePortName(t *testing.T) {
+	for name, want := range map[string]string{
+		"Launchkey Mini:Launchkey Mini MIDI 1 20:0": "launchkey mini",
+		"Launchkey Mini MK3 MIDI 1":                 "launchkey mini mk3",
+		"MIDIIN2 (Launchkey Mini MK3)":              "midiin2 launchkey mini mk3",
+		"Midi Through:Midi Through Port-0 14:0":     "through 0",
+		"USB Uno MIDI Interface [2]":                "usb uno interface",
+		"  Digital Piano  ":                         "digital piano",
+	} {
+		if got := NormalizePortName(name); got != want {
+			t.Errorf("NormalizePortName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestPortAliases(t *testing.T) {
+	ports := &namedPorts{names: []string{
+		"Midi Through:Midi Through Port-0 14:0",
+		"Launchkey Mini MK3:Launchkey Mini MK3 MIDI 1 20:0",
+		"Launchkey Mini MK3:Launchkey Mini MK3 MIDI 2 20:1",
+		"Digital Piano:Digital Piano MIDI 1 24:0",
+	}}
+	a := NewPortAliases()
+	a.Add("launchkey", "Launchkey Mini MK3 MIDI 1", "Launchkey Mini")
+	a.Add("keys", "Roland", "digital piano")
+	for name, want := range map[string]int{"launchkey": 1, "KEYS": 3, "digital": 3, "through": 0} {
+		if i, err := a.Find(ports, name); err != nil || i != want {
+			t.Errorf("Find(%q) = %d, %v, want %d", name, i, err, want)
+		}
+	}
+	a.Remove("keys")
+	if _, err := a.Find(ports, "keys"); err == nil {
+		t.Error("removed alias still found")
+	}
+	if i, err := FindPort(ports, "launchkey mini mk3"); err != nil || i != 1 {
+		t.Errorf("FindPort = %d, %v", i, err)
+	}
+}
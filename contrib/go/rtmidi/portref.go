@@ -0,0 +1,90 @@
+package rtmidi
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// PortRef identifies the port a message came from.
+type PortRef struct {
+	// API is the API of the port.
+	API API
+	// Index is the number the port was listed under when opened, or -1
+	// for a virtual port.
+	Index int
+	// Name is the name of the port, or for a virtual port the name it was
+	// opened under.
+	Name string
+	// ID identifies the port across runs of a program, unlike Index, which
+	// changes as devices come and go, and Name, which includes the client
+	// and port numbers under ALSA. Ports with the same name, such as two
+	// devices of the same model, are told apart by the order they are
+	// listed in: the second has "#2" appended, and so on.
+	ID string
+}
+
+func (r PortRef) String() string {
+	return r.ID
+}
+
+// alsaAddress matches the client and port numbers ALSA appends to names.
+var alsaAddress = regexp.MustCompile(`\s+\d+:\d+$`)
+
+// NewPortRef returns the PortRef of the port m lists as index.
+func NewPortRef(m MIDI, index int) (PortRef, error) {
+	api, err := portAPI(m)
+	if err != nil {
+		return PortRef{}, err
+	}
+	name, err := m.PortName(index)
+	if err != nil {
+		return PortRef{}, err
+	}
+	base := alsaAddress.ReplaceAllString(name, "")
+	n := 1
+	for i := 0; i < index; i++ {
+		other, err := m.PortName(i)
+		if err != nil {
+			return PortRef{}, err
+		}
+		if alsaAddress.ReplaceAllString(other, "") == base {
+			n++
+		}
+	}
+	id := api.String() + ":" + base
+	if n > 1 {
+		id += "#" + strconv.Itoa(n)
+	}
+	return PortRef{API: api, Index: index, Name: name, ID: id}, nil
+}
+
+// VirtualPortRef returns the PortRef of a virtual port opened with the
+// given API and name.
+func VirtualPortRef(api API, name string) PortRef {
+	return PortRef{API: api, Index: -1, Name: name, ID: api.String() + ":virtual:" + name}
+}
+
+// portAPI returns the API of m, or APIUnspecified if m does not tell.
+func portAPI(m MIDI) (API, error) {
+	if a, ok := m.(interface{ API() (API, error) }); ok {
+		return a.API()
+	}
+	return APIUnspecified, nil
+}
+
+// MessageEvent is a message received from a port, with its origin.
+type MessageEvent struct {
+	Port    PortRef
+	Message []byte
+	// Timestamp is the time since the previous message of the port, in
+	// seconds, as passed to MIDIIn callbacks.
+	Timestamp float64
+}
+
+// SetEventCallback sets the callback of in to one passing fn each message
+// as a MessageEvent from the port ref.
+func SetEventCallback(in MIDIIn, ref PortRef, fn func(MessageEvent)) error {
+	return in.SetCallback(func(_ MIDIIn, msg []byte, t float64) {
+		fn(MessageEvent{Port: ref, Message: msg, Timestamp: t})
+	})
+}
@@ -0,0 +1,83 @@
+package rtmidi
+
+import "testing"
+
+func TestNewPortRef(t *testing.T) {
+	ports := &namedPorts{names: []string{
+		"Midi Through:Midi Through Port-0 14:0",
+		"Launchkey Mini MK3:Launchkey Mini MK3 MIDI 1 20:0",
+	}}
+	ref, err := NewPortRef(ports, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PortRef{
+		API:   APIDummy,
+		Index: 1,
+		Name:  "Launchkey Mini MK3:Launchkey Mini MK3 MIDI 1 20:0",
+		ID:    "dummy:Launchkey Mini MK3:Launchkey Mini MK3 MIDI 1",
+	}
+	if ref != want {
+		t.Errorf("NewPortRef = %+v, want %+v", ref, want)
+	}
+
+	// The ID stays the same when ALSA numbers the client differently.
+	ports.names[1] = "Launchkey Mini MK3:Launchkey Mini MK3 MIDI 1 24:0"
+	if again, _ := NewPortRef(ports, 1); again.ID != ref.ID {
+		t.Errorf("ID changed to %q", again.ID)
+	}
+
+	if v := VirtualPortRef(APILinuxALSA, "Sequencer"); v.ID != "alsa:virtual:Sequencer" || v.Index != -1 {
+		t.Errorf("VirtualPortRef = %+v", v)
+	}
+}
+
+func TestNewPortRefSameName(t *testing.T) {
+	ports := &namedPorts{names: []string{
+		"Keystation 49:Keystation 49 MIDI 1 20:0",
+		"Midi Through:Midi Through Port-0 14:0",
+		"Keystation 49:Keystation 49 MIDI 1 24:0",
+	}}
+	first, _ := NewPortRef(ports, 0)
+	second, _ := NewPortRef(ports, 2)
+	if first.ID != "dummy:Keystation 49:Keystation 49 MIDI 1" || second.ID != first.ID+"#2" {
+		t.Fatalf("IDs %q and %q", first.ID, second.ID)
+	}
+
+	// Each keyboard ends its own notes, and is removed on its own.
+	aIn, bIn := &fakeIn{}, &fakeIn{}
+	synth, other := &fakeOut{}, &fakeOut{}
+	r := NewRouter(When(Notes()).From(first).SendTo(synth), When(Notes()).From(second).SendTo(other))
+	m := NewMerger(r.Receive)
+	m.Add(aIn, first)
+	m.Add(bIn, second)
+	aIn.deliver([]byte{0x90, 60, 100})
+	bIn.deliver([]byte{0x90, 60, 90})
+	bIn.deliver([]byte{0x80, 60, 0})
+	if got := synth.messages(); len(got) != 1 {
+		t.Errorf("first keyboard's synth got % X", got)
+	}
+	if got := other.messages(); len(got) != 2 {
+		t.Errorf("second keyboard's synth got % X", got)
+	}
+	if err := m.Remove(second); err != nil {
+		t.Fatal(err)
+	}
+	if ports := m.Ports(); len(ports) != 1 || ports[0] != first {
+		t.Errorf("Ports = %v after removing the second", ports)
+	}
+	m.Close()
+}
+
+func TestSetEventCallback(t *testing.T) {
+	in := &fakeIn{}
+	ref := VirtualPortRef(APIDummy, "in")
+	var got []MessageEvent
+	if err := SetEventCallback(in, ref, func(ev MessageEvent) { got = append(got, ev) }); err != nil {
+		t.Fatal(err)
+	}
+	in.deliver([]byte{0x90, 60, 100})
+	if len(got) != 1 || got[0].Port != ref || got[0].Message[1] != 60 {
+		t.Errorf("events %+v", got)
+	}
+}
@@ -0,0 +1,163 @@
+package rtmidi
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// The port interfaces are split by capability, so that other backends and
+// test doubles need only implement what they support, and helpers can ask
+// for no more than they use. MIDI, MIDIIn and MIDIOut combine them into the
+// interfaces implemented by the RtMidi ports. What only some ports support
+// is reached through the package functions below, so that implementations
+// written against the original interfaces keep compiling.
+
+// Port is a connection to one of the ports of a MIDI system, listed by
+// PortCount and PortName.
+type Port interface {
+	OpenPort(port int, name string) error
+	Close() error
+	PortCount() (int, error)
+	PortName(port int) (string, error)
+}
+
+// VirtualPortOpener is implemented by ports that can be opened as a virtual
+// port, which other programs connect to.
+type VirtualPortOpener interface {
+	OpenVirtualPort(name string) error
+}
+
+// Receiver receives MIDI messages, either queued for retrieval with
+// Message or passed to a callback as they arrive.
+type Receiver interface {
+	IgnoreTypes(midiSysex bool, midiTime bool, midiSense bool) error
+	SetCallback(func(MIDIIn, []byte, float64)) error
+	CancelCallback() error
+	Message() ([]byte, float64, error)
+}
+
+// Sender sends MIDI messages.
+type Sender interface {
+	SendMessage([]byte) error
+}
+
+// MIDI interface provides a common, platform-independent API for realtime MIDI
+// device enumeration and handling MIDI ports.
+type MIDI interface {
+	Port
+	VirtualPortOpener
+}
+
+// MIDIIn interface provides a common, platform-independent API for realtime
+// MIDI input. It allows access to a single MIDI input port. Incoming MIDI
+// messages are either saved to a queue for retrieval using the Message()
+// method or immediately passed to a user-specified callback function. Create
+// multiple instances of this class to connect to more than one MIDI device at
+// the same time.
+type MIDIIn interface {
+	MIDI
+	Receiver
+	API() (API, error)
+	Destroy()
+}
+
+// MIDIOut interface provides a common, platform-independent API for MIDI
+// output. It allows one to probe available MIDI output ports, to connect to
+// one such port, and to send MIDI bytes immediately over the connection.
+// Create multiple instances of this class to connect to more than one MIDI
+// device at the same time.
+type MIDIOut interface {
+	MIDI
+	Sender
+	API() (API, error)
+	Destroy()
+}
+
+type connector interface {
+	ConnectTo(portPattern string) error
+}
+
+// ConnectTo connects m, opened with OpenVirtualPort, to every port whose
+// name matches the case-insensitive regular expression portPattern, like
+// aconnect or jack_connect would. It is supported by the ALSA and JACK APIs.
+func ConnectTo(m MIDI, portPattern string) error {
+	c, ok := m.(connector)
+	if !ok {
+		return errors.New("rtmidi: port does not support connecting")
+	}
+	return c.ConnectTo(portPattern)
+}
+
+type bufferedReceiver interface {
+	SetBufferedCallback(size int, cb func(MIDIIn, []byte, float64)) error
+}
+
+// SetBufferedCallback is like m.SetCallback, but the callback runs on a
+// dedicated goroutine while the backend's realtime thread only copies
+// incoming messages into a buffer of the given size, so slow callbacks
+// cannot stall the driver. Messages arriving while the buffer is full are
+// dropped, see Dropped.
+func SetBufferedCallback(m MIDIIn, size int, cb func(MIDIIn, []byte, float64)) error {
+	b, ok := m.(bufferedReceiver)
+	if !ok {
+		return errors.New("rtmidi: port does not support buffered callbacks")
+	}
+	return b.SetBufferedCallback(size, cb)
+}
+
+type drainer interface {
+	Drain() error
+}
+
+// Drain returns once every message already received by m has been
+// delivered to its buffered callback, so that none is lost when closing
+// the port. It returns at once for ports without buffered callbacks.
+func Drain(m MIDIIn) error {
+	if d, ok := m.(drainer); ok {
+		return d.Drain()
+	}
+	return nil
+}
+
+type atSender interface {
+	SendMessageAt(msg []byte, at time.Time) error
+}
+
+// SendMessageAt sends a message on out at the given time. Where the backend
+// can schedule events (the ALSA sequencer and CoreMIDI) the message is
+// handed to it straight away, which gives less jitter than a Go timer;
+// elsewhere it is queued on a Scheduler. Times in the past send the message
+// immediately.
+func SendMessageAt(out MIDIOut, msg []byte, at time.Time) error {
+	s, ok := out.(atSender)
+	if !ok {
+		return errors.New("rtmidi: port does not support timed sending")
+	}
+	return s.SendMessageAt(msg, at)
+}
+
+type notePlayer interface {
+	PlayNote(ch, key, vel int, d time.Duration) (*Note, error)
+}
+
+// PlayNote sends a NoteOn on channel ch (0-15) of out and returns a handle
+// that guarantees the matching NoteOff is sent exactly once: on Stop, after
+// duration d if it is positive, or when the port is closed.
+func PlayNote(out MIDIOut, ch, key, vel int, d time.Duration) (*Note, error) {
+	p, ok := out.(notePlayer)
+	if !ok {
+		return nil, errors.New("rtmidi: port does not support playing notes")
+	}
+	return p.PlayNote(ch, key, vel, d)
+}
+
+// Flush waits until the queues of messages scheduled for later sending on
+// out, such as those of a Scheduler or notes started with PlayNote, are
+// empty or ctx is done. It returns at once for ports without such queues.
+func Flush(ctx context.Context, out MIDIOut) error {
+	if f, ok := out.(flusher); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
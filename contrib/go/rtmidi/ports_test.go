@@ -0,0 +1,67 @@
+package rtmidi
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// The RtMidi ports and the wrappers of the package are composed of the
+// small interfaces.
+var (
+	_ Port              = MIDI(nil)
+	_ VirtualPortOpener = MIDI(nil)
+	_ Receiver          = MIDIIn(nil)
+	_ Sender            = MIDIOut(nil)
+	_ MIDIOut           = (*StateCache)(nil)
+	_ MIDIIn            = (*sharedIn)(nil)
+)
+
+// listedPorts is a Port and nothing else.
+type listedPorts []string
+
+func (p listedPorts) OpenPort(port int, name string) error { return nil }
+func (p listedPorts) Close() error                         { return nil }
+func (p listedPorts) PortCount() (int, error)              { return len(p), nil }
+func (p listedPorts) PortName(port int) (string, error)    { return p[port], nil }
+
+func TestFindPortNeedsOnlyPort(t *testing.T) {
+	ports := listedPorts{"Midi Through Port-0", "Digital Piano MIDI 1"}
+	if i, err := FindPort(ports, "digital piano"); err != nil || i != 1 {
+		t.Errorf("FindPort = %d, %v, want 1", i, err)
+	}
+	spec := PortSpec{Port: "through"}
+	if i, err := spec.portIndex(ports); err != nil || i != 0 {
+		t.Errorf("portIndex = %d, %v, want 0", i, err)
+	}
+}
+
+// plainOut has the method set MIDIOut had before the optional methods were
+// added, as outputs written outside the package do.
+type plainOut struct{ listedPorts }
+
+func (plainOut) OpenVirtualPort(name string) error { return nil }
+func (plainOut) API() (API, error)                 { return APIDummy, nil }
+func (plainOut) SendMessage([]byte) error          { return nil }
+func (plainOut) Destroy()                          {}
+
+func TestOptionalMethods(t *testing.T) {
+	var out MIDIOut = plainOut{}
+	if err := ConnectTo(out, "synth"); err == nil {
+		t.Error("ConnectTo succeeded on a port without it")
+	}
+	if err := SendMessageAt(out, []byte{0xf8}, time.Now()); err == nil {
+		t.Error("SendMessageAt succeeded on a port without it")
+	}
+	if _, err := PlayNote(out, 0, 60, 100, 0); err == nil {
+		t.Error("PlayNote succeeded on a port without it")
+	}
+	if err := Flush(context.Background(), out); err != nil {
+		t.Errorf("Flush = %v", err)
+	}
+
+	f := &fakeOut{}
+	if err := SendMessageAt(f, []byte{0xf8}, time.Time{}); err != nil || len(f.messages()) != 1 {
+		t.Errorf("SendMessageAt = %v, sent %v", err, f.messages())
+	}
+}
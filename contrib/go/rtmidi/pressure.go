@@ -0,0 +1,154 @@
+package rtmidi
+
+import "sync"
+
+// PressureMode selects how a PressureMerger combines channel pressure with
+// polyphonic aftertouch.
+type PressureMode int
+
+const (
+	// PressureMax gives each note the higher of its own aftertouch and the
+	// channel pressure.
+	PressureMax PressureMode = iota
+	// PressurePolyFirst uses the note's aftertouch once it has received
+	// any, and the channel pressure until then.
+	PressurePolyFirst
+	// PressurePolyOnly ignores channel pressure.
+	PressurePolyOnly
+	// PressureChannelOnly ignores polyphonic aftertouch, applying the
+	// channel pressure to every held note.
+	PressureChannelOnly
+)
+
+// PressureMerger turns the channel pressure and polyphonic aftertouch
+// received from a controller into a single pressure value per held note,
+// so that a synth engine need not care which of the two a controller sends.
+type PressureMerger struct {
+	// Mode selects how the two kinds of pressure are combined.
+	Mode PressureMode
+	// Curve, if set, maps the combined pressure (0-1) before it is
+	// reported, e.g. to soften a stiff controller.
+	Curve func(float64) float64
+
+	fn func(ch, key int, pressure float64)
+
+	mu      sync.Mutex
+	held    [16][128]bool
+	poly    [16][128]int
+	channel [16]int
+	last    [16][128]float64
+}
+
+// NewPressureMerger returns a PressureMerger calling fn whenever the
+// pressure of a held note changes, with a value between 0 and 1. A released
+// note is reported once more with pressure 0.
+func NewPressureMerger(fn func(ch, key int, pressure float64)) *PressureMerger {
+	return &PressureMerger{fn: fn}
+}
+
+type pressureChange struct {
+	key      int
+	pressure float64
+}
+
+// Feed updates the pressures from a received message. Aftertouch for notes
+// that are not held is ignored.
+func (p *PressureMerger) Feed(msg []byte) {
+	if len(msg) < 2 {
+		return
+	}
+	ch := int(msg[0] & 0x0f)
+	var changes []pressureChange
+
+	p.mu.Lock()
+	switch {
+	case isNoteOn(msg):
+		key := int(msg[1] & 0x7f)
+		p.held[ch][key] = true
+		p.poly[ch][key] = -1
+		p.last[ch][key] = -1
+		changes = p.update(changes, ch, key)
+	case isNoteOff(msg):
+		key := int(msg[1] & 0x7f)
+		if p.held[ch][key] {
+			p.held[ch][key] = false
+			changes = append(changes, pressureChange{key, 0})
+		}
+	case msg[0]&0xf0 == 0xa0 && len(msg) >= 3:
+		key := int(msg[1] & 0x7f)
+		if p.held[ch][key] {
+			p.poly[ch][key] = int(msg[2] & 0x7f)
+			changes = p.update(changes, ch, key)
+		}
+	case msg[0]&0xf0 == 0xd0:
+		p.channel[ch] = int(msg[1] & 0x7f)
+		for key := range p.held[ch] {
+			if p.held[ch][key] {
+				changes = p.update(changes, ch, key)
+			}
+		}
+	}
+	p.mu.Unlock()
+
+	if p.fn != nil {
+		for _, c := range changes {
+			p.fn(ch, c.key, c.pressure)
+		}
+	}
+}
+
+// update appends the pressure of a held note to changes if it differs from
+// the one last reported. p.mu must be held.
+func (p *PressureMerger) update(changes []pressureChange, ch, key int) []pressureChange {
+	v := p.pressure(ch, key)
+	if v == p.last[ch][key] {
+		return changes
+	}
+	p.last[ch][key] = v
+	return append(changes, pressureChange{key, v})
+}
+
+// pressure combines the pressures of a held note. p.mu must be held.
+func (p *PressureMerger) pressure(ch, key int) float64 {
+	poly, channel := p.poly[ch][key], p.channel[ch]
+	var v int
+	switch p.Mode {
+	case PressureMax:
+		v = channel
+		if poly > v {
+			v = poly
+		}
+	case PressurePolyFirst:
+		v = channel
+		if poly >= 0 {
+			v = poly
+		}
+	case PressurePolyOnly:
+		if poly > 0 {
+			v = poly
+		}
+	case PressureChannelOnly:
+		v = channel
+	}
+	f := float64(v) / 127
+	if p.Curve != nil {
+		f = p.Curve(f)
+	}
+	return f
+}
+
+// Pressure returns the current pressure of a note, or 0 if it is not held.
+func (p *PressureMerger) Pressure(ch, key int) float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ch, key = ch&0x0f, key&0x7f
+	if !p.held[ch][key] {
+		return 0
+	}
+	return p.last[ch][key]
+}
+
+// Callback can be passed to MIDIIn.SetCallback to merge an input directly.
+func (p *PressureMerger) Callback(m MIDIIn, msg []byte, t float64) {
+	p.Feed(msg)
+}
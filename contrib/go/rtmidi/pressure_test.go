@@ -0,0 +1,68 @@
+package rtmidi
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestPressureMerger(t *testing.T) {
+	stream := [][]byte{
+		{0x90, 60, 100},
+		{0xd0, 64},
+		{0xa0, 60, 127},
+		{0xa0, 60, 32},
+		{0x90, 64, 100},
+		{0xd0, 96},
+		{0xa0, 61, 127},
+		{0x80, 60, 0},
+	}
+	for _, tt := range []struct {
+		mode PressureMode
+		want []string
+	}{
+		{PressureMax, []string{
+			"60:0.00", "60:0.50", "60:1.00", "60:0.50", "64:0.50", "60:0.76", "64:0.76", "60:0.00",
+		}},
+		{PressurePolyFirst, []string{
+			"60:0.00", "60:0.50", "60:1.00", "60:0.25", "64:0.50", "64:0.76", "60:0.00",
+		}},
+		{PressurePolyOnly, []string{
+			"60:0.00", "60:1.00", "60:0.25", "64:0.00", "60:0.00",
+		}},
+		{PressureChannelOnly, []string{
+			"60:0.00", "60:0.50", "64:0.50", "60:0.76", "64:0.76", "60:0.00",
+		}},
+	} {
+		var got []string
+		p := NewPressureMerger(func(ch, key int, pressure float64) {
+			got = append(got, fmt.Sprintf("%d:%.2f", key, pressure))
+		})
+		p.Mode = tt.mode
+		for _, msg := range stream {
+			p.Feed(msg)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("mode %d: got %v, want %v", tt.mode, got, tt.want)
+		}
+		if v := p.Pressure(0, 60); v != 0 {
+			t.Errorf("mode %d: released note has pressure %v", tt.mode, v)
+		}
+	}
+}
+
+func TestPressureMergerCurve(t *testing.T) {
+	p := NewPressureMerger(nil)
+	p.Curve = func(v float64) float64 { return v * v }
+	p.Feed([]byte{0x91, 60, 100})
+	p.Feed([]byte{0xa1, 60, 127})
+	if v := p.Pressure(1, 60); v != 1 {
+		t.Errorf("full pressure %v, want 1", v)
+	}
+	p.Feed([]byte{0xa1, 60, 0})
+	p.Feed([]byte{0xd1, 127})
+	p.Feed([]byte{0xa1, 60, 64})
+	if v := p.Pressure(1, 60); v != 1 {
+		t.Errorf("max of poly and channel pressure %v, want 1", v)
+	}
+}
@@ -0,0 +1,71 @@
+package rtmidi
+
+import (
+	"math"
+	"time"
+)
+
+// Grid is a quantization grid step in quarter notes.
+type Grid float64
+
+// Common quantization grids.
+const (
+	GridQuarter          Grid = 1
+	GridEighth           Grid = 1.0 / 2
+	GridEighthTriplet    Grid = 1.0 / 3
+	GridSixteenth        Grid = 1.0 / 4
+	GridSixteenthTriplet Grid = 1.0 / 6
+	GridThirtySecond     Grid = 1.0 / 8
+)
+
+// Quantizer moves the notes of a recorded track towards a rhythmic grid.
+type Quantizer struct {
+	// Grid is the step notes are snapped to.
+	Grid Grid
+	// BPM is the tempo the track was recorded at.
+	BPM float64
+	// Strength is how far notes are moved towards the grid, from 0 (not at
+	// all) to 1 (exactly on the grid).
+	Strength float64
+	// Swing delays every second grid line by this fraction of a grid step;
+	// 0 is straight and about 0.33 gives a triplet feel.
+	Swing float64
+}
+
+// Quantize returns a copy of t with every NoteOn moved towards the grid. The
+// matching NoteOff is moved by the same amount so that note durations are
+// preserved; other events are left where they are.
+func (q *Quantizer) Quantize(t Track) Track {
+	out := t.Clone()
+	if q.Grid <= 0 || q.BPM <= 0 {
+		return out
+	}
+	step := float64(time.Minute) / q.BPM * float64(q.Grid)
+	var shift [16][128][]time.Duration
+	for i, ev := range out {
+		msg := ev.Message
+		switch {
+		case isNoteOn(msg):
+			n := math.Round(float64(ev.Time) / step)
+			target := n * step
+			if int64(n)%2 != 0 {
+				target += q.Swing * step
+			}
+			d := time.Duration(math.Round((target - float64(ev.Time)) * q.Strength))
+			if ev.Time+d < 0 {
+				d = -ev.Time
+			}
+			out[i].Time += d
+			ch, key := msg[0]&0x0f, msg[1]&0x7f
+			shift[ch][key] = append(shift[ch][key], d)
+		case isNoteOff(msg):
+			ch, key := msg[0]&0x0f, msg[1]&0x7f
+			if s := shift[ch][key]; len(s) > 0 {
+				out[i].Time += s[0]
+				shift[ch][key] = s[1:]
+			}
+		}
+	}
+	out.Sort()
+	return out
+}
@@ -0,0 +1,41 @@
+package rtmidi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func ms(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+
+func TestQuantize(t *testing.T) {
+	// At 120 BPM a sixteenth note is 125ms.
+	track := Track{
+		{ms(10), []byte{0x90, 60, 100}},
+		{ms(110), []byte{0x80, 60, 0}},
+		{ms(120), []byte{0x90, 62, 100}},
+		{ms(130), []byte{0xb0, 1, 10}},
+		{ms(200), []byte{0x90, 62, 0}},
+	}
+	q := &Quantizer{Grid: GridSixteenth, BPM: 120, Strength: 1}
+	got := q.Quantize(track)
+	want := Track{
+		{ms(0), []byte{0x90, 60, 100}},
+		{ms(100), []byte{0x80, 60, 0}},
+		{ms(125), []byte{0x90, 62, 100}},
+		{ms(130), []byte{0xb0, 1, 10}},
+		{ms(205), []byte{0x90, 62, 0}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Quantize = %v, want %v", got, want)
+	}
+	if track[0].Time != ms(10) {
+		t.Error("Quantize modified its input")
+	}
+
+	q = &Quantizer{Grid: GridSixteenth, BPM: 120, Strength: 0.5, Swing: 0.2}
+	got = q.Quantize(Track{{ms(115), []byte{0x90, 60, 100}}})
+	if got[0].Time != ms(150)-ms(35)/2 {
+		t.Errorf("swung half strength note at %v, want 132.5ms", got[0].Time)
+	}
+}
@@ -0,0 +1,97 @@
+package rtmidi
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Realtime says how a goroutine delivering or sending messages on time,
+// such as that of a Scheduler, a ClockMaster or a buffered callback, runs.
+// The zero Realtime leaves it to the Go scheduler.
+//
+// Locking the goroutine to its thread keeps other goroutines from running
+// on it between messages; raising the thread's priority keeps other
+// processes from delaying it. Whether either lowers latency depends on the
+// system and its load, as the wakeup of Go timers is often what dominates:
+// BenchmarkSchedulerLatency measures the lateness of scheduled messages
+// with each setting.
+type Realtime struct {
+	// LockThread runs the goroutine on an OS thread of its own, with
+	// runtime.LockOSThread.
+	LockThread bool
+	// Priority, if positive, asks for the SCHED_FIFO scheduling policy at
+	// this priority (1 to 99) for the thread, which implies LockThread.
+	// Only Linux is supported, and the process needs CAP_SYS_NICE or a
+	// large enough RLIMIT_RTPRIO. If it cannot be had, the goroutine
+	// still runs locked to its thread, at normal priority.
+	Priority int
+}
+
+// realtimeRequest asks a goroutine to apply rt to its thread.
+type realtimeRequest struct {
+	rt    Realtime
+	reply chan error
+}
+
+// requestRealtime passes rt to the goroutine reading ctl, returning the
+// error it applied it with, or nil if the goroutine ends first.
+func requestRealtime(ctl chan realtimeRequest, done chan struct{}, rt Realtime) error {
+	req := realtimeRequest{rt: rt, reply: make(chan error, 1)}
+	select {
+	case ctl <- req:
+		return <-req.reply
+	case <-done:
+		return nil
+	}
+}
+
+// realtimeThread is the state of the thread of a goroutine applying
+// Realtime settings. A goroutine ending with its thread locked takes the
+// thread with it, so a thread with a raised priority never runs other
+// goroutines.
+type realtimeThread struct {
+	locked, raised bool
+}
+
+func (t *realtimeThread) set(rt Realtime) error {
+	if t.raised && rt.Priority <= 0 {
+		if err := resetThreadPriority(); err != nil {
+			return fmt.Errorf("rtmidi: resetting thread priority: %w", err)
+		}
+		t.raised = false
+	}
+	lock := rt.LockThread || rt.Priority > 0
+	switch {
+	case lock && !t.locked:
+		runtime.LockOSThread()
+	case !lock && t.locked:
+		runtime.UnlockOSThread()
+	}
+	t.locked = lock
+	if rt.Priority > 0 {
+		if err := raiseThreadPriority(rt.Priority); err != nil {
+			return fmt.Errorf("rtmidi: running at normal priority: %w", err)
+		}
+		t.raised = true
+	}
+	return nil
+}
+
+// realtimer is implemented by inputs whose buffered callback can run with
+// Realtime settings.
+type realtimer interface {
+	setRealtime(rt Realtime) error
+}
+
+// SetRealtime sets how the goroutine running the buffered callback of m,
+// set with SetBufferedCallback, runs. The settings also apply to buffered
+// callbacks set later, though errors are then only reported for a
+// callback already set. Callbacks set with SetCallback run on the thread
+// of the backend.
+func SetRealtime(m MIDIIn, rt Realtime) error {
+	r, ok := m.(realtimer)
+	if !ok {
+		return fmt.Errorf("rtmidi: port does not support realtime settings")
+	}
+	return r.setRealtime(rt)
+}
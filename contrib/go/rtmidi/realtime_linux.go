@@ -0,0 +1,29 @@
+package rtmidi
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+const (
+	schedOther = 0
+	schedFIFO  = 1
+)
+
+// setScheduler sets the scheduling policy of the calling thread.
+func setScheduler(policy, priority int) error {
+	param := struct{ priority int32 }{int32(priority)}
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETSCHEDULER, 0, uintptr(policy), uintptr(unsafe.Pointer(&param)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func raiseThreadPriority(priority int) error {
+	return setScheduler(schedFIFO, priority)
+}
+
+func resetThreadPriority() error {
+	return setScheduler(schedOther, 0)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package rtmidi
+
+import "errors"
+
+func raiseThreadPriority(priority int) error {
+	return errors.New("thread priority is only supported on Linux")
+}
+
+func resetThreadPriority() error {
+	return nil
+}
@@ -0,0 +1,126 @@
+package rtmidi
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestRealtimeThread(t *testing.T) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var th realtimeThread
+		for _, tc := range []struct {
+			rt             Realtime
+			locked, raised bool
+		}{
+			{Realtime{LockThread: true}, true, false},
+			{Realtime{}, false, false},
+			{Realtime{Priority: 10}, true, true},
+			{Realtime{LockThread: true}, true, false},
+			{Realtime{}, false, false},
+		} {
+			err := th.set(tc.rt)
+			if tc.raised && err != nil {
+				// Not permitted here: the thread stays locked, at normal
+				// priority.
+				t.Logf("set(%+v): %v", tc.rt, err)
+				tc.raised = false
+			} else if err != nil {
+				t.Errorf("set(%+v): %v", tc.rt, err)
+			}
+			if th.locked != tc.locked || th.raised != tc.raised {
+				t.Errorf("set(%+v): locked %v, raised %v, want %v, %v", tc.rt, th.locked, th.raised, tc.locked, tc.raised)
+			}
+		}
+	}()
+	<-done
+}
+
+func TestSchedulerSetRealtime(t *testing.T) {
+	out := &fakeOut{}
+	s := NewScheduler(out)
+	if err := s.SetRealtime(Realtime{LockThread: true}); err != nil {
+		t.Fatal(err)
+	}
+	s.ScheduleAfter(time.Millisecond, []byte{0xf8})
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(out.messages()); n != 1 {
+		t.Errorf("sent %d messages, want 1", n)
+	}
+	s.Close()
+	if err := s.SetRealtime(Realtime{}); err != nil {
+		t.Errorf("SetRealtime after Close: %v", err)
+	}
+}
+
+func TestClockMasterSetRealtime(t *testing.T) {
+	out := &fakeOut{}
+	c := NewClockMaster(out, 300)
+	if err := c.SetRealtime(Realtime{LockThread: true}); err != nil {
+		t.Fatal(err)
+	}
+	c.Start()
+	if err := c.SetRealtime(Realtime{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	c.Stop()
+	if c.Position() == 0 {
+		t.Error("clock did not run")
+	}
+}
+
+func TestDispatcherSetRealtime(t *testing.T) {
+	got := make(chan []byte, 1)
+	d := newDispatcher(4, func(msg []byte, ts float64) { got <- msg })
+	defer d.stop()
+	if err := d.setRealtime(Realtime{LockThread: true}); err != nil {
+		t.Fatal(err)
+	}
+	d.push([]byte{0x90, 60, 100}, 0)
+	if msg := <-got; len(msg) != 3 {
+		t.Errorf("delivered %v", msg)
+	}
+}
+
+// BenchmarkSchedulerLatency reports how late a Scheduler sends messages
+// due 1ms after they are scheduled, as the median and the worst lateness,
+// with each Realtime setting. Raising the priority needs the permissions
+// described on Realtime; without them that case runs at normal priority.
+// Its results depend on the system and its load.
+func BenchmarkSchedulerLatency(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		rt   Realtime
+	}{
+		{"default", Realtime{}},
+		{"locked", Realtime{LockThread: true}},
+		{"fifo", Realtime{Priority: 50}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			sent := make(chan time.Time, 1)
+			out := &fakeOut{onSend: func([]byte) { sent <- time.Now() }}
+			s := NewScheduler(out)
+			defer s.Close()
+			if err := s.SetRealtime(bc.rt); err != nil {
+				b.Log(err)
+			}
+			late := make([]time.Duration, b.N)
+			b.ResetTimer()
+			for i := range late {
+				at := time.Now().Add(time.Millisecond)
+				s.Schedule(at, []byte{0xf8})
+				late[i] = (<-sent).Sub(at)
+			}
+			b.StopTimer()
+			sort.Slice(late, func(i, j int) bool { return late[i] < late[j] })
+			b.ReportMetric(float64(late[len(late)/2].Microseconds()), "µs-median")
+			b.ReportMetric(float64(late[len(late)-1].Microseconds()), "µs-max")
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package rtmidi
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Recorder records the messages received on one or more inputs into
+// tracks sharing a common time base. Messages fed to the Recorder itself go
+// to its first track; AddTrack adds a track per further input.
+type Recorder struct {
+	mu      sync.Mutex
+	running bool
+	start   time.Time
+	tracks  []Track
+	names   []string
+	clock   Clock
+}
+
+// NewRecorder returns a stopped Recorder with a single track.
+func NewRecorder() *Recorder {
+	return &Recorder{tracks: make([]Track, 1), names: make([]string, 1), clock: SystemClock}
+}
+
+// SetClock sets the clock that tells the time of messages fed without
+// one, SystemClock by default.
+func (r *Recorder) SetClock(c Clock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clock = clockOr(c)
+}
+
+func (r *Recorder) now() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.clock.Now()
+}
+
+// RecorderTrack feeds one track of a Recorder, typically from one input.
+type RecorderTrack struct {
+	r *Recorder
+	i int
+}
+
+// AddTrack adds a track named name, which is written to Standard MIDI
+// Files as the track name.
+func (r *Recorder) AddTrack(name string) *RecorderTrack {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tracks = append(r.tracks, nil)
+	r.names = append(r.names, name)
+	return &RecorderTrack{r: r, i: len(r.tracks) - 1}
+}
+
+// Feed records a message received now.
+func (t *RecorderTrack) Feed(msg []byte) {
+	t.r.feedAt(t.i, msg, t.r.now())
+}
+
+// FeedAt records a message received at t.
+func (t *RecorderTrack) FeedAt(msg []byte, at time.Time) {
+	t.r.feedAt(t.i, msg, at)
+}
+
+// Callback can be passed to MIDIIn.SetCallback to record an input directly.
+func (t *RecorderTrack) Callback(m MIDIIn, msg []byte, ts float64) {
+	t.Feed(msg)
+}
+
+// Start discards anything recorded and starts recording, with event times
+// counted from now.
+func (r *Recorder) Start() {
+	r.StartAt(r.now())
+}
+
+// StartAt is like Start with event times counted from t.
+func (r *Recorder) StartAt(t time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.running, r.start = true, t
+	for i := range r.tracks {
+		r.tracks[i] = nil
+	}
+}
+
+// Stop stops recording and returns the first track. Tracks returns all of
+// them.
+func (r *Recorder) Stop() Track {
+	r.mu.Lock()
+	r.running = false
+	r.mu.Unlock()
+	return r.Track()
+}
+
+// Recording reports whether the recorder is running.
+func (r *Recorder) Recording() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.running
+}
+
+// Feed records a message received now on the first track.
+func (r *Recorder) Feed(msg []byte) {
+	r.feedAt(0, msg, r.now())
+}
+
+// FeedAt records a message received at t on the first track, such as a
+// time from a Timestamper. Messages from before the start are dropped.
+func (r *Recorder) FeedAt(msg []byte, t time.Time) {
+	r.feedAt(0, msg, t)
+}
+
+func (r *Recorder) feedAt(i int, msg []byte, t time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.running || t.Before(r.start) || len(msg) == 0 {
+		return
+	}
+	r.tracks[i] = append(r.tracks[i], Event{Time: t.Sub(r.start), Message: append([]byte(nil), msg...)})
+}
+
+// Callback can be passed to MIDIIn.SetCallback to record an input directly
+// on the first track.
+func (r *Recorder) Callback(m MIDIIn, msg []byte, t float64) {
+	r.Feed(msg)
+}
+
+// Track returns a copy of what has been recorded so far on the first track.
+func (r *Recorder) Track() Track {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.track(0)
+}
+
+func (r *Recorder) track(i int) Track {
+	t := r.tracks[i].Clone()
+	t.Sort()
+	return t
+}
+
+// Tracks returns a copy of what has been recorded so far on every track.
+func (r *Recorder) Tracks() []Track {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tracks := make([]Track, len(r.tracks))
+	for i := range r.tracks {
+		tracks[i] = r.track(i)
+	}
+	return tracks
+}
+
+// WriteSMF writes what has been recorded as a Standard MIDI File, see
+// WriteSMF: format 0 for a single track, format 1 with the tracks in the
+// order they were added otherwise. The first track is left out if nothing
+// was fed to it and other tracks were added.
+func (r *Recorder) WriteSMF(w io.Writer, tempo *TempoMap) error {
+	r.mu.Lock()
+	var tracks []Track
+	for i := range r.tracks {
+		if i == 0 && len(r.tracks) > 1 && len(r.tracks[0]) == 0 {
+			continue
+		}
+		t := r.track(i)
+		if r.names[i] != "" {
+			t = append(Track{{Message: TrackNameMeta(r.names[i])}}, t...)
+		}
+		tracks = append(tracks, t)
+	}
+	r.mu.Unlock()
+	return WriteSMF(w, tempo, tracks...)
+}
@@ -0,0 +1,66 @@
+package rtmidi
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	start := time.Now()
+	r.FeedAt([]byte{0x90, 59, 100}, start)
+	r.StartAt(start)
+	r.FeedAt([]byte{0x90, 60, 100}, start.Add(10*time.Millisecond))
+	r.FeedAt([]byte{0x80, 60, 0}, start.Add(250*time.Millisecond))
+	r.FeedAt([]byte{0x90, 58, 100}, start.Add(-time.Millisecond))
+	if !r.Recording() {
+		t.Error("not recording after Start")
+	}
+	got := r.Stop()
+	want := Track{
+		{Time: 10 * time.Millisecond, Message: []byte{0x90, 60, 100}},
+		{Time: 250 * time.Millisecond, Message: []byte{0x80, 60, 0}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("recorded %v, want %v", got, want)
+	}
+	r.Feed([]byte{0x90, 61, 100})
+	if len(r.Track()) != 2 {
+		t.Error("recorded after Stop")
+	}
+}
+
+func TestRecorderTracks(t *testing.T) {
+	r := NewRecorder()
+	keys, pads := r.AddTrack("Keys"), r.AddTrack("Pads")
+	start := time.Now()
+	r.StartAt(start)
+	keys.FeedAt([]byte{0x90, 60, 100}, start.Add(time.Second))
+	pads.FeedAt([]byte{0x99, 36, 100}, start.Add(500*time.Millisecond))
+	keys.FeedAt([]byte{0x80, 60, 0}, start.Add(2*time.Second))
+	r.Stop()
+
+	tracks := r.Tracks()
+	if len(tracks) != 3 || len(tracks[0]) != 0 || len(tracks[1]) != 2 || len(tracks[2]) != 1 {
+		t.Fatalf("Tracks() = %v", tracks)
+	}
+	if tracks[2][0].Time != 500*time.Millisecond {
+		t.Errorf("pads event at %v, want 500ms", tracks[2][0].Time)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteSMF(&buf, NewTempoMap(96, 120)); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if b[9] != 1 || b[11] != 2 {
+		t.Errorf("format %d with %d tracks, want format 1 with 2", b[9], b[11])
+	}
+	for _, name := range []string{"Keys", "Pads"} {
+		if !bytes.Contains(b, append([]byte{0xff, 0x03, byte(len(name))}, name...)) {
+			t.Errorf("no track name %q", name)
+		}
+	}
+}
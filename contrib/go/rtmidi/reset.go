@@ -0,0 +1,83 @@
+package rtmidi
+
+import (
+	"bytes"
+	"time"
+)
+
+// Standard system reset SysEx messages.
+var (
+	// GMSystemOn turns General MIDI mode on.
+	GMSystemOn = []byte{0xf0, 0x7e, 0x7f, 0x09, 0x01, 0xf7}
+	// GM2SystemOn turns General MIDI Level 2 mode on.
+	GM2SystemOn = []byte{0xf0, 0x7e, 0x7f, 0x09, 0x03, 0xf7}
+	// GSReset resets Roland GS devices.
+	GSReset = []byte{0xf0, 0x41, 0x10, 0x42, 0x12, 0x40, 0x00, 0x7f, 0x00, 0x41, 0xf7}
+	// XGSystemOn resets Yamaha XG devices.
+	XGSystemOn = []byte{0xf0, 0x43, 0x10, 0x4c, 0x00, 0x00, 0x7e, 0x00, 0xf7}
+)
+
+// ResetDelay is how long devices need after a system reset before they
+// reliably accept further messages. The GM, GS and XG specifications ask
+// for at least 50ms to 100ms; this leaves some margin.
+var ResetDelay = 200 * time.Millisecond
+
+// isSystemReset reports whether msg is one of the resets above, for any
+// device id, GM System Off, or System Reset.
+func isSystemReset(msg []byte) bool {
+	switch {
+	case len(msg) == 1:
+		return msg[0] == 0xff
+	case len(msg) < 4 || msg[0] != 0xf0:
+		return false
+	case msg[1] == 0x7e:
+		return len(msg) == 6 && msg[3] == 0x09 && msg[4] >= 0x01 && msg[4] <= 0x03 && msg[5] == 0xf7
+	case msg[1] == 0x41:
+		return bytes.Equal(msg[3:], GSReset[3:])
+	case msg[1] == 0x43:
+		return msg[2]&0xf0 == 0x10 && bytes.Equal(msg[3:], XGSystemOn[3:])
+	}
+	return false
+}
+
+func sendReset(out MIDIOut, msg []byte) error {
+	if err := out.SendMessage(msg); err != nil {
+		return err
+	}
+	time.Sleep(ResetDelay)
+	return nil
+}
+
+// SendGMSystemOn sends General MIDI System On and waits ResetDelay.
+func SendGMSystemOn(out MIDIOut) error {
+	return sendReset(out, GMSystemOn)
+}
+
+// SendGM2SystemOn sends General MIDI Level 2 System On and waits ResetDelay.
+func SendGM2SystemOn(out MIDIOut) error {
+	return sendReset(out, GM2SystemOn)
+}
+
+// SendGSReset sends a Roland GS Reset and waits ResetDelay.
+func SendGSReset(out MIDIOut) error {
+	return sendReset(out, GSReset)
+}
+
+// SendXGSystemOn sends a Yamaha XG System On and waits ResetDelay.
+func SendXGSystemOn(out MIDIOut) error {
+	return sendReset(out, XGSystemOn)
+}
+
+// ResetAllControllers sends Reset All Controllers and All Notes Off on every
+// channel, which stops hanging notes on devices that ignore system resets.
+func ResetAllControllers(out MIDIOut) error {
+	for ch := byte(0); ch < 16; ch++ {
+		if err := out.SendMessage([]byte{0xb0 | ch, 121, 0}); err != nil {
+			return err
+		}
+		if err := out.SendMessage([]byte{0xb0 | ch, 123, 0}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
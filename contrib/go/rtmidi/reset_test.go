@@ -0,0 +1,59 @@
+package rtmidi
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSendReset(t *testing.T) {
+	defer func(d time.Duration) { ResetDelay = d }(ResetDelay)
+	ResetDelay = 5 * time.Millisecond
+	out := &fakeOut{}
+	start := time.Now()
+	if err := SendGSReset(out); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < ResetDelay {
+		t.Error("SendGSReset returned before the reset delay")
+	}
+	SendXGSystemOn(out)
+	SendGMSystemOn(out)
+	msgs := out.messages()
+	if len(msgs) != 3 || !bytes.Equal(msgs[0], GSReset) || !bytes.Equal(msgs[1], XGSystemOn) || !bytes.Equal(msgs[2], GMSystemOn) {
+		t.Errorf("sent %v", msgs)
+	}
+	// The GS checksum covers address and data.
+	var sum byte
+	for _, b := range GSReset[5:9] {
+		sum += b
+	}
+	if (128-sum%128)%128 != GSReset[9] {
+		t.Error("bad GS reset checksum")
+	}
+}
+
+func TestResetAllControllers(t *testing.T) {
+	out := &fakeOut{}
+	ResetAllControllers(out)
+	if msgs := out.messages(); len(msgs) != 32 || !bytes.Equal(msgs[31], []byte{0xbf, 123, 0}) {
+		t.Errorf("sent %v", msgs)
+	}
+}
+
+func TestIsSystemReset(t *testing.T) {
+	for _, msg := range [][]byte{GMSystemOn, GM2SystemOn, GSReset, XGSystemOn, {0xff}} {
+		if !isSystemReset(msg) {
+			t.Errorf("% X not a reset", msg)
+		}
+	}
+	for _, msg := range [][]byte{
+		{0xf0, 0x7e, 0x7f, 0x06, 0x01, 0xf7},
+		{0xf0, 0x41, 0x10, 0x42, 0x12, 0x40, 0x01, 0x30, 0x00, 0x0f, 0xf7},
+		{0xb0, 121, 0},
+	} {
+		if isSystemReset(msg) {
+			t.Errorf("% X is a reset", msg)
+		}
+	}
+}
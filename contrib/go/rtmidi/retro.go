@@ -0,0 +1,113 @@
+package rtmidi
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+type timedMessage struct {
+	at  time.Time
+	msg []byte
+}
+
+// RetroBuffer keeps the messages received on an input during the last
+// stretch of time, so that something just played can be saved after the
+// fact without having pressed record.
+type RetroBuffer struct {
+	// MaxEvents, if positive, bounds the number of messages kept however
+	// short the window, to bound memory use under a flood of messages.
+	MaxEvents int
+
+	window time.Duration
+
+	mu     sync.Mutex
+	events []timedMessage
+	head   int
+}
+
+// NewRetroBuffer returns a RetroBuffer keeping the messages of the last
+// window.
+func NewRetroBuffer(window time.Duration) *RetroBuffer {
+	return &RetroBuffer{window: window}
+}
+
+// Feed stores a message received now.
+func (b *RetroBuffer) Feed(msg []byte) {
+	b.FeedAt(msg, time.Now())
+}
+
+// FeedAt stores a message received at t, such as a time from a Timestamper.
+// Messages must be fed in time order.
+func (b *RetroBuffer) FeedAt(msg []byte, t time.Time) {
+	if len(msg) == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, timedMessage{t, append([]byte(nil), msg...)})
+	b.expire(t)
+	if b.MaxEvents > 0 && len(b.events)-b.head > b.MaxEvents {
+		b.head = len(b.events) - b.MaxEvents
+	}
+	// Reclaim the space of expired messages once they make up half the
+	// buffer, which keeps feeding amortized constant time.
+	if b.head > 0 && b.head >= len(b.events)/2 {
+		n := copy(b.events, b.events[b.head:])
+		for i := n; i < len(b.events); i++ {
+			b.events[i] = timedMessage{}
+		}
+		b.events, b.head = b.events[:n], 0
+	}
+}
+
+// expire drops the messages older than the window. b.mu must be held.
+func (b *RetroBuffer) expire(now time.Time) {
+	cutoff := now.Add(-b.window)
+	for b.head < len(b.events) && b.events[b.head].at.Before(cutoff) {
+		b.head++
+	}
+}
+
+// Callback can be passed to MIDIIn.SetCallback to buffer an input directly.
+func (b *RetroBuffer) Callback(m MIDIIn, msg []byte, t float64) {
+	b.Feed(msg)
+}
+
+// DumpLast returns the messages of the last d (at most the window) as a
+// track starting at the first of them.
+func (b *RetroBuffer) DumpLast(d time.Duration) Track {
+	return b.dumpLastAt(d, time.Now())
+}
+
+func (b *RetroBuffer) dumpLastAt(d time.Duration, now time.Time) Track {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expire(now)
+	cutoff := now.Add(-d)
+	var t Track
+	var start time.Time
+	for _, ev := range b.events[b.head:] {
+		if ev.at.Before(cutoff) {
+			continue
+		}
+		if t == nil {
+			start = ev.at
+		}
+		t = append(t, Event{Time: ev.at.Sub(start), Message: append([]byte(nil), ev.msg...)})
+	}
+	return t
+}
+
+// SaveLast writes the messages of the last d as a Standard MIDI File, see
+// WriteSMF.
+func (b *RetroBuffer) SaveLast(w io.Writer, d time.Duration, tempo *TempoMap) error {
+	return WriteSMF(w, tempo, b.DumpLast(d))
+}
+
+// Clear drops every stored message.
+func (b *RetroBuffer) Clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events, b.head = nil, 0
+}
@@ -0,0 +1,61 @@
+package rtmidi
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetroBuffer(t *testing.T) {
+	b := NewRetroBuffer(10 * time.Second)
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		b.FeedAt([]byte{0x90, byte(40 + i), 100}, start.Add(time.Duration(i)*time.Second))
+	}
+	now := start.Add(19 * time.Second)
+	got := b.dumpLastAt(3*time.Second, now)
+	want := Track{
+		{Time: 0, Message: []byte{0x90, 56, 100}},
+		{Time: time.Second, Message: []byte{0x90, 57, 100}},
+		{Time: 2 * time.Second, Message: []byte{0x90, 58, 100}},
+		{Time: 3 * time.Second, Message: []byte{0x90, 59, 100}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DumpLast(3s) = %v, want %v", got, want)
+	}
+	if all := b.dumpLastAt(time.Minute, now); len(all) != 11 || all[0].Message[1] != 49 {
+		t.Errorf("DumpLast beyond the window kept %d messages from key %d", len(all), all[0].Message[1])
+	}
+	if late := b.dumpLastAt(time.Minute, now.Add(time.Hour)); len(late) != 0 {
+		t.Errorf("%d messages kept past the window", len(late))
+	}
+}
+
+func TestRetroBufferMaxEvents(t *testing.T) {
+	b := NewRetroBuffer(time.Hour)
+	b.MaxEvents = 5
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		b.FeedAt([]byte{0xf8}, now)
+	}
+	if got := len(b.dumpLastAt(time.Hour, now)); got != 5 {
+		t.Errorf("kept %d messages, want 5", got)
+	}
+	if cap(b.events) > 20 {
+		t.Errorf("buffer grew to %d", cap(b.events))
+	}
+}
+
+func TestRetroBufferSaveLast(t *testing.T) {
+	b := NewRetroBuffer(time.Minute)
+	b.Feed([]byte{0x90, 60, 100})
+	b.Feed([]byte{0x80, 60, 0})
+	var buf bytes.Buffer
+	if err := b.SaveLast(&buf, time.Minute, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("MThd")) || !bytes.Contains(buf.Bytes(), []byte{0x90, 60, 100}) {
+		t.Errorf("SaveLast wrote % x", buf.Bytes())
+	}
+}
@@ -0,0 +1,326 @@
+package rtmidi
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Router sends incoming messages to outputs according to a list of rules,
+// see When. Every matching rule applies, in the order the rules were
+// added, until one marked Final matches.
+//
+// A Router watches for feedback loops, where its outputs lead back to its
+// input. A message carries the links it was sent over on its way through
+// routers, and a message that is to be sent over a link it already went
+// through is dropped, and reported as a *LoopError. The links only follow
+// messages within the process, through outputs returned by Output; see
+// SetLoopWindow for loops through outside ports. Loops that transform a
+// message on every turn, such as through a transposing rule, are not
+// detected.
+type Router struct {
+	mu     sync.Mutex
+	rules  []*Rule
+	held   map[heldKey][]*Rule
+	err    func(error)
+	window time.Duration
+	recent []*linkUse
+	traces traceLog
+}
+
+// Link is a way a Router sends messages: a rule and one of its outputs.
+type Link struct {
+	// Rule is the index of the rule in the router, or -1 if the rule has
+	// been removed.
+	Rule int
+	// Output is the index of the output in the rule's SendTo list.
+	Output int
+}
+
+func (l Link) String() string {
+	return fmt.Sprintf("rule %d output %d", l.Rule, l.Output)
+}
+
+// LoopError reports a feedback loop found by a Router.
+type LoopError struct {
+	// Message is the looping message.
+	Message []byte
+	// Path lists the links the message was sent over, starting and
+	// ending with the link it was sent over twice.
+	Path []Link
+}
+
+func (e *LoopError) Error() string {
+	path := make([]string, len(e.Path))
+	for i, l := range e.Path {
+		path[i] = l.String()
+	}
+	return fmt.Sprintf("rtmidi: routing loop: %s sent over %s", FormatMessage(e.Message), strings.Join(path, ", "))
+}
+
+// heldKey identifies a note held on a port, by the ID of the port and the
+// channel and key of the note.
+type heldKey struct {
+	port string
+	note uint16
+}
+
+// linkUse records a message sent over a link. The uses a message went
+// through on its way are its path.
+type linkUse struct {
+	at   time.Time
+	msg  string
+	rule *Rule
+	out  int
+}
+
+// NewRouter returns a Router with the given rules.
+func NewRouter(rules ...*Rule) *Router {
+	return &Router{rules: rules, held: map[heldKey][]*Rule{}}
+}
+
+// SetLoopWindow also takes a message for a loop if it is sent over the same
+// link again within d, which catches loops through ports outside the
+// process, where messages cannot be tagged. As it also drops a controller
+// or clock repeating a message within d, or the same note played on two
+// merged inputs, d should be kept short, a few milliseconds. Zero, the
+// default, turns it off.
+func (r *Router) SetLoopWindow(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.window = d
+	r.recent = nil
+}
+
+// Add appends rules to the router.
+func (r *Router) Add(rules ...*Rule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = append(r.rules, rules...)
+}
+
+// Remove removes a rule. Notes it routed still have their NoteOff routed
+// the same way.
+func (r *Router) Remove(rule *Rule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, x := range r.rules {
+		if x == rule {
+			r.rules = append(r.rules[:i:i], r.rules[i+1:]...)
+			return
+		}
+	}
+}
+
+// SetErrorHandler sets a function called with errors from sending messages
+// routed by Callback, which are otherwise ignored.
+func (r *Router) SetErrorHandler(fn func(error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = fn
+}
+
+// match returns the rules msg, from the port origin, is to be sent
+// through. A NoteOff goes through the rules its NoteOn from the same port
+// went through, even if they no longer match.
+func (r *Router) match(origin PortRef, msg []byte) []*Rule {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var key heldKey
+	if isNoteOn(msg) || isNoteOff(msg) {
+		key = heldKey{origin.ID, uint16(msg[0]&0x0f)<<7 | uint16(msg[1]&0x7f)}
+		if isNoteOff(msg) {
+			if rules, ok := r.held[key]; ok {
+				delete(r.held, key)
+				return rules
+			}
+		}
+	}
+	var matched []*Rule
+	for _, rule := range r.rules {
+		if rule.from(origin) && rule.match(msg) {
+			matched = append(matched, rule)
+			if rule.final {
+				break
+			}
+		}
+	}
+	if isNoteOn(msg) {
+		if len(matched) > 0 {
+			r.held[key] = matched
+		} else {
+			delete(r.held, key)
+		}
+	}
+	return matched
+}
+
+// use returns the use of a link by msg, which has come over path. It
+// returns a *LoopError instead if path already holds the link, or if msg
+// was sent over it within the loop window.
+func (r *Router) use(path []*linkUse, msg []byte, rule *Rule, out int) (*linkUse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := &linkUse{time.Now(), string(msg), rule, out}
+	if err := r.loopLocked(path, u); err != nil {
+		return nil, err
+	}
+	if r.window > 0 {
+		i := 0
+		for i < len(r.recent) && u.at.Sub(r.recent[i].at) > r.window {
+			i++
+		}
+		r.recent = r.recent[i:]
+		if err := r.loopLocked(r.recent, u); err != nil {
+			return nil, err
+		}
+		r.recent = append(r.recent, u)
+	}
+	return u, nil
+}
+
+// loopLocked returns a *LoopError if uses, oldest first, holds the message
+// and link of u.
+func (r *Router) loopLocked(uses []*linkUse, u *linkUse) error {
+	for i, v := range uses {
+		if v.msg != u.msg || v.rule != u.rule || v.out != u.out {
+			continue
+		}
+		e := &LoopError{Message: []byte(u.msg)}
+		for _, w := range uses[i:] {
+			if w.msg == u.msg {
+				e.Path = append(e.Path, r.linkLocked(w.rule, w.out))
+			}
+		}
+		e.Path = append(e.Path, r.linkLocked(u.rule, u.out))
+		return e
+	}
+	return nil
+}
+
+func (r *Router) linkLocked(rule *Rule, out int) Link {
+	for i, x := range r.rules {
+		if x == rule {
+			return Link{i, out}
+		}
+	}
+	return Link{-1, out}
+}
+
+// Route sends msg through the matching rules. It returns the first error
+// from sending, after trying every output. The message has no origin, so
+// only rules not narrowed with From match it.
+func (r *Router) Route(msg []byte) error {
+	return r.RouteEvent(MessageEvent{Message: msg})
+}
+
+// RouteEvent sends the message of ev through the rules matching it and its
+// origin, as Route does.
+func (r *Router) RouteEvent(ev MessageEvent) error {
+	return r.routeEvent(ev, nil)
+}
+
+// routeEvent routes ev, whose message has come over path.
+func (r *Router) routeEvent(ev MessageEvent, path []*linkUse) error {
+	var first error
+	msg := ev.Message
+	tr := r.startTrace(ev.Port, msg)
+	for _, rule := range r.match(ev.Port, msg) {
+		r.trace(tr, TraceMatch, rule, -1, nil, nil, nil)
+		out := msg
+		if len(rule.transforms) > 0 {
+			out = append([]byte(nil), msg...)
+			for i, fn := range rule.transforms {
+				if out = fn(out); out == nil {
+					r.trace(tr, TraceDrop, rule, i, nil, nil, nil)
+					break
+				}
+				r.trace(tr, TraceTransform, rule, i, out, nil, nil)
+			}
+			if out == nil {
+				continue
+			}
+		}
+		for i, o := range rule.outs {
+			u, err := r.use(path, msg, rule, i)
+			if err == nil {
+				err = sendPath(o, out, append(path[:len(path):len(path)], u))
+			}
+			r.trace(tr, TraceSend, rule, i, out, o, err)
+			if err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	r.endTrace(tr)
+	return first
+}
+
+// Callback can be passed to MIDIIn.SetCallback to route an input directly.
+func (r *Router) Callback(m MIDIIn, msg []byte, t float64) {
+	r.report(r.Route(msg))
+}
+
+// Receive can be passed to NewMerger or SetEventCallback to route inputs
+// with their origin. Errors are handled as by Callback.
+func (r *Router) Receive(ev MessageEvent) {
+	r.report(r.RouteEvent(ev))
+}
+
+// report passes err, if any, to the error handler.
+func (r *Router) report(err error) {
+	if err != nil {
+		r.mu.Lock()
+		fn := r.err
+		r.mu.Unlock()
+		if fn != nil {
+			fn(err)
+		}
+	}
+}
+
+// pathSender is implemented by outputs that pass messages on to a Router
+// within the process, along with the path they came over.
+type pathSender interface {
+	sendPath(msg []byte, path []*linkUse) error
+}
+
+// sendPath sends msg, which has come over path, to out.
+func sendPath(out MIDIOut, msg []byte, path []*linkUse) error {
+	if p, ok := out.(pathSender); ok {
+		return p.sendPath(msg, path)
+	}
+	return out.SendMessage(msg)
+}
+
+// Output returns an output routing the messages sent to it through r, as
+// Route does, for chaining routers within the process. Messages a Router
+// sends to it keep the links they went through, so that loops back to a
+// router are found. The output is always open and lists no ports.
+func (r *Router) Output() MIDIOut {
+	return routerOut{r}
+}
+
+type routerOut struct {
+	r *Router
+}
+
+func (o routerOut) OpenPort(port int, name string) error { return nil }
+func (o routerOut) OpenVirtualPort(name string) error    { return nil }
+func (o routerOut) Close() error                         { return nil }
+func (o routerOut) PortCount() (int, error)              { return 0, nil }
+func (o routerOut) API() (API, error)                    { return APIDummy, nil }
+func (o routerOut) Destroy()                             {}
+
+func (o routerOut) PortName(port int) (string, error) {
+	return "", fmt.Errorf("rtmidi: port %d out of range", port)
+}
+
+func (o routerOut) SendMessage(msg []byte) error {
+	return o.r.Route(msg)
+}
+
+func (o routerOut) sendPath(msg []byte, path []*linkUse) error {
+	return o.r.routeEvent(MessageEvent{Message: msg}, path)
+}
@@ -0,0 +1,151 @@
+package rtmidi
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRouter(t *testing.T) {
+	drums, soft, loud, rest := &fakeOut{}, &fakeOut{}, &fakeOut{}, &fakeOut{}
+	r := NewRouter(
+		When(Channel(9)).And(NoteRange(35, 59)).SendTo(drums).Final(),
+		When(VelocityRange(1, 63)).SendTo(soft),
+		When(VelocityRange(64, 127)).SendTo(loud),
+		When(Not(Notes())).SendTo(rest),
+	)
+	for _, msg := range [][]byte{
+		{0x99, 36, 100},
+		{0x90, 60, 40},
+		{0x90, 62, 100},
+		{0x80, 60, 0},
+		{0x90, 62, 0},
+		{0x89, 36, 0},
+		{0xb0, 7, 100},
+	} {
+		if err := r.Route(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tt := range []struct {
+		name string
+		out  *fakeOut
+		want [][]byte
+	}{
+		{"drums", drums, [][]byte{{0x99, 36, 100}, {0x89, 36, 0}}},
+		{"soft", soft, [][]byte{{0x90, 60, 40}, {0x80, 60, 0}}},
+		{"loud", loud, [][]byte{{0x90, 62, 100}, {0x90, 62, 0}}},
+		{"rest", rest, [][]byte{{0xb0, 7, 100}}},
+	} {
+		if got := tt.out.messages(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s got % x, want % x", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRouterRemoveKeepsNoteOff(t *testing.T) {
+	out := &fakeOut{}
+	rule := When(Notes()).SendTo(out)
+	r := NewRouter(rule)
+	r.Route([]byte{0x90, 60, 100})
+	r.Remove(rule)
+	r.Route([]byte{0x80, 60, 0})
+	r.Route([]byte{0x90, 61, 100})
+	if n := len(out.messages()); n != 2 {
+		t.Errorf("%d messages sent, want NoteOn and NoteOff only", n)
+	}
+}
+
+func TestRouterErrors(t *testing.T) {
+	fail := errors.New("unplugged")
+	bad, good := &fakeOut{err: fail}, &fakeOut{}
+	r := NewRouter(When(Any()).SendTo(bad, good))
+	var got error
+	r.SetErrorHandler(func(err error) { got = err })
+	r.Callback(nil, []byte{0xb0, 1, 2}, 0)
+	if got != fail {
+		t.Errorf("error handler got %v, want %v", got, fail)
+	}
+	if len(good.messages()) != 1 {
+		t.Error("failing output stopped delivery to the others")
+	}
+}
+
+func TestRouterLoop(t *testing.T) {
+	r := NewRouter()
+	// The second output of the first rule feeds straight back into the
+	// router.
+	thru := &fakeOut{}
+	r.Add(When(Controller(7)).SendTo(thru, r.Output()), When(Controller(7)).SendTo(thru))
+
+	err := r.Route([]byte{0xb0, 7, 100})
+	loop, ok := err.(*LoopError)
+	if !ok {
+		t.Fatalf("got %v, want a loop", err)
+	}
+	want := []Link{{0, 1}, {0, 1}}
+	if !reflect.DeepEqual(loop.Path, want) {
+		t.Errorf("path %v, want %v", loop.Path, want)
+	}
+	// Once round the loop, then stopped: the rules send to thru on the way
+	// in and on the way back.
+	if n := len(thru.messages()); n != 4 {
+		t.Errorf("%d messages sent through, want 4", n)
+	}
+
+	// Repeating a message is not a loop.
+	r = NewRouter(When(Controller(7)).SendTo(thru))
+	for i := 0; i < 2; i++ {
+		if err := r.Route([]byte{0xb0, 7, 100}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRouterConcurrent(t *testing.T) {
+	// The output holds each message until both have reached it, so the
+	// two are being sent over the same link at once.
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	out := &fakeOut{onSend: func([]byte) {
+		arrived.Done()
+		arrived.Wait()
+	}}
+	r := NewRouter(When(Any()).SendTo(out))
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() { errs <- r.Route([]byte{0xf8}) }()
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Error(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("a message was not sent")
+		}
+	}
+}
+
+func TestRouterLoopWindow(t *testing.T) {
+	out := &fakeOut{}
+	r := NewRouter(When(Controller(7)).SendTo(out))
+	r.SetLoopWindow(time.Hour)
+	if err := r.Route([]byte{0xb0, 7, 100}); err != nil {
+		t.Fatal(err)
+	}
+	err := r.Route([]byte{0xb0, 7, 100})
+	if loop, ok := err.(*LoopError); !ok || !reflect.DeepEqual(loop.Path, []Link{{0, 0}, {0, 0}}) {
+		t.Errorf("repeat within the window: got %v", err)
+	}
+	r.SetLoopWindow(time.Nanosecond)
+	for i := 0; i < 2; i++ {
+		time.Sleep(time.Millisecond)
+		if err := r.Route([]byte{0xb0, 7, 100}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
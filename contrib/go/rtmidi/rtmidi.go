@@ -24,11 +24,25 @@ static inline void midiInCallback(double ts, const unsigned char *msg, size_t ms
 static inline void cgoSetCallback(RtMidiPtr in, int cb_id) {
 	rtmidi_in_set_callback(in, midiInCallback, (void*)(uintptr_t) cb_id);
 }
+
+extern void goMIDIWarning(int debug, char *msg, void *arg);
+
+static inline void midiWarning(int debug, const char *msg, void *arg) {
+	goMIDIWarning(debug, (char*) msg, arg);
+}
+
+static inline void cgoSetWarningHandler(RtMidiPtr device, int id) {
+	rtmidi_set_warning_handler(device, midiWarning, (void*)(uintptr_t) id);
+}
 */
 import "C"
 import (
 	"errors"
+	"fmt"
+	"regexp"
 	"sync"
+	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
@@ -50,24 +64,6 @@ const (
 	APIDummy = C.RTMIDI_API_RTMIDI_DUMMY
 )
 
-func (api API) String() string {
-	switch api {
-	case APIUnspecified:
-		return "unspecified"
-	case APILinuxALSA:
-		return "alsa"
-	case APIUnixJack:
-		return "jack"
-	case APIMacOSXCore:
-		return "coreaudio"
-	case APIWindowsMM:
-		return "winmm"
-	case APIDummy:
-		return "dummy"
-	}
-	return "?"
-}
-
 // CompiledAPI determines the available compiled MIDI APIs.
 func CompiledAPI() (apis []API) {
 	n := C.rtmidi_get_compiled_api(nil, 0)
@@ -79,72 +75,99 @@ func CompiledAPI() (apis []API) {
 	return apis
 }
 
-// MIDI interface provides a common, platform-independent API for realtime MIDI
-// device enumeration and handling MIDI ports.
-type MIDI interface {
-	OpenPort(port int, name string) error
-	OpenVirtualPort(name string) error
-	Close() error
-	PortCount() (int, error)
-	PortName(port int) (string, error)
-}
-
-// MIDIIn interface provides a common, platform-independent API for realtime
-// MIDI input. It allows access to a single MIDI input port. Incoming MIDI
-// messages are either saved to a queue for retrieval using the Message()
-// method or immediately passed to a user-specified callback function. Create
-// multiple instances of this class to connect to more than one MIDI device at
-// the same time.
-type MIDIIn interface {
-	MIDI
-	API() (API, error)
-	IgnoreTypes(midiSysex bool, midiTime bool, midiSense bool) error
-	SetCallback(func(MIDIIn, []byte, float64)) error
-	CancelCallback() error
-	Message() ([]byte, float64, error)
-	Destroy()
-}
-
-// MIDIOut interface provides a common, platform-independent API for MIDI
-// output. It allows one to probe available MIDI output ports, to connect to
-// one such port, and to send MIDI bytes immediately over the connection.
-// Create multiple instances of this class to connect to more than one MIDI
-// device at the same time.
-type MIDIOut interface {
-	MIDI
-	API() (API, error)
-	SendMessage([]byte) error
-	Destroy()
+type midi struct {
+	midi     C.RtMidiPtr
+	warnings *warningHandler
 }
 
-type midi struct {
-	midi C.RtMidiPtr
+// check returns the error of the last call on the port, or a warning held
+// for it under WarningsError.
+func (m *midi) check() error {
+	if !m.midi.ok {
+		return errors.New(C.GoString(m.midi.msg))
+	}
+	if m.warnings != nil {
+		return m.warnings.take()
+	}
+	return nil
+}
+
+func (m *midi) setWarningPolicy(policy WarningPolicy, fn func(*Warning)) error {
+	if m.warnings == nil {
+		m.warnings = &warningHandler{}
+		m.warnings.set(policy, fn)
+		C.cgoSetWarningHandler(m.midi, C.int(registerWarner(m)))
+		return nil
+	}
+	m.warnings.set(policy, fn)
+	return nil
+}
+
+// releaseWarnings removes the warning handler before the port is freed.
+func (m *midi) releaseWarnings() {
+	if m.warnings != nil {
+		unregisterWarner(m)
+		C.rtmidi_set_warning_handler(m.midi, nil, nil)
+	}
 }
 
 func (m *midi) OpenPort(port int, name string) error {
 	p := C.CString(name)
 	defer C.free(unsafe.Pointer(p))
 	C.rtmidi_open_port(m.midi, C.uint(port), p)
-	if !m.midi.ok {
-		return errors.New(C.GoString(m.midi.msg))
-	}
-	return nil
+	return m.check()
 }
 
 func (m *midi) OpenVirtualPort(name string) error {
 	p := C.CString(name)
 	defer C.free(unsafe.Pointer(p))
 	C.rtmidi_open_virtual_port(m.midi, p)
-	if !m.midi.ok {
-		return errors.New(C.GoString(m.midi.msg))
+	return m.check()
+}
+
+func (m *midi) ConnectTo(portPattern string) error {
+	re, err := regexp.Compile("(?i)" + portPattern)
+	if err != nil {
+		return err
+	}
+	n, err := m.PortCount()
+	if err != nil {
+		return err
+	}
+	connected := 0
+	for i := 0; i < n; i++ {
+		name, err := m.PortName(i)
+		if err != nil {
+			return err
+		}
+		if !re.MatchString(name) {
+			continue
+		}
+		C.rtmidi_connect_port(m.midi, C.uint(i))
+		if err := m.check(); err != nil {
+			return err
+		}
+		connected++
+	}
+	if connected == 0 {
+		return m.warnings.warn(fmt.Sprintf("no port matches %q", portPattern))
 	}
 	return nil
 }
 
+// SetPortName renames the open port. It is supported by the ALSA and JACK
+// APIs; see PortPool.Rename for renaming on any API.
+func (m *midi) SetPortName(name string) error {
+	p := C.CString(name)
+	defer C.free(unsafe.Pointer(p))
+	C.rtmidi_set_port_name(m.midi, p)
+	return m.check()
+}
+
 func (m *midi) PortName(port int) (string, error) {
 	p := C.rtmidi_get_port_name(m.midi, C.uint(port))
-	if !m.midi.ok {
-		return "", errors.New(C.GoString(m.midi.msg))
+	if err := m.check(); err != nil {
+		return "", err
 	}
 	defer C.free(unsafe.Pointer(p))
 	return C.GoString(p), nil
@@ -152,29 +175,46 @@ func (m *midi) PortName(port int) (string, error) {
 
 func (m *midi) PortCount() (int, error) {
 	n := C.rtmidi_get_port_count(m.midi)
-	if !m.midi.ok {
-		return 0, errors.New(C.GoString(m.midi.msg))
+	if err := m.check(); err != nil {
+		return 0, err
 	}
 	return int(n), nil
 }
 
 func (m *midi) Close() error {
 	C.rtmidi_close_port(C.RtMidiPtr(m.midi))
-	if !m.midi.ok {
-		return errors.New(C.GoString(m.midi.msg))
-	}
-	return nil
+	return m.check()
 }
 
 type midiIn struct {
 	midi
 	in C.RtMidiInPtr
-	cb func(MIDIIn, []byte, float64)
+	// handler is read by the backend thread for every message, while the
+	// callback may be replaced at any time, so it is swapped atomically.
+	handler atomic.Pointer[inputHandler]
+	lost    uint64
+	slab    messageSlab
+
+	// mu guards the registration of the callback with the backend, which
+	// RtMidi only accepts once until it is cancelled, and rt.
+	mu       sync.Mutex
+	callback bool
+	rt       Realtime
+}
+
+// inputHandler is the callback of an input, and the dispatcher running it
+// if it is buffered.
+type inputHandler struct {
+	cb   func(MIDIIn, []byte, float64)
+	disp *dispatcher
 }
 
 type midiOut struct {
 	midi
-	out C.RtMidiOutPtr
+	outQueues
+	notes noteSet
+	timed timedSender
+	out   C.RtMidiOutPtr
 }
 
 // NewMIDIInDefault opens a default MIDIIn port.
@@ -202,27 +242,31 @@ func NewMIDIIn(api API, name string, queueSize int) (MIDIIn, error) {
 
 func (m *midiIn) API() (API, error) {
 	api := C.rtmidi_in_get_current_api(m.in)
-	if !m.in.ok {
-		return APIUnspecified, errors.New(C.GoString(m.in.msg))
+	if err := m.check(); err != nil {
+		return APIUnspecified, err
 	}
 	return API(api), nil
 }
 
+// Close closes the port and releases the MIDIIn. Closing it again does
+// nothing.
 func (m *midiIn) Close() error {
-	unregisterMIDIIn(m)
+	if m.in == nil {
+		return nil
+	}
+	m.clearCallback()
 	if err := m.midi.Close(); err != nil {
 		return err
 	}
+	m.releaseWarnings()
 	C.rtmidi_in_free(m.in)
+	m.in, m.midi.midi = nil, nil
 	return nil
 }
 
 func (m *midiIn) IgnoreTypes(midiSysex bool, midiTime bool, midiSense bool) error {
 	C.rtmidi_in_ignore_types(m.in, C._Bool(midiSysex), C._Bool(midiTime), C._Bool(midiSense))
-	if !m.in.ok {
-		return errors.New(C.GoString(m.in.msg))
-	}
-	return nil
+	return m.check()
 }
 
 var (
@@ -258,38 +302,166 @@ func findMIDIIn(k int) *midiIn {
 	return inputs[k]
 }
 
+var warners = map[int]*midi{}
+
+func registerWarner(m *midi) int {
+	mu.Lock()
+	defer mu.Unlock()
+	for i := 0; ; i++ {
+		if _, ok := warners[i]; !ok {
+			warners[i] = m
+			return i
+		}
+	}
+}
+
+func unregisterWarner(m *midi) {
+	mu.Lock()
+	defer mu.Unlock()
+	for i, x := range warners {
+		if x == m {
+			delete(warners, i)
+			return
+		}
+	}
+}
+
+//export goMIDIWarning
+func goMIDIWarning(debug C.int, msg *C.char, arg unsafe.Pointer) {
+	mu.Lock()
+	m := warners[int(uintptr(arg))]
+	mu.Unlock()
+	if m != nil {
+		m.warnings.handle(&Warning{Message: C.GoString(msg), Debug: debug != 0})
+	}
+}
+
 //export goMIDIInCallback
 func goMIDIInCallback(ts C.double, msg *C.uchar, msgsz C.size_t, arg unsafe.Pointer) {
-	k := int(uintptr(arg))
-	m := findMIDIIn(k)
-	m.cb(m, C.GoBytes(unsafe.Pointer(msg), C.int(msgsz)), float64(ts))
+	if m := findMIDIIn(int(uintptr(arg))); m != nil {
+		m.receive(unsafe.Pointer(msg), int(msgsz), float64(ts))
+	}
+}
+
+// receive passes the n bytes of a message at p, received at ts, to the
+// callback of the input.
+func (m *midiIn) receive(p unsafe.Pointer, n int, ts float64) {
+	// Callbacks of a port come from a single thread of the backend, so the
+	// slab of the port needs no lock.
+	b := m.slab.copyFrom(p, n)
+	h := m.handler.Load()
+	switch {
+	case h == nil:
+	case h.disp != nil:
+		h.disp.push(b, ts)
+	default:
+		h.cb(m, b, ts)
+	}
 }
 
 func (m *midiIn) SetCallback(cb func(MIDIIn, []byte, float64)) error {
+	return m.setCallback(&inputHandler{cb: cb})
+}
+
+func (m *midiIn) SetBufferedCallback(size int, cb func(MIDIIn, []byte, float64)) error {
+	d := newDispatcher(size, func(msg []byte, ts float64) { cb(m, msg, ts) })
+	m.mu.Lock()
+	rt := m.rt
+	m.mu.Unlock()
+	if rt != (Realtime{}) {
+		d.setRealtime(rt)
+	}
+	return m.setCallback(&inputHandler{cb: cb, disp: d})
+}
+
+// setCallback makes h the handler of the input. The callback of the
+// backend is only set the first time, as replacing the callback only
+// swaps the handler it calls.
+func (m *midiIn) setCallback(h *inputHandler) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setHandler(h)
+	if m.callback {
+		return nil
+	}
 	k := registerMIDIIn(m)
-	m.cb = cb
 	C.cgoSetCallback(m.in, C.int(k))
-	if !m.in.ok {
-		return errors.New(C.GoString(m.in.msg))
+	if err := m.check(); err != nil {
+		unregisterMIDIIn(m)
+		return err
 	}
+	m.callback = true
 	return nil
 }
 
-func (m *midiIn) CancelCallback() error {
+// clearCallback removes the handler of the input and forgets the callback
+// of the backend, which is going away with the port.
+func (m *midiIn) clearCallback() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	unregisterMIDIIn(m)
-	C.rtmidi_in_cancel_callback(m.in)
-	if !m.in.ok {
-		return errors.New(C.GoString(m.in.msg))
+	m.setHandler(nil)
+	m.callback = false
+}
+
+// setHandler replaces the handler of the input, stopping the dispatcher of
+// the one replaced. The backend thread may still be pushing a message to
+// that dispatcher, which is then discarded with those it had not
+// delivered, and a message it is delivering may still be in the callback
+// when setHandler returns.
+func (m *midiIn) setHandler(h *inputHandler) {
+	old := m.handler.Swap(h)
+	if old != nil && old.disp != nil {
+		old.disp.stop()
+		atomic.AddUint64(&m.lost, old.disp.dropped())
+	}
+}
+
+func (m *midiIn) setRealtime(rt Realtime) error {
+	m.mu.Lock()
+	m.rt = rt
+	h := m.handler.Load()
+	m.mu.Unlock()
+	if h != nil && h.disp != nil {
+		return h.disp.setRealtime(rt)
 	}
 	return nil
 }
 
+func (m *midiIn) dropped() uint64 {
+	n := atomic.LoadUint64(&m.lost)
+	if h := m.handler.Load(); h != nil && h.disp != nil {
+		n += h.disp.dropped()
+	}
+	return n
+}
+
+func (m *midiIn) Drain() error {
+	if h := m.handler.Load(); h != nil && h.disp != nil {
+		h.disp.flush()
+	}
+	return nil
+}
+
+func (m *midiIn) CancelCallback() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setHandler(nil)
+	if !m.callback {
+		return nil
+	}
+	unregisterMIDIIn(m)
+	m.callback = false
+	C.rtmidi_in_cancel_callback(m.in)
+	return m.check()
+}
+
 func (m *midiIn) Message() ([]byte, float64, error) {
 	msg := make([]C.uchar, 64*1024, 64*1024)
 	sz := C.size_t(len(msg))
 	r := C.rtmidi_in_get_message(m.in, &msg[0], &sz)
-	if !m.in.ok {
-		return nil, 0, errors.New(C.GoString(m.in.msg))
+	if err := m.check(); err != nil {
+		return nil, 0, err
 	}
 	b := make([]byte, int(sz), int(sz))
 	for i, c := range msg[:sz] {
@@ -298,8 +470,16 @@ func (m *midiIn) Message() ([]byte, float64, error) {
 	return b, float64(r), nil
 }
 
+// Destroy releases the MIDIIn without closing the port first. Destroying
+// it after Close, or again, does nothing.
 func (m *midiIn) Destroy() {
+	if m.in == nil {
+		return
+	}
+	m.clearCallback()
+	m.releaseWarnings()
 	C.rtmidi_in_free(m.in)
+	m.in, m.midi.midi = nil, nil
 }
 
 // NewMIDIOutDefault opens a default MIDIOut port.
@@ -326,30 +506,97 @@ func NewMIDIOut(api API, name string) (MIDIOut, error) {
 
 func (m *midiOut) API() (API, error) {
 	api := C.rtmidi_out_get_current_api(m.out)
-	if !m.out.ok {
-		return APIUnspecified, errors.New(C.GoString(m.out.msg))
+	if err := m.check(); err != nil {
+		return APIUnspecified, err
 	}
 	return API(api), nil
 }
 
+// Close closes the port and releases the MIDIOut. Closing it again does
+// nothing.
 func (m *midiOut) Close() error {
+	if m.out == nil {
+		return nil
+	}
+	m.notes.stopAll()
+	m.timed.close()
+	C.rtmidi_out_release_schedule(m.out)
 	if err := m.midi.Close(); err != nil {
 		return err
 	}
+	m.releaseWarnings()
 	C.rtmidi_out_free(m.out)
+	m.out, m.midi.midi = nil, nil
 	return nil
 }
 
 func (m *midiOut) SendMessage(b []byte) error {
-	p := C.CBytes(b)
-	defer C.free(unsafe.Pointer(p))
-	C.rtmidi_out_send_message(m.out, (*C.uchar)(p), C.int(len(b)))
-	if !m.out.ok {
-		return errors.New(C.GoString(m.out.msg))
+	p, owned := cMessage(b)
+	if owned {
+		defer cFree(p)
 	}
-	return nil
+	C.rtmidi_out_send_message(m.out, p, C.int(len(b)))
+	return m.check()
+}
+
+// cMessage returns the bytes of b for passing to the backend, and whether
+// they are a copy the caller must free with cFree.
+func cMessage(b []byte) (*C.uchar, bool) {
+	if len(b) > 0 && len(b) <= smallMessageSize {
+		// The backends copy the message before returning, so it can be
+		// passed as it is, saving the C allocation. A copy in an array on
+		// the stack would be moved to the heap by the call.
+		return (*C.uchar)(unsafe.Pointer(&b[0])), false
+	}
+	return cCopy(b), true
 }
 
+// cCopy returns a copy of b allocated by C.
+func cCopy(b []byte) *C.uchar {
+	return (*C.uchar)(C.CBytes(b))
+}
+
+func cFree(p *C.uchar) {
+	C.free(unsafe.Pointer(p))
+}
+
+func (m *midiOut) SendMessageAt(b []byte, at time.Time) error {
+	return m.timed.send(m, b, at, m.sendNative)
+}
+
+func (m *midiOut) sendNative(b []byte, delay time.Duration) (bool, error) {
+	p, owned := cMessage(b)
+	if owned {
+		defer cFree(p)
+	}
+	switch C.rtmidi_out_send_message_at(m.out, p, C.int(len(b)), C.double(delay.Seconds())) {
+	case 0:
+		return false, nil
+	case 1:
+		return true, nil
+	}
+	return false, errors.New(C.GoString(m.out.msg))
+}
+
+func (m *midiOut) PlayNote(ch, key, vel int, d time.Duration) (*Note, error) {
+	return m.notes.play(m, ch, key, vel, d)
+}
+
+func (m *midiOut) setClock(c Clock) {
+	m.notes.setClock(c)
+	m.timed.setClock(c)
+}
+
+// Destroy releases the MIDIOut without closing the port first. Destroying
+// it after Close, or again, does nothing.
 func (m *midiOut) Destroy() {
+	if m.out == nil {
+		return
+	}
+	m.notes.stopAll()
+	m.timed.close()
+	C.rtmidi_out_release_schedule(m.out)
+	m.releaseWarnings()
 	C.rtmidi_out_free(m.out)
+	m.out, m.midi.midi = nil, nil
 }
@@ -1,4 +1,329 @@
+#include "../../../rtmidi_c.h"
 #include "../../../RtMidi.h"
 
 #include "../../../RtMidi.cpp"
 #include "../../../rtmidi_c.cpp"
+
+#include <string.h>
+#include <map>
+#include <mutex>
+#include "rtmidi_stub.h"
+
+// The Go binding needs the backend state that RtMidi keeps protected. A
+// pointer to a protected member may be formed through a derived class, which
+// gives access without modifying RtMidi itself.
+struct RtMidiAccess : RtMidi {
+    static MidiApi *api (RtMidi *m) { return m->*(&RtMidiAccess::rtapi_); }
+};
+
+struct MidiApiAccess : MidiApi {
+    static void *data (MidiApi *a) { return a->*(&MidiApiAccess::apiData_); }
+    static bool connected (MidiApi *a) { return a->*(&MidiApiAccess::connected_); }
+    static void clearFirstError (MidiApi *a) { a->*(&MidiApiAccess::firstErrorOccurred_) = false; }
+};
+
+static void stub_error (RtMidiPtr device, const char *msg)
+{
+    device->ok  = false;
+    device->msg = msg;
+}
+
+void rtmidi_connect_port (RtMidiPtr device, unsigned int portNumber)
+{
+    MidiApi *api = RtMidiAccess::api ((RtMidi*) device->ptr);
+    bool input = dynamic_cast<MidiInApi*> (api) != NULL;
+
+    switch (api->getCurrentApi ()) {
+#if defined(__LINUX_ALSA__)
+    case RtMidi::LINUX_ALSA: {
+        AlsaMidiData *data = static_cast<AlsaMidiData *> (MidiApiAccess::data (api));
+        if (data->vport < 0) {
+            stub_error (device, "rtmidi_connect_port: no virtual port open");
+            return;
+        }
+        snd_seq_port_info_t *pinfo;
+        snd_seq_port_info_alloca (&pinfo);
+        unsigned int caps = input ? SND_SEQ_PORT_CAP_READ|SND_SEQ_PORT_CAP_SUBS_READ
+                                  : SND_SEQ_PORT_CAP_WRITE|SND_SEQ_PORT_CAP_SUBS_WRITE;
+        if (portInfo (data->seq, pinfo, caps, (int) portNumber) == 0) {
+            stub_error (device, "rtmidi_connect_port: invalid port number");
+            return;
+        }
+        int client = snd_seq_port_info_get_client (pinfo);
+        int port = snd_seq_port_info_get_port (pinfo);
+        int err = input ? snd_seq_connect_from (data->seq, data->vport, client, port)
+                        : snd_seq_connect_to (data->seq, data->vport, client, port);
+        if (err < 0)
+            stub_error (device, "rtmidi_connect_port: ALSA error making connection");
+        return;
+    }
+#endif
+#if defined(__UNIX_JACK__)
+    case RtMidi::UNIX_JACK: {
+        JackMidiData *data = static_cast<JackMidiData *> (MidiApiAccess::data (api));
+        if (data->port == NULL) {
+            stub_error (device, "rtmidi_connect_port: no virtual port open");
+            return;
+        }
+        std::string name = api->getPortName (portNumber);
+        if (name.empty ()) {
+            stub_error (device, "rtmidi_connect_port: invalid port number");
+            return;
+        }
+        int err = input ? jack_connect (data->client, name.c_str (), jack_port_name (data->port))
+                        : jack_connect (data->client, jack_port_name (data->port), name.c_str ());
+        if (err != 0 && err != EEXIST)
+            stub_error (device, "rtmidi_connect_port: JACK error making connection");
+        return;
+    }
+#endif
+    default:
+        stub_error (device, "rtmidi_connect_port: not supported by this API");
+    }
+}
+
+void rtmidi_set_port_name (RtMidiPtr device, const char *portName)
+{
+    RtMidi *m = (RtMidi*) device->ptr;
+    switch (RtMidiAccess::api (m)->getCurrentApi ()) {
+    case RtMidi::LINUX_ALSA:
+    case RtMidi::UNIX_JACK:
+        break;
+    default:
+        // The other APIs only warn that renaming is not implemented.
+        stub_error (device, "rtmidi_set_port_name: not supported by this API");
+        return;
+    }
+    try {
+        m->setPortName (portName);
+    } catch (const RtMidiError &err) {
+        device->ok  = false;
+        device->msg = err.what ();
+    }
+}
+
+// Installing an error callback stops RtMidi from throwing errors, so the
+// callback throws them itself after handing warnings to Go.
+struct StubWarningHandler {
+    RtMidiWarningCallback callback;
+    void *data;
+    MidiApi *api;
+};
+
+static std::map<RtMidiPtr, StubWarningHandler*> warningHandlers;
+static std::mutex warningHandlersMutex;
+
+static void stub_error_callback (RtMidiError::Type type, const std::string &text, void *data)
+{
+    StubWarningHandler *h = (StubWarningHandler*) data;
+    if (type == RtMidiError::WARNING || type == RtMidiError::DEBUG_WARNING) {
+        h->callback (type == RtMidiError::DEBUG_WARNING, text.c_str (), h->data);
+        return;
+    }
+    // RtMidi guards against errors raised from the callback until it
+    // returns, which a throw skips.
+    MidiApiAccess::clearFirstError (h->api);
+    throw RtMidiError (text, type);
+}
+
+void rtmidi_set_warning_handler (RtMidiPtr device, RtMidiWarningCallback callback, void *data)
+{
+    MidiApi *api = RtMidiAccess::api ((RtMidi*) device->ptr);
+    std::lock_guard<std::mutex> lock (warningHandlersMutex);
+    std::map<RtMidiPtr, StubWarningHandler*>::iterator it = warningHandlers.find (device);
+    if (it != warningHandlers.end ()) {
+        api->setErrorCallback (NULL, NULL);
+        delete it->second;
+        warningHandlers.erase (it);
+    }
+    if (callback == NULL)
+        return;
+    StubWarningHandler *h = new StubWarningHandler;
+    h->callback = callback;
+    h->data = data;
+    h->api = api;
+    warningHandlers[device] = h;
+    api->setErrorCallback (stub_error_callback, h);
+}
+
+void rtmidi_get_native (RtMidiPtr device, struct RtMidiNative *native)
+{
+    MidiApi *api = RtMidiAccess::api ((RtMidi*) device->ptr);
+    void *apiData = MidiApiAccess::data (api);
+    bool input = dynamic_cast<MidiInApi*> (api) != NULL;
+
+    memset (native, 0, sizeof (*native));
+    native->vport = -1;
+    switch (api->getCurrentApi ()) {
+#if defined(__LINUX_ALSA__)
+    case RtMidi::LINUX_ALSA: {
+        AlsaMidiData *data = static_cast<AlsaMidiData *> (apiData);
+        native->handle = data->seq;
+        native->client = snd_seq_client_id (data->seq);
+        native->vport = data->vport;
+        return;
+    }
+#endif
+#if defined(__UNIX_JACK__)
+    case RtMidi::UNIX_JACK: {
+        JackMidiData *data = static_cast<JackMidiData *> (apiData);
+        native->handle = data->client;
+        native->port = data->port;
+        return;
+    }
+#endif
+#if defined(__MACOSX_CORE__)
+    case RtMidi::MACOSX_CORE: {
+        CoreMidiData *data = static_cast<CoreMidiData *> (apiData);
+        native->coremidi_client = data->client;
+        native->coremidi_port = data->port;
+        native->coremidi_endpoint = data->endpoint;
+        native->coremidi_destination = data->destinationId;
+        return;
+    }
+#endif
+#if defined(__WINDOWS_MM__)
+    case RtMidi::WINDOWS_MM: {
+        WinMidiData *data = static_cast<WinMidiData *> (apiData);
+        native->handle = input ? (void*) data->inHandle : (void*) data->outHandle;
+        return;
+    }
+#endif
+    default:
+        (void) apiData;
+        (void) input;
+        stub_error (device, "rtmidi_get_native: no native handles for this API");
+    }
+}
+
+#if defined(__LINUX_ALSA__)
+// ALSA delivers scheduled events through a queue, allocated for an output
+// on its first scheduled message.
+static std::mutex alsaQueuesMutex;
+static std::map<RtMidiOutPtr, int> alsaQueues;
+
+static int alsa_queue (RtMidiOutPtr device, snd_seq_t *seq)
+{
+    std::lock_guard<std::mutex> lock (alsaQueuesMutex);
+    std::map<RtMidiOutPtr, int>::iterator it = alsaQueues.find (device);
+    if (it != alsaQueues.end ())
+        return it->second;
+    int queue = snd_seq_alloc_queue (seq);
+    if (queue < 0)
+        return queue;
+    snd_seq_start_queue (seq, queue, NULL);
+    snd_seq_drain_output (seq);
+    alsaQueues[device] = queue;
+    return queue;
+}
+
+static int alsa_send_at (RtMidiOutPtr device, AlsaMidiData *data, const unsigned char *message, int length, double delay)
+{
+    int queue = alsa_queue (device, data->seq);
+    if (queue < 0) {
+        stub_error (device, "rtmidi_out_send_message_at: ALSA error allocating queue");
+        return -1;
+    }
+    // The coder is shared with sendMessage, which grows it again as needed.
+    if ((unsigned int) length > data->bufferSize
+        && snd_midi_event_resize_buffer (data->coder, length) != 0) {
+        stub_error (device, "rtmidi_out_send_message_at: ALSA error resizing MIDI event buffer");
+        return -1;
+    }
+
+    snd_seq_real_time_t rt;
+    rt.tv_sec = (unsigned int) delay;
+    rt.tv_nsec = (unsigned int) ((delay - rt.tv_sec) * 1e9);
+
+    snd_midi_event_reset_encode (data->coder);
+    long offset = 0;
+    while (offset < length) {
+        snd_seq_event_t ev;
+        snd_seq_ev_clear (&ev);
+        snd_seq_ev_set_source (&ev, data->vport);
+        snd_seq_ev_set_subs (&ev);
+        snd_seq_ev_schedule_real (&ev, queue, 1, &rt);
+        long result = snd_midi_event_encode (data->coder, message + offset, length - offset, &ev);
+        if (result < 0 || ev.type == SND_SEQ_EVENT_NONE) {
+            stub_error (device, "rtmidi_out_send_message_at: event parsing error");
+            return -1;
+        }
+        offset += result;
+        if (snd_seq_event_output (data->seq, &ev) < 0) {
+            stub_error (device, "rtmidi_out_send_message_at: error sending MIDI message to port");
+            return -1;
+        }
+    }
+    snd_seq_drain_output (data->seq);
+    return 1;
+}
+#endif
+
+#if defined(__MACOSX_CORE__)
+static int core_send_at (RtMidiOutPtr device, MidiApi *api, CoreMidiData *data, const unsigned char *message, int length, double delay)
+{
+    MIDITimeStamp timeStamp = AudioGetCurrentHostTime () + AudioConvertNanosToHostTime ((UInt64) (delay * 1e9));
+    ByteCount remaining = length;
+    std::vector<Byte> buffer ((remaining > 65535 ? 65535 : remaining) + 16);
+    MIDIPacketList *packetList = (MIDIPacketList*) &buffer[0];
+
+    while (remaining) {
+        MIDIPacket *packet = MIDIPacketListInit (packetList);
+        ByteCount bytesForPacket = remaining > 65535 ? 65535 : remaining;
+        packet = MIDIPacketListAdd (packetList, buffer.size (), packet, timeStamp, bytesForPacket,
+                                    (const Byte*) &message[length - remaining]);
+        remaining -= bytesForPacket;
+        if (!packet) {
+            stub_error (device, "rtmidi_out_send_message_at: could not allocate packet list");
+            return -1;
+        }
+        if (data->endpoint && MIDIReceived (data->endpoint, packetList) != noErr) {
+            stub_error (device, "rtmidi_out_send_message_at: error sending MIDI to virtual destinations");
+            return -1;
+        }
+        if (MidiApiAccess::connected (api) && MIDISend (data->port, data->destinationId, packetList) != noErr) {
+            stub_error (device, "rtmidi_out_send_message_at: error sending MIDI message to port");
+            return -1;
+        }
+    }
+    return 1;
+}
+#endif
+
+int rtmidi_out_send_message_at (RtMidiOutPtr device, const unsigned char *message, int length, double delay)
+{
+    MidiApi *api = RtMidiAccess::api ((RtMidi*) device->ptr);
+
+    if (length <= 0) {
+        stub_error (device, "rtmidi_out_send_message_at: no data in message argument");
+        return -1;
+    }
+    switch (api->getCurrentApi ()) {
+#if defined(__LINUX_ALSA__)
+    case RtMidi::LINUX_ALSA:
+        return alsa_send_at (device, static_cast<AlsaMidiData *> (MidiApiAccess::data (api)), message, length, delay);
+#endif
+#if defined(__MACOSX_CORE__)
+    case RtMidi::MACOSX_CORE:
+        return core_send_at (device, api, static_cast<CoreMidiData *> (MidiApiAccess::data (api)), message, length, delay);
+#endif
+    default:
+        return 0;
+    }
+}
+
+void rtmidi_out_release_schedule (RtMidiOutPtr device)
+{
+#if defined(__LINUX_ALSA__)
+    std::lock_guard<std::mutex> lock (alsaQueuesMutex);
+    std::map<RtMidiOutPtr, int>::iterator it = alsaQueues.find (device);
+    if (it == alsaQueues.end ())
+        return;
+    MidiApi *api = RtMidiAccess::api ((RtMidi*) device->ptr);
+    AlsaMidiData *data = static_cast<AlsaMidiData *> (MidiApiAccess::data (api));
+    snd_seq_free_queue (data->seq, it->second);
+    alsaQueues.erase (it);
+#else
+    (void) device;
+#endif
+}
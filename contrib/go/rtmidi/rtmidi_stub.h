@@ -1 +1,49 @@
 #include "../../../rtmidi_c.h"
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+/* Connect the virtual port of device to the port listed as portNumber by
+   device, using the connection API of the backend (ALSA or JACK). */
+void rtmidi_connect_port (RtMidiPtr device, unsigned int portNumber);
+
+/* Rename the port of device. Supported by the ALSA and JACK APIs. */
+void rtmidi_set_port_name (RtMidiPtr device, const char *portName);
+
+/* Called with a warning of a device; debug is set for the warnings RtMidi
+   only prints in debug builds. */
+typedef void (*RtMidiWarningCallback) (int debug, const char *message, void *data);
+
+/* Pass the warnings of device to callback instead of printing them. Errors
+   are reported as before. A NULL callback restores printing. */
+void rtmidi_set_warning_handler (RtMidiPtr device, RtMidiWarningCallback callback, void *data);
+
+/* Native handles of the backend behind a device. Only the fields belonging
+   to the current API are set; the others are zero. */
+struct RtMidiNative {
+    void *handle;                  /* snd_seq_t*, jack_client_t*, HMIDIIN or HMIDIOUT */
+    void *port;                    /* jack_port_t* */
+    int client;                    /* ALSA client id */
+    int vport;                     /* ALSA port of this end, -1 if none */
+    unsigned int coremidi_client;  /* MIDIClientRef */
+    unsigned int coremidi_port;    /* MIDIPortRef */
+    unsigned int coremidi_endpoint;    /* MIDIEndpointRef of a virtual port */
+    unsigned int coremidi_destination; /* MIDIEndpointRef connected to */
+};
+
+/* Fill native with the handles of device. */
+void rtmidi_get_native (RtMidiPtr device, struct RtMidiNative *native);
+
+/* Send message after delay seconds using the scheduler of the backend.
+   Returns 1 if the message was queued, 0 if the backend cannot schedule
+   messages and -1 on error. */
+int rtmidi_out_send_message_at (RtMidiOutPtr device, const unsigned char *message, int length, double delay);
+
+/* Release the resources used by rtmidi_out_send_message_at, dropping any
+   messages still queued. */
+void rtmidi_out_release_schedule (RtMidiOutPtr device);
+
+#ifdef __cplusplus
+}
+#endif
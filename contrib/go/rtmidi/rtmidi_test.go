@@ -1,7 +1,9 @@
 package rtmidi
 
 import (
+	"context"
 	"log"
+	"time"
 )
 
 func ExampleCompiledAPI() {
@@ -44,3 +46,42 @@ func ExampleMIDIIn_SetCallback() {
 	})
 	<-make(chan struct{})
 }
+
+func ExampleSetBufferedCallback() {
+	in, err := NewMIDIInDefault()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer in.Destroy()
+	if err := in.OpenPort(0, "RtMidi"); err != nil {
+		log.Fatal(err)
+	}
+	defer in.Close()
+	SetBufferedCallback(in, 1024, func(m MIDIIn, msg []byte, t float64) {
+		// Slow processing here does not hold up the MIDI driver.
+		log.Println(msg, t)
+	})
+	<-make(chan struct{})
+}
+
+func ExampleFlush() {
+	out, err := NewMIDIOutDefault()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer out.Destroy()
+	if err := out.OpenPort(0, "RtMidi"); err != nil {
+		log.Fatal(err)
+	}
+	defer out.Close()
+	s := NewScheduler(out)
+	defer s.Close()
+	s.ScheduleAfter(0, []byte{0x90, 60, 100})
+	s.ScheduleAfter(time.Second, []byte{0x80, 60, 0})
+	// Wait for the NoteOff before closing the port.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := Flush(ctx, out); err != nil {
+		log.Println(err)
+	}
+}
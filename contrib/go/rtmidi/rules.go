@@ -0,0 +1,195 @@
+package rtmidi
+
+// Predicate reports whether a message matches a condition of a routing
+// rule. Predicates are built once when the rule is made, so matching a
+// message costs no more than a few comparisons.
+type Predicate func(msg []byte) bool
+
+// And returns a predicate matching messages that match both p and q.
+func (p Predicate) And(q Predicate) Predicate {
+	return func(msg []byte) bool { return p(msg) && q(msg) }
+}
+
+// Or returns a predicate matching messages that match p or q.
+func (p Predicate) Or(q Predicate) Predicate {
+	return func(msg []byte) bool { return p(msg) || q(msg) }
+}
+
+// Not returns a predicate matching messages that p does not match.
+func Not(p Predicate) Predicate {
+	return func(msg []byte) bool { return !p(msg) }
+}
+
+// Any matches every message.
+func Any() Predicate {
+	return func(msg []byte) bool { return true }
+}
+
+// Channel matches channel messages on any of the given channels (0-15).
+func Channel(chs ...int) Predicate {
+	var mask uint16
+	for _, ch := range chs {
+		mask |= 1 << uint(ch&0x0f)
+	}
+	return func(msg []byte) bool {
+		return len(msg) > 0 && msg[0] >= 0x80 && msg[0] < 0xf0 && mask&(1<<(msg[0]&0x0f)) != 0
+	}
+}
+
+// Type matches messages of any of the given kinds, given as channel message
+// status bytes without the channel, such as 0x90 for NoteOn, or as system
+// status bytes such as 0xf8 for Clock.
+func Type(statuses ...byte) Predicate {
+	var set [256]bool
+	for _, s := range statuses {
+		set[s] = true
+	}
+	return func(msg []byte) bool {
+		if len(msg) == 0 {
+			return false
+		}
+		s := msg[0]
+		if s < 0xf0 {
+			s &= 0xf0
+		}
+		return set[s]
+	}
+}
+
+// Notes matches NoteOn, NoteOff and polyphonic aftertouch messages.
+func Notes() Predicate {
+	return func(msg []byte) bool {
+		return len(msg) >= 2 && msg[0] >= 0x80 && msg[0] < 0xb0
+	}
+}
+
+// NoteRange matches NoteOn, NoteOff and polyphonic aftertouch messages
+// for keys lo to hi inclusive.
+func NoteRange(lo, hi int) Predicate {
+	return func(msg []byte) bool {
+		if len(msg) < 2 || msg[0] < 0x80 || msg[0] >= 0xb0 {
+			return false
+		}
+		k := int(msg[1])
+		return k >= lo && k <= hi
+	}
+}
+
+// VelocityRange matches NoteOn messages with a velocity of lo to hi
+// inclusive. A Router sends the matching NoteOff wherever the NoteOn went,
+// so velocity splits do not leave notes hanging.
+func VelocityRange(lo, hi int) Predicate {
+	return func(msg []byte) bool {
+		if !isNoteOn(msg) {
+			return false
+		}
+		v := int(msg[2])
+		return v >= lo && v <= hi
+	}
+}
+
+// Controller matches control changes of any of the given controllers.
+func Controller(ccs ...int) Predicate {
+	var set [128]bool
+	for _, cc := range ccs {
+		set[cc&0x7f] = true
+	}
+	return func(msg []byte) bool {
+		return len(msg) >= 3 && msg[0]&0xf0 == 0xb0 && set[msg[1]&0x7f]
+	}
+}
+
+// Rule sends the messages matching a predicate to a set of outputs, after
+// optionally transforming them. Rules are made with When.
+type Rule struct {
+	match      Predicate
+	transforms []func([]byte) []byte
+	outs       []MIDIOut
+	final      bool
+	name       string
+	ports      []string
+}
+
+// RuleBuilder builds a Rule, see When.
+type RuleBuilder struct {
+	rule Rule
+}
+
+// When starts a rule matching messages that match p. Conditions are added
+// with And and Or, and the rule is completed with SendTo:
+//
+//	When(Channel(9)).And(NoteRange(35, 59)).SendTo(drumOut)
+func When(p Predicate) *RuleBuilder {
+	return &RuleBuilder{rule: Rule{match: p}}
+}
+
+// And narrows the rule to messages also matching p.
+func (b *RuleBuilder) And(p Predicate) *RuleBuilder {
+	b.rule.match = b.rule.match.And(p)
+	return b
+}
+
+// Or widens the rule to messages matching p.
+func (b *RuleBuilder) Or(p Predicate) *RuleBuilder {
+	b.rule.match = b.rule.match.Or(p)
+	return b
+}
+
+// From narrows the rule to messages from the given ports, told apart by
+// their ID. Messages routed without an origin, with Router.Route or
+// Router.Callback, never match a rule narrowed with From.
+func (b *RuleBuilder) From(refs ...PortRef) *RuleBuilder {
+	n := len(b.rule.ports)
+	b.rule.ports = b.rule.ports[:n:n]
+	for _, ref := range refs {
+		b.rule.ports = append(b.rule.ports, ref.ID)
+	}
+	return b
+}
+
+// Transform makes the rule send fn(msg) instead of msg. fn receives a copy
+// it may modify, and may return nil to drop the message.
+func (b *RuleBuilder) Transform(fn func(msg []byte) []byte) *RuleBuilder {
+	n := len(b.rule.transforms)
+	b.rule.transforms = append(b.rule.transforms[:n:n], fn)
+	return b
+}
+
+// SendTo completes the rule, sending matching messages to outs.
+func (b *RuleBuilder) SendTo(outs ...MIDIOut) *Rule {
+	r := b.rule
+	r.outs = outs
+	return &r
+}
+
+// Final stops a Router from trying the rules after r on messages r
+// matched.
+func (r *Rule) Final() *Rule {
+	r.final = true
+	return r
+}
+
+// Named names the rule in traces, see Router.SetTracing.
+func (r *Rule) Named(name string) *Rule {
+	r.name = name
+	return r
+}
+
+// Match reports whether msg matches the rule, regardless of the ports
+// given to From.
+func (r *Rule) Match(msg []byte) bool {
+	return r.match(msg)
+}
+
+// from reports whether the rule takes messages from the port origin.
+func (r *Rule) from(origin PortRef) bool {
+	if r.ports == nil {
+		return true
+	}
+	for _, id := range r.ports {
+		if id != "" && id == origin.ID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package rtmidi
+
+import "testing"
+
+func TestPredicates(t *testing.T) {
+	noteOn := []byte{0x99, 36, 100}
+	noteOff := []byte{0x89, 36, 0}
+	cc := []byte{0xb0, 64, 127}
+	clock := []byte{0xf8}
+	for _, tt := range []struct {
+		name string
+		p    Predicate
+		msgs [][]byte
+		want []bool
+	}{
+		{"Channel", Channel(9, 10), [][]byte{noteOn, cc, clock}, []bool{true, false, false}},
+		{"Type", Type(0xb0, 0xf8), [][]byte{noteOn, cc, clock}, []bool{false, true, true}},
+		{"Notes", Notes(), [][]byte{noteOn, noteOff, cc}, []bool{true, true, false}},
+		{"NoteRange", NoteRange(35, 59), [][]byte{noteOn, {0x90, 60, 1}, cc}, []bool{true, false, false}},
+		{"VelocityRange", VelocityRange(90, 127), [][]byte{noteOn, noteOff, {0x90, 60, 89}}, []bool{true, false, false}},
+		{"Controller", Controller(64), [][]byte{cc, {0xb0, 1, 0}, noteOn}, []bool{true, false, false}},
+		{"And", Channel(0).And(Type(0xb0)), [][]byte{cc, noteOn, {0xb1, 64, 0}}, []bool{true, false, false}},
+		{"Or", Type(0xf8).Or(Controller(64)), [][]byte{cc, clock, noteOn}, []bool{true, true, false}},
+		{"Not", Not(Channel(9)), [][]byte{noteOn, cc, nil}, []bool{false, true, true}},
+	} {
+		for i, msg := range tt.msgs {
+			if got := tt.p(msg); got != tt.want[i] {
+				t.Errorf("%s(% x) = %v, want %v", tt.name, msg, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestRuleTransform(t *testing.T) {
+	out := &fakeOut{}
+	transpose := func(msg []byte) []byte { msg[1] += 12; return msg }
+	drop := func(msg []byte) []byte {
+		if msg[1] > 100 {
+			return nil
+		}
+		return msg
+	}
+	r := NewRouter(When(Notes()).Transform(transpose).Transform(drop).SendTo(out))
+	msg := []byte{0x90, 60, 100}
+	r.Route(msg)
+	r.Route([]byte{0x90, 95, 100})
+	if msg[1] != 60 {
+		t.Error("transform modified the routed message")
+	}
+	if got := out.messages(); len(got) != 1 || got[0][1] != 72 {
+		t.Errorf("sent % x", got)
+	}
+}
@@ -0,0 +1,110 @@
+package rtmidi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ScanResult lists the ports of an API.
+type ScanResult struct {
+	API             API
+	Inputs, Outputs []PortRef
+	// Err is the first error listing the ports, or the error of the
+	// context if the API did not finish in time, in which case the ports
+	// listed so far are given.
+	Err error
+}
+
+// ScanAll lists the ports of every compiled API, all at once, returning
+// the results in the order of CompiledAPI. Listing ports can block for
+// seconds with some backends, such as a stale JACK server or Bluetooth
+// stack; ScanAll returns when ctx is done even so, with the ports listed
+// so far and the error of the context for the APIs that did not finish.
+// Those are left to finish in the background, and their clients closed
+// then.
+func ScanAll(ctx context.Context) []ScanResult {
+	apis := compiledAPI()
+	var mu sync.Mutex
+	results := make([]ScanResult, len(apis))
+	finished := make([]bool, len(apis))
+	done := make(chan struct{}, len(apis))
+	for i, api := range apis {
+		results[i].API = api
+		go func(i int, api API) {
+			scanAPI(api, func(update func(r *ScanResult)) {
+				mu.Lock()
+				defer mu.Unlock()
+				update(&results[i])
+			})
+			mu.Lock()
+			finished[i] = true
+			mu.Unlock()
+			done <- struct{}{}
+		}(i, api)
+	}
+	for range apis {
+		select {
+		case <-done:
+			continue
+		case <-ctx.Done():
+		}
+		break
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	scanned := append([]ScanResult(nil), results...)
+	for i := range scanned {
+		if !finished[i] && scanned[i].Err == nil {
+			scanned[i].Err = ctx.Err()
+		}
+	}
+	return scanned
+}
+
+// scanAPI lists the ports of api, recording them with record as they are
+// listed.
+func scanAPI(api API, record func(func(r *ScanResult))) {
+	in, err := newMIDIIn(api, DefaultInputClient, DefaultQueueSize)
+	if err != nil {
+		record(func(r *ScanResult) { r.Err = fmt.Errorf("rtmidi: creating input: %w", err) })
+		return
+	}
+	err = listPorts(in, func(ref PortRef) {
+		record(func(r *ScanResult) { r.Inputs = append(r.Inputs, ref) })
+	})
+	in.Close()
+	if err != nil {
+		record(func(r *ScanResult) { r.Err = err })
+		return
+	}
+	out, err := newMIDIOut(api, DefaultOutputClient)
+	if err != nil {
+		record(func(r *ScanResult) { r.Err = fmt.Errorf("rtmidi: creating output: %w", err) })
+		return
+	}
+	err = listPorts(out, func(ref PortRef) {
+		record(func(r *ScanResult) { r.Outputs = append(r.Outputs, ref) })
+	})
+	out.Close()
+	if err != nil {
+		record(func(r *ScanResult) { r.Err = err })
+	}
+}
+
+// listPorts calls add with the PortRef of each port m lists, as soon as it
+// is known.
+func listPorts(m MIDI, add func(PortRef)) error {
+	n, err := m.PortCount()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		ref, err := NewPortRef(m, i)
+		if err != nil {
+			return err
+		}
+		add(ref)
+	}
+	return nil
+}
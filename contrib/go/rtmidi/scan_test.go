@@ -0,0 +1,65 @@
+package rtmidi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingOut is an output whose port listing blocks after the first port
+// until unblocked.
+type blockingOut struct {
+	namedPorts
+	unblock chan struct{}
+}
+
+func (b *blockingOut) PortName(port int) (string, error) {
+	if port > 0 {
+		<-b.unblock
+	}
+	return b.namedPorts.PortName(port)
+}
+
+func TestScanAll(t *testing.T) {
+	defer func() { compiledAPI, newMIDIIn, newMIDIOut = CompiledAPI, NewMIDIIn, NewMIDIOut }()
+	unblock := make(chan struct{})
+	defer close(unblock)
+	compiledAPI = func() []API { return []API{APILinuxALSA, APIUnixJack, APIDummy} }
+	newMIDIIn = func(api API, client string, size int) (MIDIIn, error) {
+		if api == APIDummy {
+			return nil, errors.New("no dummy")
+		}
+		return &fakeIn{}, nil
+	}
+	newMIDIOut = func(api API, client string) (MIDIOut, error) {
+		names := []string{"Synth:Synth MIDI 1 20:0"}
+		if api == APIUnixJack {
+			names = append(names, "Drums:Drums MIDI 1 24:0")
+			return &blockingOut{namedPorts: namedPorts{names: names}, unblock: unblock}, nil
+		}
+		return &namedPorts{names: names}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results := ScanAll(ctx)
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("ScanAll took %v", d)
+	}
+	if len(results) != 3 {
+		t.Fatalf("%d results", len(results))
+	}
+	alsa, jack, dummy := results[0], results[1], results[2]
+	if alsa.Err != nil || len(alsa.Outputs) != 1 || alsa.Outputs[0].ID != "dummy:Synth:Synth MIDI 1" {
+		t.Errorf("alsa %+v", alsa)
+	}
+	// The ports listed before the deadline are given.
+	if jack.API != APIUnixJack || jack.Err != context.DeadlineExceeded || len(jack.Outputs) != 1 {
+		t.Errorf("jack %+v", jack)
+	}
+	if dummy.Err == nil {
+		t.Errorf("dummy %+v", dummy)
+	}
+}
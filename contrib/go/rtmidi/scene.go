@@ -0,0 +1,239 @@
+package rtmidi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Scene is a named set of messages, such as program changes, controller
+// values and SysEx, sent together to switch a rig to a new sound.
+type Scene struct {
+	Name string
+	// Messages are sent in order.
+	Messages [][]byte
+	// Outputs names the outputs the scene is sent to, as added to the
+	// SceneEngine. Empty means all of them.
+	Outputs []string
+	// Delay is waited before sending the first message.
+	Delay time.Duration
+	// Pace is waited between messages, for devices that drop messages
+	// arriving too quickly after a program change or SysEx.
+	Pace time.Duration
+}
+
+// sceneJSON is how a Scene is stored: messages as hex strings such as
+// "c0 05" and durations as strings such as "20ms".
+type sceneJSON struct {
+	Name     string   `json:"name"`
+	Messages []string `json:"messages"`
+	Outputs  []string `json:"outputs,omitempty"`
+	Delay    string   `json:"delay,omitempty"`
+	Pace     string   `json:"pace,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s Scene) MarshalJSON() ([]byte, error) {
+	j := sceneJSON{Name: s.Name, Outputs: s.Outputs}
+	for _, msg := range s.Messages {
+		j.Messages = append(j.Messages, hexMessage(msg))
+	}
+	if s.Delay != 0 {
+		j.Delay = s.Delay.String()
+	}
+	if s.Pace != 0 {
+		j.Pace = s.Pace.String()
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *Scene) UnmarshalJSON(b []byte) error {
+	var j sceneJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	scene := Scene{Name: j.Name, Outputs: j.Outputs}
+	for _, m := range j.Messages {
+		msg, err := parseHexMessage(m)
+		if err != nil || len(msg) == 0 {
+			return fmt.Errorf("rtmidi: scene %q: invalid message %q", j.Name, m)
+		}
+		scene.Messages = append(scene.Messages, msg)
+	}
+	var err error
+	if j.Delay != "" {
+		if scene.Delay, err = time.ParseDuration(j.Delay); err != nil {
+			return fmt.Errorf("rtmidi: scene %q: %w", j.Name, err)
+		}
+	}
+	if j.Pace != "" {
+		if scene.Pace, err = time.ParseDuration(j.Pace); err != nil {
+			return fmt.Errorf("rtmidi: scene %q: %w", j.Name, err)
+		}
+	}
+	*s = scene
+	return nil
+}
+
+// SceneEngine switches between scenes on a set of named outputs. Scenes are
+// triggered one at a time: a scene is sent completely before the next one
+// starts.
+type SceneEngine struct {
+	mu      sync.Mutex
+	outputs map[string]MIDIOut
+	scenes  map[string]Scene
+
+	trigger sync.Mutex
+	current string
+}
+
+// NewSceneEngine returns a SceneEngine with no outputs or scenes.
+func NewSceneEngine() *SceneEngine {
+	return &SceneEngine{outputs: map[string]MIDIOut{}, scenes: map[string]Scene{}}
+}
+
+// AddOutput makes out available to scenes under name.
+func (e *SceneEngine) AddOutput(name string, out MIDIOut) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.outputs[name] = out
+}
+
+// Add adds a scene, replacing any with the same name.
+func (e *SceneEngine) Add(s Scene) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scenes[s.Name] = s
+}
+
+// Scenes returns the names of the scenes, sorted.
+func (e *SceneEngine) Scenes() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.sortedNames()
+}
+
+// Load adds the scenes of a JSON document of the form
+//
+//	{"scenes": [{"name": "verse", "outputs": ["synth"], "pace": "5ms",
+//	  "messages": ["b0 00 01", "c0 05", "b0 07 64"]}]}
+func (e *SceneEngine) Load(r io.Reader) error {
+	var doc struct {
+		Scenes []Scene `json:"scenes"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return err
+	}
+	for _, s := range doc.Scenes {
+		e.Add(s)
+	}
+	return nil
+}
+
+// Save writes the scenes as a JSON document readable by Load.
+func (e *SceneEngine) Save(w io.Writer) error {
+	var doc struct {
+		Scenes []Scene `json:"scenes"`
+	}
+	e.mu.Lock()
+	for _, name := range e.sortedNames() {
+		doc.Scenes = append(doc.Scenes, e.scenes[name])
+	}
+	e.mu.Unlock()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func (e *SceneEngine) sortedNames() []string {
+	names := make([]string, 0, len(e.scenes))
+	for name := range e.scenes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Trigger sends the scene called name. Its outputs are checked before
+// anything is sent; each message then goes to every output before the next
+// one. Trigger waits for a scene being sent to complete first.
+//
+// A scene is sent whole, so that no output is left between two scenes: ctx
+// only cancels the trigger until the first message is sent, and an output
+// failing is left out for the rest of the scene while the others still
+// receive all of it. The error then names the output, and Current is not
+// changed.
+func (e *SceneEngine) Trigger(ctx context.Context, name string) error {
+	e.mu.Lock()
+	s, ok := e.scenes[name]
+	if !ok {
+		e.mu.Unlock()
+		return fmt.Errorf("rtmidi: no scene %q", name)
+	}
+	outNames := s.Outputs
+	if len(outNames) == 0 {
+		outNames = e.outputNames()
+	}
+	outs := make([]MIDIOut, len(outNames))
+	for i, n := range outNames {
+		out, ok := e.outputs[n]
+		if !ok {
+			e.mu.Unlock()
+			return fmt.Errorf("rtmidi: scene %q: no output %q", name, n)
+		}
+		outs[i] = out
+	}
+	e.mu.Unlock()
+
+	e.trigger.Lock()
+	defer e.trigger.Unlock()
+	if err := sleepCtx(ctx, SystemClock, s.Delay); err != nil {
+		return err
+	}
+	var first error
+	failed := make([]bool, len(outs))
+	for i, msg := range s.Messages {
+		if i > 0 {
+			sleepCtx(context.Background(), SystemClock, s.Pace)
+		}
+		for k, out := range outs {
+			if failed[k] {
+				continue
+			}
+			if err := out.SendMessage(msg); err != nil {
+				failed[k] = true
+				if first == nil {
+					first = fmt.Errorf("rtmidi: scene %q: output %q: %w", name, outNames[k], err)
+				}
+			}
+		}
+	}
+	if first != nil {
+		return first
+	}
+	e.mu.Lock()
+	e.current = name
+	e.mu.Unlock()
+	return nil
+}
+
+func (e *SceneEngine) outputNames() []string {
+	names := make([]string, 0, len(e.outputs))
+	for name := range e.outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Current returns the name of the last scene sent completely.
+func (e *SceneEngine) Current() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.current
+}
@@ -0,0 +1,120 @@
+package rtmidi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testScenes = `{"scenes": [
+	{"name": "verse", "outputs": ["synth"], "messages": ["B0 00 01", "C0 05"], "pace": "10ms"},
+	{"name": "chorus", "messages": ["c0 07", "F0 7E 7F 09 01 F7"]}
+]}`
+
+func TestSceneEngine(t *testing.T) {
+	synth, fx := &fakeOut{}, &fakeOut{}
+	e := NewSceneEngine()
+	e.AddOutput("synth", synth)
+	e.AddOutput("fx", fx)
+	if err := e.Load(strings.NewReader(testScenes)); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Scenes(); !reflect.DeepEqual(got, []string{"chorus", "verse"}) {
+		t.Errorf("Scenes() = %v", got)
+	}
+
+	start := time.Now()
+	if err := e.Trigger(context.Background(), "verse"); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 10*time.Millisecond {
+		t.Error("scene not paced")
+	}
+	if got, want := synth.messages(), [][]byte{{0xb0, 0, 1}, {0xc0, 5}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("synth got % x, want % x", got, want)
+	}
+	if len(fx.messages()) != 0 {
+		t.Error("scene sent to an output it does not name")
+	}
+
+	if err := e.Trigger(context.Background(), "chorus"); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]byte{{0xc0, 7}, {0xf0, 0x7e, 0x7f, 0x09, 0x01, 0xf7}}
+	if got := fx.messages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("fx got % x, want % x", got, want)
+	}
+	if e.Current() != "chorus" {
+		t.Errorf("Current() = %q", e.Current())
+	}
+
+	if err := e.Trigger(context.Background(), "bridge"); err == nil {
+		t.Error("unknown scene triggered")
+	}
+	e.Add(Scene{Name: "bad", Outputs: []string{"synth", "drums"}, Messages: [][]byte{{0xc0, 1}}})
+	n := len(synth.messages())
+	if err := e.Trigger(context.Background(), "bad"); err == nil || len(synth.messages()) != n {
+		t.Errorf("scene with a missing output: %v, %d messages sent", err, len(synth.messages())-n)
+	}
+}
+
+func TestSceneJSON(t *testing.T) {
+	e := NewSceneEngine()
+	e.Add(Scene{Name: "intro", Messages: [][]byte{{0xc0, 1}}, Delay: 50 * time.Millisecond})
+	var buf bytes.Buffer
+	if err := e.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	e2 := NewSceneEngine()
+	if err := e2.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(e2.scenes, e.scenes) {
+		t.Errorf("round trip gave %v, want %v", e2.scenes, e.scenes)
+	}
+	if err := e2.Load(strings.NewReader(`{"scenes": [{"name": "x", "messages": ["C0 ZZ"]}]}`)); err == nil {
+		t.Error("invalid message accepted")
+	}
+}
+
+func TestSceneTriggerWhole(t *testing.T) {
+	synth, fx := &fakeOut{}, &fakeOut{}
+	e := NewSceneEngine()
+	e.AddOutput("synth", synth)
+	e.AddOutput("fx", fx)
+	e.Add(Scene{Name: "verse", Messages: [][]byte{{0xc0, 1}, {0xb0, 7, 90}, {0xb0, 10, 64}}, Pace: time.Millisecond})
+
+	// A context done once sending has started does not cut the scene short.
+	ctx, cancel := context.WithCancel(context.Background())
+	synth.onSend = func([]byte) { cancel() }
+	if err := e.Trigger(ctx, "verse"); err != nil {
+		t.Fatal(err)
+	}
+	if len(synth.messages()) != 3 || len(fx.messages()) != 3 {
+		t.Errorf("sent %d and %d messages, want 3", len(synth.messages()), len(fx.messages()))
+	}
+
+	// An output failing does not stop the others.
+	e.Add(Scene{Name: "chorus", Messages: [][]byte{{0xc0, 2}, {0xb0, 7, 100}}})
+	fx.err = errors.New("unplugged")
+	err := e.Trigger(context.Background(), "chorus")
+	if err == nil || !strings.Contains(err.Error(), `"fx"`) {
+		t.Errorf("Trigger returned %v", err)
+	}
+	if n := len(synth.messages()); n != 5 {
+		t.Errorf("synth got %d messages, want 5", n)
+	}
+	if e.Current() != "verse" {
+		t.Errorf("Current() = %q after a failed scene", e.Current())
+	}
+
+	b, _ := json.Marshal(Scene{Name: "x", Messages: [][]byte{{0xc0, 0x0a}}})
+	if !strings.Contains(string(b), `"c0 0a"`) {
+		t.Errorf("stored as %s", b)
+	}
+}
@@ -0,0 +1,233 @@
+package rtmidi
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type scheduled struct {
+	at  time.Time
+	seq uint64
+	msg []byte
+}
+
+type scheduleQueue []scheduled
+
+func (q scheduleQueue) Len() int { return len(q) }
+func (q scheduleQueue) Less(i, j int) bool {
+	if q[i].at.Equal(q[j].at) {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].at.Before(q[j].at)
+}
+func (q scheduleQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *scheduleQueue) Push(x interface{}) { *q = append(*q, x.(scheduled)) }
+func (q *scheduleQueue) Pop() interface{} {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
+
+// Scheduler sends messages to a MIDIOut at given times from its own
+// goroutine. Messages scheduled for the same time are sent in the order they
+// were scheduled.
+type Scheduler struct {
+	out     MIDIOut
+	mu      sync.Mutex
+	queue   scheduleQueue
+	seq     uint64
+	sending bool
+	emptied chan struct{}
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	ctl     chan realtimeRequest
+	closed  bool
+	err     func(error)
+	detach  func()
+	clock   Clock
+	clockID int
+}
+
+// NewScheduler returns a running Scheduler sending to out. Flushing out waits
+// for the scheduler's queue to empty.
+func NewScheduler(out MIDIOut) *Scheduler {
+	s := &Scheduler{
+		out:   out,
+		wake:  make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+		ctl:   make(chan realtimeRequest),
+		clock: SystemClock,
+	}
+	s.detach = attachQueue(out, s)
+	go s.loop()
+	return s
+}
+
+// Schedule queues msg to be sent at the given time. Messages whose time has
+// passed are sent immediately. It returns an error once the scheduler is
+// closed.
+func (s *Scheduler) Schedule(at time.Time, msg []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.New("rtmidi: scheduler is closed")
+	}
+	heap.Push(&s.queue, scheduled{at: at, seq: s.seq, msg: append([]byte(nil), msg...)})
+	s.seq++
+	s.wakeLocked()
+	return nil
+}
+
+// ScheduleAfter queues msg to be sent after d has elapsed.
+func (s *Scheduler) ScheduleAfter(d time.Duration, msg []byte) error {
+	return s.Schedule(s.now().Add(d), msg)
+}
+
+// now returns the time on the clock of the scheduler.
+func (s *Scheduler) now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clock.Now()
+}
+
+// SetClock sets the clock the times of the messages are on, SystemClock
+// by default.
+func (s *Scheduler) SetClock(c Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = clockOr(c)
+	s.clockID++
+	s.wakeLocked()
+}
+
+// wakeLocked has the goroutine of the scheduler look at the queue again.
+func (s *Scheduler) wakeLocked() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Pending returns the number of messages waiting to be sent.
+func (s *Scheduler) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
+
+// Clear drops all messages waiting to be sent.
+func (s *Scheduler) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = s.queue[:0]
+	s.notifyEmpty()
+}
+
+// Flush waits until every scheduled message has been sent or ctx is done.
+func (s *Scheduler) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	if len(s.queue) == 0 && !s.sending {
+		s.mu.Unlock()
+		return nil
+	}
+	if s.emptied == nil {
+		s.emptied = make(chan struct{})
+	}
+	emptied := s.emptied
+	s.mu.Unlock()
+	select {
+	case <-emptied:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) notifyEmpty() {
+	if len(s.queue) == 0 && !s.sending && s.emptied != nil {
+		close(s.emptied)
+		s.emptied = nil
+	}
+}
+
+// SetErrorHandler sets a function called with errors from sending scheduled
+// messages, which are otherwise ignored.
+func (s *Scheduler) SetErrorHandler(fn func(error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = fn
+}
+
+// SetRealtime sets how the goroutine sending the scheduled messages runs.
+func (s *Scheduler) SetRealtime(rt Realtime) error {
+	return requestRealtime(s.ctl, s.done, rt)
+}
+
+// Close stops the scheduler, dropping any messages waiting to be sent. It
+// does not close the MIDIOut.
+func (s *Scheduler) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	s.queue = nil
+	s.notifyEmpty()
+	s.mu.Unlock()
+	close(s.stop)
+	<-s.done
+	s.detach()
+}
+
+func (s *Scheduler) loop() {
+	defer close(s.done)
+	var thread realtimeThread
+	var timer Timer
+	timerClock := -1
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		s.mu.Lock()
+		var due [][]byte
+		clock, clockID := s.clock, s.clockID
+		now := clock.Now()
+		for len(s.queue) > 0 && !s.queue[0].at.After(now) {
+			due = append(due, heap.Pop(&s.queue).(scheduled).msg)
+		}
+		wait := time.Hour
+		if len(s.queue) > 0 {
+			wait = s.queue[0].at.Sub(now)
+		}
+		errFn := s.err
+		s.sending = len(due) > 0
+		s.mu.Unlock()
+		for _, msg := range due {
+			if err := s.out.SendMessage(msg); err != nil && errFn != nil {
+				errFn(err)
+			}
+		}
+		s.mu.Lock()
+		s.sending = false
+		s.notifyEmpty()
+		s.mu.Unlock()
+		timer, timerClock = resetTimer(timer, timerClock, clock, clockID, wait)
+		select {
+		case <-s.wake:
+		case <-s.stop:
+			return
+		case <-timer.C():
+		case req := <-s.ctl:
+			req.reply <- thread.set(req.rt)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package rtmidi
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScheduler(t *testing.T) {
+	out := &fakeOut{}
+	s := NewScheduler(out)
+	defer s.Close()
+	now := time.Now()
+	s.Schedule(now.Add(20*time.Millisecond), []byte{3})
+	s.Schedule(now.Add(10*time.Millisecond), []byte{1})
+	s.Schedule(now.Add(10*time.Millisecond), []byte{2})
+	s.Schedule(now.Add(time.Hour), []byte{4})
+	deadline := time.Now().Add(time.Second)
+	for len(out.messages()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := out.messages(); !reflect.DeepEqual(got, [][]byte{{1}, {2}, {3}}) {
+		t.Errorf("sent %v", got)
+	}
+	if n := s.Pending(); n != 1 {
+		t.Errorf("Pending() = %d, want 1", n)
+	}
+	s.Clear()
+	if n := s.Pending(); n != 0 {
+		t.Errorf("Pending() after Clear = %d", n)
+	}
+}
+
+func TestSchedulerFlush(t *testing.T) {
+	out := &fakeOut{}
+	var queues outQueues
+	s := NewScheduler(out)
+	defer s.Close()
+	detach := queues.attachQueue(s)
+	defer detach()
+	s.ScheduleAfter(20*time.Millisecond, []byte{1})
+	s.ScheduleAfter(30*time.Millisecond, []byte{2})
+	if err := queues.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(out.messages()); n != 2 {
+		t.Errorf("Flush returned with %d of 2 messages sent", n)
+	}
+	s.ScheduleAfter(time.Hour, []byte{3})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Flush(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Flush returned %v", err)
+	}
+}
+
+func TestSchedulerAfterClose(t *testing.T) {
+	s := NewScheduler(&fakeOut{})
+	s.Close()
+	s.SetClock(NewFakeClock(time.Now()))
+	if err := s.Schedule(time.Now(), []byte{0xf8}); err == nil {
+		t.Error("Schedule after Close succeeded")
+	}
+	s.Close()
+}
+
+func TestSchedulerScheduleWhileClosing(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		s := NewScheduler(&fakeOut{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for s.ScheduleAfter(time.Hour, []byte{0xf8}) == nil {
+			}
+		}()
+		s.Close()
+		<-done
+	}
+}
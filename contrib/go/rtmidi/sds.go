@@ -0,0 +1,340 @@
+package rtmidi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Sample Dump Standard message types.
+const (
+	sdsHeader  = 0x01
+	sdsPacket  = 0x02
+	sdsRequest = 0x03
+	sdsWait    = 0x7c
+	sdsCancel  = 0x7d
+	sdsNAK     = 0x7e
+	sdsACK     = 0x7f
+)
+
+// Sample loop types.
+const (
+	LoopForward     = 0x00
+	LoopAlternating = 0x01
+	LoopOff         = 0x7f
+)
+
+// ErrCancelled is returned when the other side of a transfer cancels it.
+var ErrCancelled = errors.New("rtmidi: transfer cancelled by device")
+
+// Sample is a sound sample as transferred by the MIDI Sample Dump Standard.
+type Sample struct {
+	// Number is the sample slot on the device.
+	Number int
+	// Bits is the sample resolution, from 8 to 28.
+	Bits int
+	// Period is the sample period in nanoseconds.
+	Period int
+	// LoopStart and LoopEnd are word offsets of the sustain loop.
+	LoopStart, LoopEnd int
+	// LoopType is LoopForward, LoopAlternating or LoopOff.
+	LoopType int
+	// Data holds the signed sample words.
+	Data []int32
+}
+
+// Rate returns the sample rate in Hz.
+func (s *Sample) Rate() float64 {
+	if s.Period == 0 {
+		return 0
+	}
+	return 1e9 / float64(s.Period)
+}
+
+func (s *Sample) bytesPerWord() int {
+	return (s.Bits + 6) / 7
+}
+
+func put21(b []byte, v int) {
+	b[0], b[1], b[2] = byte(v&0x7f), byte(v>>7&0x7f), byte(v>>14&0x7f)
+}
+
+func get21(b []byte) int {
+	return int(b[0]&0x7f) | int(b[1]&0x7f)<<7 | int(b[2]&0x7f)<<14
+}
+
+func sdsHeaderMessage(ch int, s *Sample) []byte {
+	msg := make([]byte, 21)
+	msg[0], msg[1], msg[2], msg[3] = 0xf0, 0x7e, byte(ch&0x7f), sdsHeader
+	msg[4], msg[5] = byte(s.Number&0x7f), byte(s.Number>>7&0x7f)
+	msg[6] = byte(s.Bits)
+	put21(msg[7:], s.Period)
+	put21(msg[10:], len(s.Data))
+	put21(msg[13:], s.LoopStart)
+	put21(msg[16:], s.LoopEnd)
+	msg[19] = byte(s.LoopType & 0x7f)
+	msg[20] = 0xf7
+	return msg
+}
+
+func parseSDSHeader(msg []byte) (*Sample, int, error) {
+	if len(msg) != 21 || msg[1] != 0x7e || msg[3] != sdsHeader {
+		return nil, 0, errors.New("rtmidi: not a sample dump header")
+	}
+	s := &Sample{
+		Number:    int(msg[4]) | int(msg[5])<<7,
+		Bits:      int(msg[6]),
+		Period:    get21(msg[7:]),
+		LoopStart: get21(msg[13:]),
+		LoopEnd:   get21(msg[16:]),
+		LoopType:  int(msg[19]),
+	}
+	if s.Bits < 8 || s.Bits > 28 {
+		return nil, 0, fmt.Errorf("rtmidi: unsupported sample format %d bits", s.Bits)
+	}
+	return s, get21(msg[10:]), nil
+}
+
+func sdsPacketMessage(ch int, n int, data []byte) []byte {
+	msg := make([]byte, 127)
+	msg[0], msg[1], msg[2], msg[3], msg[4] = 0xf0, 0x7e, byte(ch&0x7f), sdsPacket, byte(n&0x7f)
+	copy(msg[5:125], data)
+	var sum byte
+	for _, b := range msg[1:125] {
+		sum ^= b
+	}
+	msg[125], msg[126] = sum&0x7f, 0xf7
+	return msg
+}
+
+func sdsHandshake(ch int, typ byte, n int) []byte {
+	return []byte{0xf0, 0x7e, byte(ch & 0x7f), typ, byte(n & 0x7f), 0xf7}
+}
+
+func encodeSDSWords(s *Sample) []byte {
+	n := s.bytesPerWord()
+	shift := uint(7*n - s.Bits)
+	off := int64(1) << uint(s.Bits-1)
+	out := make([]byte, 0, len(s.Data)*n)
+	for _, v := range s.Data {
+		u := uint64(int64(v)+off) << shift
+		for i := n - 1; i >= 0; i-- {
+			out = append(out, byte(u>>uint(7*i)&0x7f))
+		}
+	}
+	return out
+}
+
+func decodeSDSWords(s *Sample, data []byte, words int) {
+	n := s.bytesPerWord()
+	shift := uint(7*n - s.Bits)
+	off := int64(1) << uint(s.Bits-1)
+	for i := 0; i+n <= len(data) && len(s.Data) < words; i += n {
+		var u uint64
+		for _, b := range data[i : i+n] {
+			u = u<<7 | uint64(b&0x7f)
+		}
+		s.Data = append(s.Data, int32(int64(u>>shift)-off))
+	}
+}
+
+// SampleDump transfers samples with a device using the MIDI Sample Dump
+// Standard over a pair of ports connected to it. Handshaking is used when the
+// device answers, falling back to open loop transfer otherwise. While a
+// transfer runs it takes over the callback of the input.
+type SampleDump struct {
+	// Channel is the SysEx channel (device ID) of the device.
+	Channel int
+	// Progress, if set, is called with the number of words transferred so
+	// far and the total.
+	Progress func(done, total int)
+	// OpenLoop disables handshaking; packets are sent PacketDelay apart.
+	OpenLoop bool
+	// PacketDelay is the gap between packets in open loop mode. Zero means
+	// 20ms as recommended by the standard.
+	PacketDelay time.Duration
+
+	in  MIDIIn
+	out MIDIOut
+}
+
+// NewSampleDump returns a SampleDump talking to device ch through in and out.
+func NewSampleDump(in MIDIIn, out MIDIOut, ch int) *SampleDump {
+	return &SampleDump{Channel: ch, in: in, out: out}
+}
+
+func (d *SampleDump) packetDelay() time.Duration {
+	if d.PacketDelay > 0 {
+		return d.PacketDelay
+	}
+	return 20 * time.Millisecond
+}
+
+func (d *SampleDump) progress(done, total int) {
+	if d.Progress != nil {
+		d.Progress(done, total)
+	}
+}
+
+// reply waits for the next handshake message from the device. It returns
+// zero if the device did not answer within timeout.
+func (d *SampleDump) reply(ctx context.Context, r *sysexReader, timeout time.Duration) (byte, error) {
+	for {
+		msg, err := r.next(ctx, timeout)
+		if err == ErrTimeout {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if len(msg) < 6 || msg[1] != 0x7e || int(msg[2]) != d.Channel&0x7f {
+			continue
+		}
+		switch msg[3] {
+		case sdsWait:
+			timeout = -1
+			continue
+		case sdsACK, sdsNAK, sdsCancel:
+			return msg[3], nil
+		}
+	}
+}
+
+func (d *SampleDump) cancel(err error) error {
+	d.out.SendMessage(sdsHandshake(d.Channel, sdsCancel, 0))
+	return err
+}
+
+// Send transfers s to the device.
+func (d *SampleDump) Send(ctx context.Context, s *Sample) error {
+	if s.Bits < 8 || s.Bits > 28 {
+		return fmt.Errorf("rtmidi: unsupported sample format %d bits", s.Bits)
+	}
+	r, err := newSysexReader(d.in, nil)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := d.out.SendMessage(sdsHeaderMessage(d.Channel, s)); err != nil {
+		return err
+	}
+	handshake := !d.OpenLoop
+	if handshake {
+		typ, err := d.reply(ctx, r, 2*time.Second)
+		if err != nil {
+			return d.cancel(err)
+		}
+		switch typ {
+		case sdsCancel:
+			return ErrCancelled
+		case 0:
+			handshake = false
+		}
+	}
+	data := encodeSDSWords(s)
+	perPacket := 120 / s.bytesPerWord()
+	chunk := perPacket * s.bytesPerWord()
+	for n, i := 0, 0; i < len(data); {
+		end := i + chunk
+		if end > len(data) {
+			end = len(data)
+		}
+		if err := d.out.SendMessage(sdsPacketMessage(d.Channel, n, data[i:end])); err != nil {
+			return err
+		}
+		if !handshake {
+			select {
+			case <-time.After(d.packetDelay()):
+			case <-ctx.Done():
+				return d.cancel(ctx.Err())
+			}
+		} else {
+			typ, err := d.reply(ctx, r, d.packetDelay())
+			if err != nil {
+				return d.cancel(err)
+			}
+			switch typ {
+			case sdsNAK:
+				continue
+			case sdsCancel:
+				return ErrCancelled
+			}
+		}
+		i, n = end, n+1
+		done := i / s.bytesPerWord()
+		d.progress(done, len(s.Data))
+	}
+	return nil
+}
+
+// Receive requests sample number from the device and returns it. A packet
+// sent again, as when the device missed an ACK, is acknowledged and
+// ignored; a missing packet cancels the dump.
+func (d *SampleDump) Receive(ctx context.Context, number int) (*Sample, error) {
+	r, err := newSysexReader(d.in, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	req := []byte{0xf0, 0x7e, byte(d.Channel & 0x7f), sdsRequest, byte(number & 0x7f), byte(number >> 7 & 0x7f), 0xf7}
+	if err := d.out.SendMessage(req); err != nil {
+		return nil, err
+	}
+	var s *Sample
+	var words int
+	for s == nil {
+		msg, err := r.next(ctx, 2*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		if len(msg) > 3 && msg[1] == 0x7e && int(msg[2]) == d.Channel&0x7f && msg[3] == sdsHeader {
+			if s, words, err = parseSDSHeader(msg); err != nil {
+				return nil, d.cancel(err)
+			}
+		}
+	}
+	if !d.OpenLoop {
+		d.out.SendMessage(sdsHandshake(d.Channel, sdsACK, 0))
+	}
+	// next is the number of the packet expected, counting modulo 128.
+	next, received := 0, false
+	for len(s.Data) < words {
+		msg, err := r.next(ctx, 2*time.Second)
+		if err != nil {
+			return nil, d.cancel(err)
+		}
+		if len(msg) != 127 || msg[1] != 0x7e || int(msg[2]) != d.Channel&0x7f || msg[3] != sdsPacket {
+			if len(msg) > 3 && msg[3] == sdsCancel {
+				return nil, ErrCancelled
+			}
+			continue
+		}
+		var sum byte
+		for _, b := range msg[1:125] {
+			sum ^= b
+		}
+		if sum&0x7f != msg[125] {
+			if !d.OpenLoop {
+				d.out.SendMessage(sdsHandshake(d.Channel, sdsNAK, int(msg[4])))
+			}
+			continue
+		}
+		switch n := int(msg[4]); {
+		case received && n == (next-1)&0x7f:
+			if !d.OpenLoop {
+				d.out.SendMessage(sdsHandshake(d.Channel, sdsACK, n))
+			}
+			continue
+		case n != next:
+			return nil, d.cancel(fmt.Errorf("rtmidi: sample dump packet %d received, want %d", n, next))
+		}
+		next, received = (next+1)&0x7f, true
+		decodeSDSWords(s, msg[5:125], words)
+		if !d.OpenLoop {
+			d.out.SendMessage(sdsHandshake(d.Channel, sdsACK, int(msg[4])))
+		}
+		d.progress(len(s.Data), words)
+	}
+	return s, nil
+}
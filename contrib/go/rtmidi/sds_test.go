@@ -0,0 +1,141 @@
+package rtmidi
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSDSWords(t *testing.T) {
+	s := &Sample{Bits: 16, Data: []int32{-32768, 0, 32767, -1}}
+	data := encodeSDSWords(s)
+	if len(data) != 12 {
+		t.Fatalf("encoded %d bytes", len(data))
+	}
+	if !reflect.DeepEqual(data[3:6], []byte{0x40, 0, 0}) {
+		t.Errorf("zero encoded as % x", data[3:6])
+	}
+	got := &Sample{Bits: 16}
+	decodeSDSWords(got, data, 4)
+	if !reflect.DeepEqual(got.Data, s.Data) {
+		t.Errorf("decoded %v", got.Data)
+	}
+}
+
+func testSample(n int) *Sample {
+	s := &Sample{Number: 3, Bits: 12, Period: 22676, LoopStart: 10, LoopEnd: 90, LoopType: LoopForward}
+	for i := 0; i < n; i++ {
+		s.Data = append(s.Data, int32(i*37%4096-2048))
+	}
+	return s
+}
+
+func TestSampleDumpSend(t *testing.T) {
+	in, out := &fakeIn{}, &fakeOut{}
+	var packets int
+	out.onSend = func(msg []byte) {
+		switch msg[3] {
+		case sdsHeader:
+			go in.deliver(sdsHandshake(0, sdsACK, 0))
+		case sdsPacket:
+			packets++
+			typ := byte(sdsACK)
+			if packets == 2 {
+				typ = sdsNAK
+			}
+			go in.deliver(sdsHandshake(0, typ, int(msg[4])))
+		}
+	}
+	var done, total int
+	d := NewSampleDump(in, out, 0)
+	d.Progress = func(n, t int) { done, total = n, t }
+	s := testSample(150)
+	if err := d.Send(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+	// 12-bit words take two bytes, so 60 words fit in a packet; one resend.
+	if packets != 4 || done != 150 || total != 150 {
+		t.Errorf("packets %d, progress %d/%d", packets, done, total)
+	}
+}
+
+func TestSampleDumpReceive(t *testing.T) {
+	in, out := &fakeIn{}, &fakeOut{}
+	s := testSample(100)
+	out.onSend = func(msg []byte) {
+		if msg[3] != sdsRequest {
+			return
+		}
+		go func() {
+			in.deliver(sdsHeaderMessage(0, s))
+			data := encodeSDSWords(s)
+			for n := 0; n*120 < len(data); n++ {
+				end := (n + 1) * 120
+				if end > len(data) {
+					end = len(data)
+				}
+				in.deliver(sdsPacketMessage(0, n, data[n*120:end]))
+			}
+		}()
+	}
+	d := NewSampleDump(in, out, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	got, err := d.Receive(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, s) {
+		t.Errorf("received %+v", got)
+	}
+}
+
+func TestSampleDumpCancel(t *testing.T) {
+	in, out := &fakeIn{}, &fakeOut{}
+	out.onSend = func(msg []byte) {
+		if msg[3] == sdsHeader {
+			go in.deliver(sdsHandshake(0, sdsCancel, 0))
+		}
+	}
+	if err := NewSampleDump(in, out, 0).Send(context.Background(), testSample(10)); err != ErrCancelled {
+		t.Errorf("Send returned %v", err)
+	}
+}
+
+func TestSampleDumpReceivePacketNumbers(t *testing.T) {
+	for name, packets := range map[string][]int{
+		"duplicate": {0, 0, 1, 1, 2},
+		"gap":       {0, 2},
+	} {
+		in, out := &fakeIn{}, &fakeOut{}
+		s := testSample(150)
+		data := encodeSDSWords(s)
+		out.onSend = func(msg []byte) {
+			if msg[3] != sdsRequest {
+				return
+			}
+			go func() {
+				in.deliver(sdsHeaderMessage(0, s))
+				for _, n := range packets {
+					end := (n + 1) * 120
+					if end > len(data) {
+						end = len(data)
+					}
+					in.deliver(sdsPacketMessage(0, n, data[n*120:end]))
+				}
+			}()
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		got, err := NewSampleDump(in, out, 0).Receive(ctx, 3)
+		cancel()
+		switch {
+		case name == "duplicate" && err != nil:
+			t.Errorf("%s: %v", name, err)
+		case name == "duplicate" && !reflect.DeepEqual(got, s):
+			t.Errorf("%s: received %+v", name, got)
+		case name == "gap" && err == nil:
+			t.Errorf("%s: missing packet not detected", name)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package rtmidi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// nativeSendFunc queues msg to be sent by the backend after delay. It
+// reports false if the backend has no scheduler.
+type nativeSendFunc func(msg []byte, delay time.Duration) (bool, error)
+
+// timedSender implements SendMessageAt, using the scheduler of the
+// backend where there is one and a Scheduler otherwise.
+type timedSender struct {
+	mu     sync.Mutex
+	sched  *Scheduler
+	last   time.Time
+	detach func()
+	clock  Clock
+}
+
+// setClock sets the clock the times of messages are on.
+func (t *timedSender) setClock(c Clock) {
+	t.mu.Lock()
+	t.clock = c
+	sched := t.sched
+	t.mu.Unlock()
+	if sched != nil {
+		sched.SetClock(c)
+	}
+}
+
+// send sends msg to out at the given time. native may be nil.
+func (t *timedSender) send(out MIDIOut, msg []byte, at time.Time, native nativeSendFunc) error {
+	t.mu.Lock()
+	clock := clockOr(t.clock)
+	t.mu.Unlock()
+	delay := at.Sub(clock.Now())
+	if delay <= 0 {
+		return out.SendMessage(msg)
+	}
+	if native != nil {
+		ok, err := native(msg, delay)
+		if err != nil {
+			return err
+		}
+		if ok {
+			t.mu.Lock()
+			if t.detach == nil {
+				t.detach = attachQueue(out, t)
+			}
+			if at.After(t.last) {
+				t.last = at
+			}
+			t.mu.Unlock()
+			return nil
+		}
+	}
+	t.mu.Lock()
+	if t.sched == nil {
+		t.sched = NewScheduler(out)
+		t.sched.SetClock(clock)
+	}
+	sched := t.sched
+	t.mu.Unlock()
+	return sched.Schedule(at, msg)
+}
+
+// Flush waits until the time of the last message handed to the backend has
+// passed or ctx is done. Messages left to the Scheduler are waited for by
+// the Scheduler itself.
+func (t *timedSender) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	clock := clockOr(t.clock)
+	d := t.last.Sub(clock.Now())
+	t.mu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+	return sleepCtx(ctx, clock, d)
+}
+
+// close drops the messages still waiting to be sent.
+func (t *timedSender) close() {
+	t.mu.Lock()
+	sched, detach := t.sched, t.detach
+	t.sched, t.detach, t.last = nil, nil, time.Time{}
+	t.mu.Unlock()
+	if sched != nil {
+		sched.Close()
+	}
+	if detach != nil {
+		detach()
+	}
+}
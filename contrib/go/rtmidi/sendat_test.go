@@ -0,0 +1,68 @@
+package rtmidi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimedSenderFallback(t *testing.T) {
+	out := &fakeOut{}
+	var ts timedSender
+	defer ts.close()
+	start := time.Now()
+	if err := ts.send(out, []byte{0x90, 60, 100}, start.Add(20*time.Millisecond), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.send(out, []byte{0x80, 60, 0}, start.Add(-time.Second), nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(out.messages()); n != 1 {
+		t.Fatalf("%d messages sent before the scheduled time, want 1", n)
+	}
+	if err := out.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Error("scheduled message sent early")
+	}
+	if n := len(out.messages()); n != 2 {
+		t.Errorf("%d messages sent, want 2", n)
+	}
+}
+
+func TestTimedSenderNative(t *testing.T) {
+	out := &fakeOut{}
+	var ts timedSender
+	defer ts.close()
+	var delays []time.Duration
+	native := func(msg []byte, delay time.Duration) (bool, error) {
+		delays = append(delays, delay)
+		return true, nil
+	}
+	start := time.Now()
+	if err := ts.send(out, []byte{0x90, 60, 100}, start.Add(30*time.Millisecond), native); err != nil {
+		t.Fatal(err)
+	}
+	if len(delays) != 1 || delays[0] <= 0 || delays[0] > 30*time.Millisecond {
+		t.Errorf("native send delays %v", delays)
+	}
+	if len(out.messages()) != 0 || ts.sched != nil {
+		t.Error("natively scheduled message sent by Go")
+	}
+	if err := out.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 30*time.Millisecond {
+		t.Error("Flush returned before the natively scheduled time")
+	}
+
+	fail := errors.New("queue full")
+	err := ts.send(out, []byte{0x90, 60, 100}, time.Now().Add(time.Second), func([]byte, time.Duration) (bool, error) {
+		return false, fail
+	})
+	if err != fail {
+		t.Errorf("send returned %v, want %v", err, fail)
+	}
+}
@@ -0,0 +1,161 @@
+package rtmidi
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Step is one step of a sequencer pattern. A step with zero velocity is a
+// rest.
+type Step struct {
+	Note     int
+	Velocity int
+	// Gate is the length of the note as a fraction of the step length.
+	// Zero means 0.5.
+	Gate float64
+}
+
+// Pattern is a sequence of steps of equal length.
+type Pattern struct {
+	Steps []Step
+	// StepLength is the length of each step; zero means GridSixteenth.
+	StepLength Grid
+}
+
+func (p *Pattern) stepPulses() int {
+	l := p.StepLength
+	if l <= 0 {
+		l = GridSixteenth
+	}
+	n := int(math.Round(float64(l) * ClocksPerQuarter))
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// Sequencer is a step sequencer driven by the pulses of a PulseSource. It
+// plays a chain of patterns in a loop, sending notes through a Scheduler so
+// that swing and gate lengths are not limited to clock pulse resolution.
+type Sequencer struct {
+	// Channel is the MIDI channel (0-15) notes are sent on.
+	Channel int
+	// Swing delays every second step by this fraction of a step.
+	Swing float64
+
+	sched  *Scheduler
+	mu     sync.Mutex
+	src    PulseSource
+	cancel func()
+	chain  []*Pattern
+	pat    int
+	step   int
+	pos    int
+	next   []*Pattern
+}
+
+// NewSequencer returns a Sequencer sending through sched.
+func NewSequencer(sched *Scheduler) *Sequencer {
+	return &Sequencer{sched: sched}
+}
+
+// Chain sets the patterns to play in a loop. If the sequencer is playing, the
+// new chain starts once the current pattern has finished.
+func (s *Sequencer) Chain(patterns ...*Pattern) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.chain) == 0 {
+		s.chain = patterns
+		s.pat, s.step, s.pos = 0, 0, 0
+		return
+	}
+	s.next = patterns
+}
+
+// Position returns the index in the chain of the pattern playing and the
+// index of its next step.
+func (s *Sequencer) Position() (pattern, step int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pat, s.step
+}
+
+// Attach makes the sequencer follow src, detaching it from any previous
+// source.
+func (s *Sequencer) Attach(src PulseSource) {
+	s.Detach()
+	cancel := src.OnPulse(s.Pulse)
+	s.mu.Lock()
+	s.src, s.cancel = src, cancel
+	s.mu.Unlock()
+}
+
+// Detach stops following the current source.
+func (s *Sequencer) Detach() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.src, s.cancel = nil, nil
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// Pulse advances the sequencer by one clock pulse. Pulse 0, the start of the
+// song, rewinds to the first step of the chain.
+func (s *Sequencer) Pulse(pulse int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pulse == 0 {
+		if s.next != nil {
+			s.chain, s.next = s.next, nil
+		}
+		s.pat, s.step, s.pos = 0, 0, 0
+	}
+	if len(s.chain) == 0 {
+		return
+	}
+	p := s.chain[s.pat]
+	n := p.stepPulses()
+	if s.pos%n == 0 {
+		if s.step < len(p.Steps) {
+			s.play(p, p.Steps[s.step], n)
+		}
+		s.step++
+	}
+	s.pos++
+	if s.pos >= n*len(p.Steps) {
+		s.pat, s.step, s.pos = s.pat+1, 0, 0
+		if s.pat >= len(s.chain) {
+			s.pat = 0
+		}
+		if s.next != nil {
+			s.chain, s.next, s.pat = s.next, nil, 0
+		}
+	}
+}
+
+func (s *Sequencer) play(p *Pattern, st Step, pulses int) {
+	if st.Velocity <= 0 {
+		return
+	}
+	bpm := DefaultBPM
+	if s.src != nil {
+		if t := s.src.Tempo(); t > 0 {
+			bpm = t
+		}
+	}
+	stepDur := time.Duration(float64(pulses) * float64(time.Minute) / (bpm * ClocksPerQuarter))
+	at := s.sched.now()
+	if s.step%2 == 1 {
+		at = at.Add(time.Duration(s.Swing * float64(stepDur)))
+	}
+	gate := st.Gate
+	if gate <= 0 {
+		gate = 0.5
+	}
+	ch := byte(s.Channel & 0x0f)
+	s.sched.Schedule(at, []byte{0x90 | ch, byte(st.Note & 0x7f), byte(st.Velocity & 0x7f)})
+	s.sched.Schedule(at.Add(time.Duration(gate*float64(stepDur))), []byte{0x80 | ch, byte(st.Note & 0x7f), 0})
+}
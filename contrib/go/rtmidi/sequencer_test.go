@@ -0,0 +1,46 @@
+package rtmidi
+
+import (
+	"testing"
+	"time"
+)
+
+type fakePulses struct {
+	pulseHub
+	bpm float64
+}
+
+func (f *fakePulses) Tempo() float64 { return f.bpm }
+
+func TestSequencer(t *testing.T) {
+	out := &fakeOut{}
+	sched := NewScheduler(out)
+	defer sched.Close()
+	seq := NewSequencer(sched)
+	a := &Pattern{Steps: []Step{{Note: 60, Velocity: 100}, {}}, StepLength: GridEighth}
+	b := &Pattern{Steps: []Step{{Note: 64, Velocity: 90, Gate: 0.1}}, StepLength: GridQuarter}
+	seq.Chain(a, b)
+	src := &fakePulses{bpm: 6000}
+	seq.Attach(src)
+	// Two eighths of a, a quarter of b, then a again.
+	for p := 0; p < 24+24+1; p++ {
+		src.emit(p)
+	}
+	seq.Detach()
+	deadline := time.Now().Add(time.Second)
+	for len(out.messages()) < 6 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	var notes []int
+	for _, msg := range out.messages() {
+		if msg[0] == 0x90 {
+			notes = append(notes, int(msg[1]))
+		}
+	}
+	if len(notes) != 3 || notes[0] != 60 || notes[1] != 64 || notes[2] != 60 {
+		t.Errorf("played %v, want [60 64 60]", notes)
+	}
+	if pat, step := seq.Position(); pat != 0 || step != 1 {
+		t.Errorf("Position() = %d, %d", pat, step)
+	}
+}
@@ -0,0 +1,424 @@
+package rtmidi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	sharedMu sync.Mutex
+	shared   = map[string]*sharedPort{}
+)
+
+// sharedPort is a port opened for real and the handles attached to it.
+type sharedPort struct {
+	key  string
+	port destroyer
+
+	mu      sync.Mutex
+	ins     []*sharedIn
+	outs    int
+	sending sync.Mutex
+}
+
+// OpenSharedIn opens the input described by spec, or attaches to it if it
+// is already open within the process.
+//
+// Ports of the Windows multimedia API can only be opened once, even within
+// a process, so two parts of a program cannot both open the same device.
+// Opening it with OpenSharedIn or OpenSharedOut works around this: the
+// first open opens the port, and later ones attach to it. Every handle on a
+// shared input receives all of its messages, and the messages sent through
+// the handles on a shared output are merged. The port is closed when its
+// last handle is closed. Sharing works with every API, and virtual ports
+// cannot be shared.
+func OpenSharedIn(spec PortSpec) (MIDIIn, error) {
+	spec.Output = false
+	var h *sharedIn
+	_, err := openShared(spec, func(p *sharedPort) {
+		// As on a plain input, SysEx, timing and active sensing are
+		// ignored until IgnoreTypes says otherwise.
+		h = &sharedIn{shared: p, ignored: [3]bool{true, true, true}}
+		p.ins = append(p.ins, h)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// OpenSharedOut opens the output described by spec, or attaches to it if
+// it is already open within the process, see OpenSharedIn.
+func OpenSharedOut(spec PortSpec) (MIDIOut, error) {
+	spec.Output = true
+	p, err := openShared(spec, func(p *sharedPort) { p.outs++ })
+	if err != nil {
+		return nil, err
+	}
+	return &sharedOut{shared: p}, nil
+}
+
+// openShared returns the shared port for spec, opening it if needed, after
+// calling attach with it locked. The port is identified by the API it was
+// created with, which APIUnspecified resolves to, and by its name, as
+// indexes of different clients need not agree.
+func openShared(spec PortSpec, attach func(p *sharedPort)) (*sharedPort, error) {
+	if spec.Virtual {
+		return nil, errors.New("rtmidi: virtual ports cannot be shared")
+	}
+	m, err := createSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	var name string
+	var api API
+	index, err := spec.portIndex(m)
+	if err == nil {
+		name, err = m.PortName(index)
+	}
+	if err == nil {
+		api, err = m.(interface{ API() (API, error) }).API()
+	}
+	if err != nil {
+		m.Destroy()
+		return nil, err
+	}
+	key := fmt.Sprintf("%v %v %s", api, spec.Output, name)
+
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	p, ok := shared[key]
+	if ok {
+		m.Destroy()
+	} else {
+		if err := m.OpenPort(index, spec.Name); err != nil {
+			m.Destroy()
+			return nil, err
+		}
+		p = &sharedPort{key: key, port: m}
+		if in, ok := m.(MIDIIn); ok {
+			if err := in.SetCallback(p.dispatch); err != nil {
+				m.Close()
+				return nil, err
+			}
+		}
+		shared[key] = p
+	}
+	p.mu.Lock()
+	attach(p)
+	p.mu.Unlock()
+	return p, nil
+}
+
+// release detaches a handle, closing the port after the last one. The port
+// is forgotten only once it is closed, so that it is not opened again while
+// it is still open.
+func (p *sharedPort) release() error {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	p.mu.Lock()
+	last := len(p.ins) == 0 && p.outs == 0
+	p.mu.Unlock()
+	if !last {
+		return nil
+	}
+	err := p.port.Close()
+	delete(shared, p.key)
+	return err
+}
+
+// dispatch passes a message of a shared input to every handle.
+func (p *sharedPort) dispatch(_ MIDIIn, msg []byte, t float64) {
+	p.mu.Lock()
+	ins := p.ins
+	p.mu.Unlock()
+	for _, h := range ins {
+		h.receive(append([]byte(nil), msg...), t)
+	}
+}
+
+// ignore sets the message types ignored by the port to those every handle
+// ignores; handles filter out the rest themselves.
+func (p *sharedPort) ignore() error {
+	p.mu.Lock()
+	sysex, timing, sense := true, true, true
+	for _, h := range p.ins {
+		h.mu.Lock()
+		sysex = sysex && h.ignored[0]
+		timing = timing && h.ignored[1]
+		sense = sense && h.ignored[2]
+		h.mu.Unlock()
+	}
+	p.mu.Unlock()
+	return p.port.(MIDIIn).IgnoreTypes(sysex, timing, sense)
+}
+
+// sharedIn is a handle on a shared input.
+type sharedIn struct {
+	shared *sharedPort
+
+	mu      sync.Mutex
+	cb      func(MIDIIn, []byte, float64)
+	disp    *dispatcher
+	rt      Realtime
+	lost    uint64
+	queue   [][]byte
+	times   []float64
+	ignored [3]bool
+	closed  bool
+}
+
+func (h *sharedIn) OpenPort(port int, name string) error {
+	return errors.New("rtmidi: shared port is already open")
+}
+
+func (h *sharedIn) OpenVirtualPort(name string) error {
+	return errors.New("rtmidi: shared port is already open")
+}
+
+func (h *sharedIn) ConnectTo(portPattern string) error {
+	return errors.New("rtmidi: shared port is not virtual")
+}
+
+func (h *sharedIn) PortCount() (int, error)           { return h.shared.port.PortCount() }
+func (h *sharedIn) PortName(port int) (string, error) { return h.shared.port.PortName(port) }
+func (h *sharedIn) API() (API, error)                 { return h.shared.port.(MIDIIn).API() }
+
+// IgnoreTypes sets the types of message this handle ignores, which may
+// differ from those of other handles.
+func (h *sharedIn) IgnoreTypes(midiSysex bool, midiTime bool, midiSense bool) error {
+	h.mu.Lock()
+	h.ignored = [3]bool{midiSysex, midiTime, midiSense}
+	h.mu.Unlock()
+	return h.shared.ignore()
+}
+
+func (h *sharedIn) SetCallback(cb func(MIDIIn, []byte, float64)) error {
+	h.mu.Lock()
+	disp := h.disp
+	h.cb, h.disp = cb, nil
+	h.mu.Unlock()
+	h.stop(disp)
+	return nil
+}
+
+func (h *sharedIn) SetBufferedCallback(size int, cb func(MIDIIn, []byte, float64)) error {
+	d := newDispatcher(size, func(msg []byte, ts float64) { cb(h, msg, ts) })
+	h.mu.Lock()
+	old, rt := h.disp, h.rt
+	h.cb, h.disp = cb, d
+	h.mu.Unlock()
+	if rt != (Realtime{}) {
+		d.setRealtime(rt)
+	}
+	h.stop(old)
+	return nil
+}
+
+// stop stops a dispatcher replaced by the handle, keeping its count of
+// dropped messages.
+func (h *sharedIn) stop(disp *dispatcher) {
+	if disp == nil {
+		return
+	}
+	disp.stop()
+	h.mu.Lock()
+	h.lost += disp.dropped()
+	h.mu.Unlock()
+}
+
+func (h *sharedIn) dropped() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := h.lost
+	if h.disp != nil {
+		n += h.disp.dropped()
+	}
+	return n
+}
+
+func (h *sharedIn) setRealtime(rt Realtime) error {
+	h.mu.Lock()
+	h.rt = rt
+	disp := h.disp
+	h.mu.Unlock()
+	if disp == nil {
+		return nil
+	}
+	return disp.setRealtime(rt)
+}
+
+func (h *sharedIn) CancelCallback() error {
+	return h.SetCallback(nil)
+}
+
+func (h *sharedIn) Drain() error {
+	h.mu.Lock()
+	disp := h.disp
+	h.mu.Unlock()
+	if disp != nil {
+		disp.flush()
+	}
+	return nil
+}
+
+func (h *sharedIn) receive(msg []byte, t float64) {
+	h.mu.Lock()
+	if h.closed || len(msg) == 0 ||
+		h.ignored[0] && msg[0] == 0xf0 ||
+		h.ignored[1] && (msg[0] == 0xf1 || msg[0] == 0xf8) ||
+		h.ignored[2] && msg[0] == 0xfe {
+		h.mu.Unlock()
+		return
+	}
+	cb, disp := h.cb, h.disp
+	if cb == nil && len(h.queue) < DefaultQueueSize {
+		h.queue = append(h.queue, msg)
+		h.times = append(h.times, t)
+	}
+	h.mu.Unlock()
+	switch {
+	case disp != nil:
+		disp.push(msg, t)
+	case cb != nil:
+		cb(h, msg, t)
+	}
+}
+
+func (h *sharedIn) Message() ([]byte, float64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.queue) == 0 {
+		return []byte{}, 0, nil
+	}
+	msg, t := h.queue[0], h.times[0]
+	h.queue, h.times = h.queue[1:], h.times[1:]
+	return msg, t, nil
+}
+
+// Close detaches the handle, closing the port if it was the last one.
+func (h *sharedIn) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	disp := h.disp
+	h.disp = nil
+	h.mu.Unlock()
+	h.stop(disp)
+	p := h.shared
+	p.mu.Lock()
+	for i, x := range p.ins {
+		if x == h {
+			p.ins = append(p.ins[:i:i], p.ins[i+1:]...)
+			break
+		}
+	}
+	p.mu.Unlock()
+	return p.release()
+}
+
+func (h *sharedIn) Destroy() {
+	h.Close()
+}
+
+// sharedOut is a handle on a shared output.
+type sharedOut struct {
+	shared *sharedPort
+	notes  noteSet
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (h *sharedOut) out() MIDIOut {
+	return h.shared.port.(MIDIOut)
+}
+
+func (h *sharedOut) OpenPort(port int, name string) error {
+	return errors.New("rtmidi: shared port is already open")
+}
+
+func (h *sharedOut) OpenVirtualPort(name string) error {
+	return errors.New("rtmidi: shared port is already open")
+}
+
+func (h *sharedOut) ConnectTo(portPattern string) error {
+	return errors.New("rtmidi: shared port is not virtual")
+}
+
+func (h *sharedOut) PortCount() (int, error)           { return h.shared.port.PortCount() }
+func (h *sharedOut) PortName(port int) (string, error) { return h.shared.port.PortName(port) }
+func (h *sharedOut) API() (API, error)                 { return h.out().API() }
+
+var errSharedClosed = errors.New("rtmidi: shared port handle is closed")
+
+// SendMessage sends msg. Messages from different handles are merged whole,
+// one at a time.
+func (h *sharedOut) SendMessage(msg []byte) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return errSharedClosed
+	}
+	h.shared.sending.Lock()
+	defer h.shared.sending.Unlock()
+	return h.out().SendMessage(msg)
+}
+
+func (h *sharedOut) SendMessageAt(msg []byte, at time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return errSharedClosed
+	}
+	h.shared.sending.Lock()
+	defer h.shared.sending.Unlock()
+	return SendMessageAt(h.out(), msg, at)
+}
+
+// PlayNote plays a note belonging to this handle: closing the handle ends
+// its notes only.
+func (h *sharedOut) PlayNote(ch, key, vel int, d time.Duration) (*Note, error) {
+	return h.notes.play(h, ch, key, vel, d)
+}
+
+// setClock sets the clock of the notes of the handle only, as the times
+// given to SendMessageAt are kept by the shared port.
+func (h *sharedOut) setClock(c Clock) {
+	h.notes.setClock(c)
+}
+
+func (h *sharedOut) Flush(ctx context.Context) error {
+	if err := h.notes.Flush(ctx); err != nil {
+		return err
+	}
+	return Flush(ctx, h.out())
+}
+
+// Close ends the notes of the handle and detaches it, closing the port if
+// it was the last one. The handle cannot send afterwards.
+func (h *sharedOut) Close() error {
+	h.notes.stopAll()
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	h.mu.Unlock()
+	p := h.shared
+	p.mu.Lock()
+	p.outs--
+	p.mu.Unlock()
+	return p.release()
+}
+
+func (h *sharedOut) Destroy() {
+	h.Close()
+}
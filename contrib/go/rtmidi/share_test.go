@@ -0,0 +1,148 @@
+package rtmidi
+
+import (
+	"reflect"
+	"testing"
+)
+
+// exclusiveIn is an input whose ports, like those of WinMM, can only be
+// opened once.
+type exclusiveIn struct {
+	fakeIn
+	opens  *int
+	closed bool
+}
+
+func (e *exclusiveIn) PortCount() (int, error)           { return 2, nil }
+func (e *exclusiveIn) PortName(port int) (string, error) { return []string{"Keys", "Pads"}[port], nil }
+func (e *exclusiveIn) OpenPort(port int, name string) error {
+	*e.opens++
+	return nil
+}
+func (e *exclusiveIn) Close() error { e.closed = true; return nil }
+
+func TestSharedIn(t *testing.T) {
+	defer func() { newMIDIIn = NewMIDIIn }()
+	opens := 0
+	var created []*exclusiveIn
+	newMIDIIn = func(api API, client string, size int) (MIDIIn, error) {
+		e := &exclusiveIn{opens: &opens}
+		created = append(created, e)
+		return e, nil
+	}
+
+	a, err := OpenSharedIn(PortSpec{Port: "keys"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := OpenSharedIn(PortSpec{Index: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opens != 1 {
+		t.Fatalf("port opened %d times", opens)
+	}
+
+	var gotA, gotB [][]byte
+	a.SetCallback(func(m MIDIIn, msg []byte, ts float64) { gotA = append(gotA, msg) })
+	b.SetCallback(func(m MIDIIn, msg []byte, ts float64) { gotB = append(gotB, msg) })
+	a.IgnoreTypes(false, false, false)
+	dev := created[0]
+	dev.deliver([]byte{0x90, 60, 100})
+	dev.deliver([]byte{0xf8})
+	if want := [][]byte{{0x90, 60, 100}, {0xf8}}; !reflect.DeepEqual(gotA, want) {
+		t.Errorf("first handle got % X", gotA)
+	}
+	if want := [][]byte{{0x90, 60, 100}}; !reflect.DeepEqual(gotB, want) {
+		t.Errorf("second handle got % X", gotB)
+	}
+
+	a.Close()
+	if dev.closed {
+		t.Fatal("port closed with a handle left")
+	}
+	dev.deliver([]byte{0x90, 61, 100})
+	if len(gotA) != 2 || len(gotB) != 2 {
+		t.Errorf("closed handle still receiving")
+	}
+	b.Close()
+	if !dev.closed {
+		t.Fatal("port left open")
+	}
+
+	// Once closed, the port is opened again.
+	c, err := OpenSharedIn(PortSpec{Index: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if opens != 2 {
+		t.Errorf("port opened %d times", opens)
+	}
+}
+
+// filterIn records the types of message it is set to ignore.
+type filterIn struct {
+	fakeIn
+	ignored [3]bool
+}
+
+func (f *filterIn) IgnoreTypes(midiSysex bool, midiTime bool, midiSense bool) error {
+	f.ignored = [3]bool{midiSysex, midiTime, midiSense}
+	return nil
+}
+
+func TestSharedInFilters(t *testing.T) {
+	defer func() { newMIDIIn = NewMIDIIn }()
+	dev := &filterIn{}
+	newMIDIIn = func(api API, client string, size int) (MIDIIn, error) { return dev, nil }
+	a, _ := OpenSharedIn(PortSpec{})
+	defer a.Close()
+	b, _ := OpenSharedIn(PortSpec{})
+	defer b.Close()
+
+	var gotA, gotB [][]byte
+	a.SetCallback(func(m MIDIIn, msg []byte, ts float64) { gotA = append(gotA, msg) })
+	b.SetCallback(func(m MIDIIn, msg []byte, ts float64) { gotB = append(gotB, msg) })
+	if err := b.IgnoreTypes(false, true, true); err != nil {
+		t.Fatal(err)
+	}
+	if want := [3]bool{false, true, true}; dev.ignored != want {
+		t.Errorf("port ignores %v, want %v", dev.ignored, want)
+	}
+	for _, msg := range [][]byte{{0xf0, 0x7e, 0xf7}, {0xf8}, {0xfe}, {0x90, 60, 100}} {
+		dev.deliver(msg)
+	}
+	if want := [][]byte{{0x90, 60, 100}}; !reflect.DeepEqual(gotA, want) {
+		t.Errorf("handle with the default filters got % X", gotA)
+	}
+	if want := [][]byte{{0xf0, 0x7e, 0xf7}, {0x90, 60, 100}}; !reflect.DeepEqual(gotB, want) {
+		t.Errorf("handle receiving SysEx got % X", gotB)
+	}
+}
+
+func TestSharedOut(t *testing.T) {
+	defer func() { newMIDIOut = NewMIDIOut }()
+	var created []*namedPorts
+	newMIDIOut = func(api API, name string) (MIDIOut, error) {
+		o := &namedPorts{names: []string{"Synth"}}
+		created = append(created, o)
+		return o, nil
+	}
+	// The API a port is created with resolves APIUnspecified.
+	a, _ := OpenSharedOut(PortSpec{})
+	b, _ := OpenSharedOut(PortSpec{API: APIDummy})
+	a.SendMessage([]byte{0xc0, 5})
+	PlayNote(b, 0, 60, 100, 0)
+	PlayNote(a, 0, 62, 100, 0)
+	b.Close()
+	if err := b.SendMessage([]byte{0xc0, 7}); err == nil {
+		t.Error("closed handle sent a message")
+	}
+	a.SendMessage([]byte{0xc0, 6})
+	want := [][]byte{{0xc0, 5}, {0x90, 60, 100}, {0x90, 62, 100}, {0x80, 60, 0}, {0xc0, 6}}
+	if got := created[0].messages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("sent % X, want % X", got, want)
+	}
+	a.Close()
+}
@@ -0,0 +1,53 @@
+package rtmidi
+
+import "unsafe"
+
+// smallMessageSize is the size of the largest MIDI message other than
+// SysEx: a status byte and two data bytes. Most messages, and all those
+// sent at clock rate, are this size or smaller.
+const smallMessageSize = 3
+
+// messageSlabSize is the size of the blocks a messageSlab cuts buffers
+// from.
+const messageSlabSize = 1024
+
+// messageSlab cuts the buffers of small received messages from larger
+// blocks, so that a stream of clock and note messages costs an allocation
+// per block rather than per message. Each buffer is capped at its length,
+// so appending to it never writes over the next one; a buffer kept by the
+// receiver keeps its block alive. A messageSlab is not safe for concurrent
+// use.
+type messageSlab struct {
+	block []byte
+}
+
+// take returns a buffer of n bytes, n at most smallMessageSize.
+func (s *messageSlab) take(n int) []byte {
+	if len(s.block) < n {
+		s.block = make([]byte, messageSlabSize)
+	}
+	b := s.block[:n:n]
+	s.block = s.block[n:]
+	return b
+}
+
+// copyFrom returns a copy of the n bytes of a received message at p, cut
+// from the slab if the message is small.
+func (s *messageSlab) copyFrom(p unsafe.Pointer, n int) []byte {
+	if n <= 0 || n > smallMessageSize {
+		return copyBytes(p, n)
+	}
+	b := s.take(n)
+	copy(b, unsafe.Slice((*byte)(p), n))
+	return b
+}
+
+// copyBytes returns a copy of the n bytes at p in a buffer of its own, as
+// C.GoBytes does.
+func copyBytes(p unsafe.Pointer, n int) []byte {
+	b := make([]byte, n)
+	if n > 0 {
+		copy(b, unsafe.Slice((*byte)(p), n))
+	}
+	return b
+}
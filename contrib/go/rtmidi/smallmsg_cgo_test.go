@@ -0,0 +1,74 @@
+//go:build cgo
+
+package rtmidi
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// benchmarkSend sends msg to a virtual output, which needs a backend with
+// virtual ports but no device.
+func benchmarkSend(b *testing.B, msg []byte) {
+	out, err := NewMIDIOut(APIUnspecified, "RtMidi Benchmark")
+	if err != nil {
+		b.Skip(err)
+	}
+	defer out.Close()
+	if err := out.OpenVirtualPort("RtMidi Benchmark"); err != nil {
+		b.Skip(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := out.SendMessage(msg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSendClock(b *testing.B) {
+	benchmarkSend(b, []byte{0xf8})
+}
+
+func BenchmarkSendNoteOn(b *testing.B) {
+	benchmarkSend(b, []byte{0x90, 60, 100})
+}
+
+func BenchmarkSendSysEx(b *testing.B) {
+	benchmarkSend(b, []byte{0xf0, 0x7e, 0x7f, 0x06, 0x01, 0xf7})
+}
+
+// BenchmarkReceiveCallback runs the body of the input callback of the
+// backend on a stream of clock messages, without the call from C.
+func BenchmarkReceiveCallback(b *testing.B) {
+	m := &midiIn{}
+	m.handler.Store(&inputHandler{cb: func(_ MIDIIn, msg []byte, _ float64) { received = msg }})
+	clock := []byte{0xf8}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m.receive(unsafe.Pointer(&clock[0]), len(clock), 0)
+	}
+}
+
+// BenchmarkSendMessage and BenchmarkSendCBytes prepare a clock message for
+// the backend as SendMessage does, and with the C copy every message took
+// before.
+
+func BenchmarkSendMessage(b *testing.B) {
+	b.ReportAllocs()
+	clock := []byte{0xf8}
+	for i := 0; i < b.N; i++ {
+		if p, owned := cMessage(clock); owned {
+			cFree(p)
+		}
+	}
+}
+
+func BenchmarkSendCBytes(b *testing.B) {
+	b.ReportAllocs()
+	clock := []byte{0xf8}
+	for i := 0; i < b.N; i++ {
+		cFree(cCopy(clock))
+	}
+}
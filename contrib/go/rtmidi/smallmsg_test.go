@@ -0,0 +1,59 @@
+package rtmidi
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestMessageSlab(t *testing.T) {
+	var s messageSlab
+	a := s.take(3)
+	copy(a, []byte{0x90, 60, 100})
+	b := s.take(1)
+	b[0] = 0xf8
+	a = append(a, 0)
+	if b[0] != 0xf8 || cap(b) != 1 {
+		t.Errorf("appending to a buffer overwrote the next: % x", b)
+	}
+	for i := 0; i < messageSlabSize; i++ {
+		if len(s.take(3)) != 3 {
+			t.Fatal("short buffer")
+		}
+	}
+}
+
+func TestCopyFrom(t *testing.T) {
+	var s messageSlab
+	for _, msg := range [][]byte{{0xf8}, {0x90, 60, 100}, {0xf0, 0x7e, 0x7f, 0x06, 0x01, 0xf7}} {
+		got := s.copyFrom(unsafe.Pointer(&msg[0]), len(msg))
+		if string(got) != string(msg) || &got[0] == &msg[0] {
+			t.Errorf("copyFrom(% x) = % x", msg, got)
+		}
+	}
+	if got := s.copyFrom(nil, 0); got == nil || len(got) != 0 {
+		t.Errorf("copyFrom of an empty message = %#v", got)
+	}
+}
+
+// The receive benchmarks copy a stream of clock messages with the copies
+// the input callback makes: copyBytes, which every message went through
+// before the slab and SysEx still does, and copyFrom.
+
+var received []byte
+
+func BenchmarkReceiveCopy(b *testing.B) {
+	b.ReportAllocs()
+	clock := []byte{0xf8}
+	for i := 0; i < b.N; i++ {
+		received = copyBytes(unsafe.Pointer(&clock[0]), len(clock))
+	}
+}
+
+func BenchmarkReceiveSlab(b *testing.B) {
+	b.ReportAllocs()
+	clock := []byte{0xf8}
+	var s messageSlab
+	for i := 0; i < b.N; i++ {
+		received = s.copyFrom(unsafe.Pointer(&clock[0]), len(clock))
+	}
+}
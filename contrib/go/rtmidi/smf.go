@@ -0,0 +1,305 @@
+package rtmidi
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// DefaultPPQN is the resolution used for Standard MIDI Files when none is
+// given.
+const DefaultPPQN = 480
+
+// WriteSMF writes tracks as a Standard MIDI File, converting event times to
+// ticks with tempo, whose changes are written as tempo meta events on the
+// first track. A single track is written as format 0, several tracks as
+// format 1. A nil tempo means DefaultPPQN at DefaultBPM.
+//
+// Channel messages, SysEx and meta events are written; system common and
+// realtime messages have no place in a file and are left out. A meta event
+// is given as a message of 0xFF followed by its type and data, as made by
+// TrackNameMeta; a lone 0xFF is a System Reset.
+func WriteSMF(w io.Writer, tempo *TempoMap, tracks ...Track) error {
+	if len(tracks) == 0 {
+		return errors.New("rtmidi: no tracks to write")
+	}
+	if tempo == nil {
+		tempo = NewTempoMap(DefaultPPQN, DefaultBPM)
+	}
+	if tempo.PPQN <= 0 || tempo.PPQN > 0x7fff {
+		return errors.New("rtmidi: invalid PPQN")
+	}
+	var chunks [][]byte
+	for i, t := range tracks {
+		chunks = append(chunks, encodeSMFTrack(tempo, t, i == 0))
+	}
+
+	bw := bufio.NewWriter(w)
+	format := uint16(1)
+	if len(chunks) == 1 {
+		format = 0
+	}
+	hdr := make([]byte, 14)
+	copy(hdr, "MThd")
+	binary.BigEndian.PutUint32(hdr[4:], 6)
+	binary.BigEndian.PutUint16(hdr[8:], format)
+	binary.BigEndian.PutUint16(hdr[10:], uint16(len(chunks)))
+	binary.BigEndian.PutUint16(hdr[12:], uint16(tempo.PPQN))
+	bw.Write(hdr)
+	for _, c := range chunks {
+		var n [8]byte
+		copy(n[:], "MTrk")
+		binary.BigEndian.PutUint32(n[4:], uint32(len(c)))
+		bw.Write(n[:])
+		bw.Write(c)
+	}
+	return bw.Flush()
+}
+
+// smfEvent is an event of a track chunk at an absolute tick.
+type smfEvent struct {
+	tick int
+	data []byte
+}
+
+func encodeSMFTrack(tempo *TempoMap, t Track, withTempo bool) []byte {
+	var events []smfEvent
+	if withTempo {
+		for _, c := range tempo.Changes {
+			us := BPMToMicroseconds(c.BPM)
+			events = append(events, smfEvent{c.Tick, []byte{0xff, 0x51, 0x03, byte(us >> 16), byte(us >> 8), byte(us)}})
+		}
+	}
+	sorted := append(Track(nil), t...)
+	sorted.Sort()
+	for _, ev := range sorted {
+		data := smfEventData(ev.Message)
+		if data == nil {
+			continue
+		}
+		events = append(events, smfEvent{tempo.Tick(ev.Time), data})
+	}
+	// Tempo changes come first at equal ticks, as they were added first.
+	sort.SliceStable(events, func(i, j int) bool { return events[i].tick < events[j].tick })
+
+	var b []byte
+	last := 0
+	for _, ev := range events {
+		b = appendVarLen(b, uint32(ev.tick-last))
+		b = append(b, ev.data...)
+		last = ev.tick
+	}
+	return append(b, 0x00, 0xff, 0x2f, 0x00)
+}
+
+// smfEventData returns the bytes storing msg in a track chunk, or nil if it
+// cannot be stored.
+func smfEventData(msg []byte) []byte {
+	if len(msg) == 0 {
+		return nil
+	}
+	switch {
+	case msg[0] >= 0x80 && msg[0] < 0xf0:
+		if len(msg) < channelMessageLen(msg[0]) {
+			return nil
+		}
+		return msg[:channelMessageLen(msg[0])]
+	case msg[0] == 0xf0:
+		b := []byte{0xf0}
+		b = appendVarLen(b, uint32(len(msg)-1))
+		return append(b, msg[1:]...)
+	case msg[0] == 0xff && len(msg) >= 2:
+		b := []byte{0xff, msg[1]}
+		b = appendVarLen(b, uint32(len(msg)-2))
+		return append(b, msg[2:]...)
+	}
+	return nil
+}
+
+// TrackNameMeta returns the track name meta event naming a track written
+// by WriteSMF.
+func TrackNameMeta(name string) []byte {
+	return append([]byte{0xff, 0x03}, name...)
+}
+
+// channelMessageLen returns the length of a channel message with the given
+// status byte.
+func channelMessageLen(status byte) int {
+	switch status & 0xf0 {
+	case 0xc0, 0xd0:
+		return 2
+	}
+	return 3
+}
+
+func appendVarLen(b []byte, v uint32) []byte {
+	var buf [5]byte
+	i := len(buf) - 1
+	buf[i] = byte(v & 0x7f)
+	for v >>= 7; v > 0; v >>= 7 {
+		i--
+		buf[i] = byte(v&0x7f) | 0x80
+	}
+	return append(b, buf[i:]...)
+}
+
+// SMF is the content of a Standard MIDI File.
+type SMF struct {
+	// Format is 0 for a single track, 1 for simultaneous tracks and 2 for
+	// independent sequences.
+	Format int
+	// Tempo holds the resolution and tempo changes of the file.
+	Tempo *TempoMap
+	// Tracks hold the events of each track chunk, with times converted
+	// using Tempo. Tempo and end of track meta events are left out; other
+	// meta events are kept as described for WriteSMF.
+	Tracks []Track
+}
+
+// Save writes the file, see WriteSMF.
+func (s *SMF) Save(w io.Writer) error {
+	return WriteSMF(w, s.Tempo, s.Tracks...)
+}
+
+// ReadSMF reads a Standard MIDI File. Files timed in SMPTE frames are not
+// supported.
+func ReadSMF(r io.Reader) (*SMF, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 14 || string(data[:4]) != "MThd" {
+		return nil, errors.New("rtmidi: not a Standard MIDI File")
+	}
+	hlen := int(binary.BigEndian.Uint32(data[4:]))
+	if hlen < 6 || len(data) < 8+hlen {
+		return nil, errors.New("rtmidi: truncated SMF header")
+	}
+	format := int(binary.BigEndian.Uint16(data[8:]))
+	ntracks := int(binary.BigEndian.Uint16(data[10:]))
+	division := binary.BigEndian.Uint16(data[12:])
+	if division&0x8000 != 0 || division == 0 {
+		return nil, errors.New("rtmidi: SMPTE timed SMF not supported")
+	}
+	data = data[8+hlen:]
+
+	tempo := &TempoMap{PPQN: int(division)}
+	var tracks [][]smfEvent
+	for len(data) >= 8 && len(tracks) < ntracks {
+		clen := int(binary.BigEndian.Uint32(data[4:]))
+		if len(data) < 8+clen {
+			return nil, errors.New("rtmidi: truncated SMF track")
+		}
+		id, chunk := string(data[:4]), data[8:8+clen]
+		data = data[8+clen:]
+		if id != "MTrk" {
+			continue
+		}
+		events, err := decodeSMFTrack(chunk, tempo)
+		if err != nil {
+			return nil, fmt.Errorf("rtmidi: SMF track %d: %w", len(tracks), err)
+		}
+		tracks = append(tracks, events)
+	}
+	if len(tracks) < ntracks {
+		return nil, errors.New("rtmidi: truncated SMF")
+	}
+	if len(tempo.Changes) == 0 {
+		tempo.Changes = []TempoChange{{Tick: 0, BPM: DefaultBPM}}
+	}
+
+	smf := &SMF{Format: format, Tempo: tempo}
+	for _, events := range tracks {
+		t := make(Track, len(events))
+		for i, ev := range events {
+			t[i] = Event{Time: tempo.Duration(ev.tick), Message: ev.data}
+		}
+		smf.Tracks = append(smf.Tracks, t)
+	}
+	return smf, nil
+}
+
+// decodeSMFTrack decodes the events of a track chunk, adding its tempo
+// changes to tempo.
+func decodeSMFTrack(b []byte, tempo *TempoMap) ([]smfEvent, error) {
+	var events []smfEvent
+	tick := 0
+	var running byte
+	for len(b) > 0 {
+		delta, n := readVarLen(b)
+		if n == 0 {
+			return nil, errors.New("invalid delta time")
+		}
+		tick += int(delta)
+		b = b[n:]
+		if len(b) == 0 {
+			return nil, errors.New("missing event")
+		}
+		status := b[0]
+		switch {
+		case status == 0xff:
+			if len(b) < 2 {
+				return nil, errors.New("truncated meta event")
+			}
+			l, n := readVarLen(b[2:])
+			if n == 0 || len(b) < 2+n+int(l) {
+				return nil, errors.New("truncated meta event")
+			}
+			typ, payload := b[1], b[2+n:2+n+int(l)]
+			b = b[2+n+int(l):]
+			switch {
+			case typ == 0x2f:
+				return events, nil
+			case typ == 0x51 && len(payload) == 3:
+				us := int(payload[0])<<16 | int(payload[1])<<8 | int(payload[2])
+				if us > 0 {
+					tempo.SetTempo(tick, MicrosecondsToBPM(us))
+				}
+			default:
+				events = append(events, smfEvent{tick, append([]byte{0xff, typ}, payload...)})
+			}
+		case status == 0xf0 || status == 0xf7:
+			l, n := readVarLen(b[1:])
+			if n == 0 || len(b) < 1+n+int(l) {
+				return nil, errors.New("truncated SysEx event")
+			}
+			payload := b[1+n : 1+n+int(l)]
+			b = b[1+n+int(l):]
+			msg := append([]byte(nil), payload...)
+			if status == 0xf0 {
+				msg = append([]byte{0xf0}, msg...)
+			}
+			events = append(events, smfEvent{tick, msg})
+		default:
+			if status&0x80 != 0 {
+				running = status
+				b = b[1:]
+			} else if running == 0 {
+				return nil, errors.New("data byte without status")
+			}
+			l := channelMessageLen(running) - 1
+			if len(b) < l {
+				return nil, errors.New("truncated channel message")
+			}
+			events = append(events, smfEvent{tick, append([]byte{running}, b[:l]...)})
+			b = b[l:]
+		}
+	}
+	return events, nil
+}
+
+// readVarLen reads a variable length quantity, returning the number of
+// bytes read or 0 if it is invalid.
+func readVarLen(b []byte) (uint32, int) {
+	var v uint32
+	for i := 0; i < len(b) && i < 4; i++ {
+		v = v<<7 | uint32(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
@@ -0,0 +1,123 @@
+package rtmidi
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteSMF(t *testing.T) {
+	track := Track{
+		{Time: 0, Message: []byte{0x90, 60, 100}},
+		{Time: 500 * time.Millisecond, Message: []byte{0x80, 60, 0}},
+		{Time: 500 * time.Millisecond, Message: []byte{0xf8}},
+		{Time: time.Second, Message: []byte{0xf0, 0x7e, 0x7f, 0x09, 0x01, 0xf7}},
+	}
+	var buf bytes.Buffer
+	if err := WriteSMF(&buf, NewTempoMap(96, 120), track); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 0, 0, 1, 0, 96,
+		'M', 'T', 'r', 'k', 0, 0, 0, 27,
+		0x00, 0xff, 0x51, 0x03, 0x07, 0xa1, 0x20,
+		0x00, 0x90, 60, 100,
+		0x60, 0x80, 60, 0,
+		0x60, 0xf0, 0x05, 0x7e, 0x7f, 0x09, 0x01, 0xf7,
+		0x00, 0xff, 0x2f, 0x00,
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("WriteSMF wrote\n% x\nwant\n% x", buf.Bytes(), want)
+	}
+}
+
+func TestWriteSMFFormat1(t *testing.T) {
+	var buf bytes.Buffer
+	a := Track{{Message: []byte{0xc0, 1}}}
+	b := Track{{Message: []byte{0xc1, 2}}}
+	if err := WriteSMF(&buf, nil, a, b); err != nil {
+		t.Fatal(err)
+	}
+	hdr := buf.Bytes()[:14]
+	if want := []byte{'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 1, 0, 2, 0x01, 0xe0}; !bytes.Equal(hdr, want) {
+		t.Errorf("header % x, want % x", hdr, want)
+	}
+	if n := bytes.Count(buf.Bytes(), []byte("MTrk")); n != 2 {
+		t.Errorf("%d track chunks, want 2", n)
+	}
+}
+
+func TestAppendVarLen(t *testing.T) {
+	for _, tt := range []struct {
+		v    uint32
+		want []byte
+	}{
+		{0, []byte{0x00}},
+		{0x7f, []byte{0x7f}},
+		{0x80, []byte{0x81, 0x00}},
+		{0x3fff, []byte{0xff, 0x7f}},
+		{0x0fffffff, []byte{0xff, 0xff, 0xff, 0x7f}},
+	} {
+		if got := appendVarLen(nil, tt.v); !bytes.Equal(got, tt.want) {
+			t.Errorf("appendVarLen(%#x) = % x, want % x", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestReadSMF(t *testing.T) {
+	data := []byte{
+		'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 1, 0, 2, 0, 96,
+		'M', 'T', 'r', 'k', 0, 0, 0, 18,
+		0x00, 0xff, 0x51, 0x03, 0x07, 0xa1, 0x20,
+		0x60, 0xff, 0x51, 0x03, 0x0f, 0x42, 0x40,
+		0x00, 0xff, 0x2f, 0x00,
+		'M', 'T', 'r', 'k', 0, 0, 0, 22,
+		0x00, 0xff, 0x03, 0x01, 'A',
+		0x00, 0x90, 60, 100,
+		0x60, 60, 0, // running status
+		0x60, 0xf0, 0x03, 0x7e, 0x01, 0xf7,
+		0x00, 0xff, 0x2f, 0x00,
+	}
+	smf, err := ReadSMF(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if smf.Format != 1 || smf.Tempo.PPQN != 96 || len(smf.Tracks) != 2 || len(smf.Tracks[0]) != 0 {
+		t.Fatalf("ReadSMF = %+v", smf)
+	}
+	if got := smf.Tempo.BPM(96); got != 60 {
+		t.Errorf("tempo at tick 96 = %v, want 60", got)
+	}
+	want := Track{
+		{Time: 0, Message: TrackNameMeta("A")},
+		{Time: 0, Message: []byte{0x90, 60, 100}},
+		{Time: 500 * time.Millisecond, Message: []byte{0x90, 60, 0}},
+		{Time: 1500 * time.Millisecond, Message: []byte{0xf0, 0x7e, 0x01, 0xf7}},
+	}
+	if !reflect.DeepEqual(smf.Tracks[1], want) {
+		t.Errorf("track 1 = %v, want %v", smf.Tracks[1], want)
+	}
+
+	var buf bytes.Buffer
+	if err := smf.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	again, err := ReadSMF(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again.Tracks[1], want) || !reflect.DeepEqual(again.Tempo, smf.Tempo) {
+		t.Errorf("round trip gave %v, %v", again.Tracks, again.Tempo)
+	}
+
+	for _, bad := range [][]byte{
+		[]byte("RIFF"),
+		data[:20],
+		{'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 0, 0, 1, 0xe7, 0x28},
+	} {
+		if _, err := ReadSMF(bytes.NewReader(bad)); err == nil {
+			t.Errorf("ReadSMF(% x) succeeded", bad)
+		}
+	}
+}
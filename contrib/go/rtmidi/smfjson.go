@@ -0,0 +1,125 @@
+package rtmidi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// smfJSON is the JSON representation of an SMF.
+type smfJSON struct {
+	Format int               `json:"format"`
+	PPQN   int               `json:"ppqn"`
+	Tempo  []tempoChangeJSON `json:"tempo"`
+	Tracks [][]eventJSON     `json:"tracks"`
+}
+
+type tempoChangeJSON struct {
+	Tick int     `json:"tick"`
+	BPM  float64 `json:"bpm"`
+}
+
+type eventJSON struct {
+	Tick int            `json:"tick"`
+	Time *time.Duration `json:"time,omitempty"`
+	Data string         `json:"data"`
+	Text string         `json:"text,omitempty"`
+}
+
+// MarshalJSON encodes the file as JSON, for tools that diff, script or
+// display MIDI files:
+//
+//	{
+//	  "format": 1,
+//	  "ppqn": 480,
+//	  "tempo": [{"tick": 0, "bpm": 120}],
+//	  "tracks": [
+//	    [
+//	      {"tick": 0, "time": 0, "data": "ff 03 50 69 61 6e 6f", "text": "Meta 03 \"Piano\""},
+//	      {"tick": 480, "time": 500000000, "data": "90 3c 64", "text": "NoteOn ch=1 C4 vel=100"}
+//	    ]
+//	  ]
+//	}
+//
+// Each track is a list of events. The data of an event is its message in
+// hex, and its time is in nanoseconds from the start of the file, with its
+// tick as converted by the tempo map. The text describes the message as
+// FormatMessage does, or gives the type of a meta event and any text it
+// holds, and is only there to be read. Decoding the JSON gives back the
+// same SMF.
+func (s *SMF) MarshalJSON() ([]byte, error) {
+	tempo := s.Tempo
+	if tempo == nil {
+		tempo = NewTempoMap(DefaultPPQN, DefaultBPM)
+	}
+	j := smfJSON{Format: s.Format, PPQN: tempo.PPQN, Tempo: []tempoChangeJSON{}, Tracks: [][]eventJSON{}}
+	for _, c := range tempo.Changes {
+		j.Tempo = append(j.Tempo, tempoChangeJSON{c.Tick, c.BPM})
+	}
+	for _, t := range s.Tracks {
+		events := []eventJSON{}
+		for _, ev := range t {
+			d := ev.Time
+			events = append(events, eventJSON{
+				Tick: tempo.Tick(d),
+				Time: &d,
+				Data: hexMessage(ev.Message),
+				Text: smfEventText(ev.Message),
+			})
+		}
+		j.Tracks = append(j.Tracks, events)
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes a file encoded by MarshalJSON. Events without a
+// time are placed at their tick, which suits JSON written by hand; when
+// both are given the time is used.
+func (s *SMF) UnmarshalJSON(b []byte) error {
+	var j smfJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	if j.PPQN <= 0 {
+		return errors.New("rtmidi: SMF JSON without a valid ppqn")
+	}
+	tempo := &TempoMap{PPQN: j.PPQN}
+	for _, c := range j.Tempo {
+		if c.BPM <= 0 {
+			return fmt.Errorf("rtmidi: SMF JSON tempo %v at tick %d", c.BPM, c.Tick)
+		}
+		tempo.SetTempo(c.Tick, c.BPM)
+	}
+	if len(tempo.Changes) == 0 {
+		tempo.Changes = []TempoChange{{Tick: 0, BPM: DefaultBPM}}
+	}
+	smf := SMF{Format: j.Format, Tempo: tempo}
+	for i, events := range j.Tracks {
+		t := make(Track, len(events))
+		for k, ev := range events {
+			msg, err := parseHexMessage(ev.Data)
+			if err != nil {
+				return fmt.Errorf("rtmidi: SMF JSON track %d event %d: %w", i, k, err)
+			}
+			t[k] = Event{Time: tempo.Duration(ev.Tick), Message: msg}
+			if ev.Time != nil {
+				t[k].Time = *ev.Time
+			}
+		}
+		smf.Tracks = append(smf.Tracks, t)
+	}
+	*s = smf
+	return nil
+}
+
+// smfEventText describes a message of an SMF track.
+func smfEventText(msg []byte) string {
+	if len(msg) < 2 || msg[0] != 0xff {
+		return FormatMessage(msg)
+	}
+	if msg[1] >= 0x01 && msg[1] <= 0x0f {
+		return fmt.Sprintf("Meta %02x %q", msg[1], msg[2:])
+	}
+	return fmt.Sprintf("Meta %02x", msg[1])
+}
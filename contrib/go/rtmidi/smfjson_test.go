@@ -0,0 +1,82 @@
+package rtmidi
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSMFJSONRoundTrip(t *testing.T) {
+	tempo := NewTempoMap(96, 120)
+	tempo.SetTempo(192, 100)
+	tracks := []Track{
+		{
+			{Time: 0, Message: TrackNameMeta("Piano")},
+			{Time: 0, Message: []byte{0x90, 60, 100}},
+			{Time: 500 * time.Millisecond, Message: []byte{0x80, 60, 0}},
+			{Time: 1500 * time.Millisecond, Message: []byte{0xf0, 0x7e, 0x7f, 0x09, 0x01, 0xf7}},
+		},
+		{{Time: time.Second, Message: []byte{0xb1, 7, 90}}},
+	}
+	var file bytes.Buffer
+	if err := WriteSMF(&file, tempo, tracks...); err != nil {
+		t.Fatal(err)
+	}
+	smf, err := ReadSMF(bytes.NewReader(file.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.MarshalIndent(smf, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`"ppqn": 96`, `"bpm": 100`, `"data": "90 3c 64"`, `"text": "Meta 03 \"Piano\""`} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("JSON lacks %s:\n%s", s, b)
+		}
+	}
+	var back SMF
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&back, smf) {
+		t.Errorf("decoded %+v, want %+v", back, smf)
+	}
+	var again bytes.Buffer
+	if err := back.Save(&again); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again.Bytes(), file.Bytes()) {
+		t.Errorf("saved\n% x\nwant\n% x", again.Bytes(), file.Bytes())
+	}
+}
+
+func TestSMFJSONTicks(t *testing.T) {
+	var smf SMF
+	err := json.Unmarshal([]byte(`{"format": 0, "ppqn": 480, "tracks": [[
+		{"tick": 0, "data": "90 3C 64"},
+		{"tick": 960, "data": "803c00"}
+	]]}`), &smf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Track{
+		{Time: 0, Message: []byte{0x90, 60, 100}},
+		{Time: time.Second, Message: []byte{0x80, 60, 0}},
+	}
+	if !reflect.DeepEqual(smf.Tracks[0], want) || smf.Tempo.BPM(0) != DefaultBPM {
+		t.Errorf("decoded %+v", smf)
+	}
+	for _, bad := range []string{
+		`{"ppqn": 0}`,
+		`{"ppqn": 96, "tempo": [{"tick": 0, "bpm": 0}]}`,
+		`{"ppqn": 96, "tracks": [[{"data": "9x"}]]}`,
+	} {
+		if err := json.Unmarshal([]byte(bad), &smf); err == nil {
+			t.Errorf("decoded %s", bad)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package rtmidi
+
+import (
+	"sort"
+	"sync"
+)
+
+// ChannelSnapshot holds the known controller values of a channel. Program
+// and PitchBend are only known if HasProgram and HasPitchBend are set.
+type ChannelSnapshot struct {
+	Program      int
+	HasProgram   bool
+	PitchBend    int
+	HasPitchBend bool
+	Controllers  map[int]int
+}
+
+// Snapshot holds the known controller values of the channels (0-15) of a
+// device, as captured by StateCache.Snapshot or StateTracker.Snapshot.
+type Snapshot map[int]ChannelSnapshot
+
+// snapshot captures the given channels of s, or all of them if none are
+// given. Channels with nothing known are left out.
+func (s *controllerState) snapshot(channels []int) Snapshot {
+	if len(channels) == 0 {
+		channels = make([]int, 16)
+		for ch := range channels {
+			channels[ch] = ch
+		}
+	}
+	snap := Snapshot{}
+	for _, ch := range channels {
+		ch &= 0x0f
+		cs := ChannelSnapshot{Controllers: map[int]int{}}
+		if p := s.program[ch]; p >= 0 {
+			cs.Program, cs.HasProgram = int(p), true
+		}
+		if b := s.bend[ch]; b >= 0 {
+			cs.PitchBend, cs.HasPitchBend = int(b), true
+		}
+		for cc, v := range s.cc[ch] {
+			if v >= 0 {
+				cs.Controllers[cc] = int(v)
+			}
+		}
+		if cs.HasProgram || cs.HasPitchBend || len(cs.Controllers) > 0 {
+			snap[ch] = cs
+		}
+	}
+	return snap
+}
+
+// Messages returns the messages recreating the snapshot, channel by
+// channel: bank select, program change, the other controllers and pitch
+// bend.
+func (s Snapshot) Messages() [][]byte {
+	channels := make([]int, 0, len(s))
+	for ch := range s {
+		channels = append(channels, ch)
+	}
+	sort.Ints(channels)
+	var msgs [][]byte
+	for _, ch := range channels {
+		state := newControllerState()
+		cs := s[ch]
+		ch &= 0x0f
+		if cs.HasProgram {
+			state.program[ch] = int16(cs.Program & 0x7f)
+		}
+		if cs.HasPitchBend {
+			state.bend[ch] = int32(clampPitchBend(cs.PitchBend))
+		}
+		for cc, v := range cs.Controllers {
+			if cc >= 0 && cc < 128 {
+				state.cc[ch][cc] = int16(v & 0x7f)
+			}
+		}
+		msgs = append(msgs, state.messages(ch)...)
+	}
+	return msgs
+}
+
+// Snapshot captures the cached values of the given channels, or of all of
+// them if none are given.
+func (c *StateCache) Snapshot(channels ...int) Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state.snapshot(channels)
+}
+
+// Restore sends the values of a snapshot. Values the device already has
+// according to the cache are not sent again.
+func (c *StateCache) Restore(s Snapshot) error {
+	for _, msg := range s.Messages() {
+		if err := c.SendMessage(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StateTracker follows the controller values received on an input, so that
+// the state a controller or another application set up can be captured.
+type StateTracker struct {
+	mu    sync.Mutex
+	state *controllerState
+}
+
+// NewStateTracker returns a StateTracker with nothing known.
+func NewStateTracker() *StateTracker {
+	return &StateTracker{state: newControllerState()}
+}
+
+// Feed updates the tracked state from a received message. Of SysEx
+// messages, only the GM, GS and XG resets clear the state.
+func (t *StateTracker) Feed(msg []byte) {
+	if len(msg) > 0 && msg[0] == 0xf0 && !isSystemReset(msg) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.apply(msg)
+}
+
+// Callback can be passed to MIDIIn.SetCallback to track an input directly.
+func (t *StateTracker) Callback(m MIDIIn, msg []byte, ts float64) {
+	t.Feed(msg)
+}
+
+// Snapshot captures the tracked values of the given channels, or of all of
+// them if none are given.
+func (t *StateTracker) Snapshot(channels ...int) Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state.snapshot(channels)
+}
+
+// Reset forgets the tracked state.
+func (t *StateTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.clear()
+}
@@ -0,0 +1,76 @@
+package rtmidi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	out := &fakeOut{}
+	c := NewStateCache(out)
+	for _, msg := range [][]byte{
+		{0xb0, 7, 100},
+		{0xb0, 10, 64},
+		{0xc0, 3},
+		{0xb2, 74, 20},
+		{0xe2, 0x00, 0x50},
+	} {
+		c.SendMessage(msg)
+	}
+	scene := c.Snapshot()
+	want := Snapshot{
+		0: {Program: 3, HasProgram: true, Controllers: map[int]int{7: 100, 10: 64}},
+		2: {PitchBend: 0x2800, HasPitchBend: true, Controllers: map[int]int{74: 20}},
+	}
+	if !reflect.DeepEqual(scene, want) {
+		t.Fatalf("Snapshot() = %v, want %v", scene, want)
+	}
+	if ch2 := c.Snapshot(2); len(ch2) != 1 || ch2[2].Controllers[74] != 20 {
+		t.Errorf("Snapshot(2) = %v", ch2)
+	}
+
+	c.SendMessage([]byte{0xb0, 7, 50})
+	c.SendMessage([]byte{0xb2, 74, 90})
+	before := len(out.messages())
+	if err := c.Restore(scene); err != nil {
+		t.Fatal(err)
+	}
+	got := out.messages()[before:]
+	restored := [][]byte{{0xb0, 7, 100}, {0xb2, 74, 20}}
+	if !reflect.DeepEqual(got, restored) {
+		t.Errorf("Restore sent % x, want % x", got, restored)
+	}
+}
+
+func TestStateTracker(t *testing.T) {
+	tr := NewStateTracker()
+	tr.Callback(nil, []byte{0xb9, 1, 30}, 0)
+	tr.Callback(nil, []byte{0xc9, 12}, 0)
+	tr.Callback(nil, []byte{0x99, 36, 100}, 0)
+	want := [][]byte{{0xc9, 12}, {0xb9, 1, 30}}
+	if got := tr.Snapshot().Messages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Messages() = % x, want % x", got, want)
+	}
+	// Only SysEx resetting the device clears the state.
+	tr.Feed([]byte{0xf0, 0x43, 0x10, 0x4c, 0x08, 0x00, 0x07, 0x01, 0xf7})
+	if got := tr.Snapshot().Messages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Messages() after SysEx = % x, want % x", got, want)
+	}
+	tr.Feed([]byte{0xf0, 0x41, 0x11, 0x42, 0x12, 0x40, 0x00, 0x7f, 0x00, 0x41, 0xf7})
+	if s := tr.Snapshot(); len(s) != 0 {
+		t.Errorf("Snapshot after GS Reset = %v", s)
+	}
+	tr.Feed([]byte{0xb9, 1, 30})
+	tr.Reset()
+	if s := tr.Snapshot(); len(s) != 0 {
+		t.Errorf("Snapshot after Reset = %v", s)
+	}
+}
+
+func TestSnapshotZeroValues(t *testing.T) {
+	s := Snapshot{1: {Controllers: map[int]int{7: 90}}}
+	want := [][]byte{{0xb1, 7, 90}}
+	if got := s.Messages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Messages() = % x, want % x", got, want)
+	}
+}
@@ -0,0 +1,229 @@
+package rtmidi
+
+import (
+	"sync"
+	"time"
+)
+
+// controllerState holds the last known controller, program and pitch bend
+// values of the 16 channels of a device. Unknown values are -1.
+type controllerState struct {
+	cc      [16][128]int16
+	program [16]int16
+	bend    [16]int32
+}
+
+func newControllerState() *controllerState {
+	s := &controllerState{}
+	s.clear()
+	return s
+}
+
+func (s *controllerState) clear() {
+	for ch := range s.cc {
+		s.clearChannel(ch)
+	}
+}
+
+func (s *controllerState) clearChannel(ch int) {
+	for cc := range s.cc[ch] {
+		s.cc[ch][cc] = -1
+	}
+	s.program[ch] = -1
+	s.bend[ch] = -1
+}
+
+// statefulCC reports whether a controller holds a value, as opposed to
+// triggering an action, like data entry and the channel mode messages, or
+// selecting the parameter data entry applies to.
+func statefulCC(cc int) bool {
+	switch {
+	case cc == 6 || cc == 38:
+		return false
+	case cc >= 96 && cc <= 101:
+		return false
+	case cc >= 120:
+		return false
+	}
+	return true
+}
+
+// apply updates the state from msg and reports whether msg changed it, or
+// is a message the state does not cover and must always be sent.
+func (s *controllerState) apply(msg []byte) bool {
+	if len(msg) == 0 {
+		return true
+	}
+	ch := int(msg[0] & 0x0f)
+	switch {
+	case msg[0] == 0xf0 || msg[0] == 0xff:
+		// SysEx may reset the device, as GM System On does.
+		s.clear()
+		return true
+	case msg[0]&0xf0 == 0xb0 && len(msg) >= 3:
+		cc, v := int(msg[1]&0x7f), int16(msg[2]&0x7f)
+		if cc == 121 {
+			s.resetControllers(ch)
+			return true
+		}
+		if !statefulCC(cc) {
+			return true
+		}
+		if s.cc[ch][cc] == v {
+			return false
+		}
+		s.cc[ch][cc] = v
+		if cc == 0 || cc == 32 {
+			// The next program change selects from the new bank.
+			s.program[ch] = -1
+		}
+		return true
+	case msg[0]&0xf0 == 0xc0 && len(msg) >= 2:
+		v := int16(msg[1] & 0x7f)
+		if s.program[ch] == v {
+			return false
+		}
+		s.program[ch] = v
+		return true
+	case msg[0]&0xf0 == 0xe0 && len(msg) >= 3:
+		v := int32(msg[1]&0x7f) | int32(msg[2]&0x7f)<<7
+		if s.bend[ch] == v {
+			return false
+		}
+		s.bend[ch] = v
+		return true
+	}
+	return true
+}
+
+// unchanged reports whether msg sets a value the state already has, without
+// updating it.
+func (s *controllerState) unchanged(msg []byte) bool {
+	if len(msg) < 2 {
+		return false
+	}
+	ch := int(msg[0] & 0x0f)
+	switch {
+	case msg[0]&0xf0 == 0xb0 && len(msg) >= 3:
+		cc := int(msg[1] & 0x7f)
+		return cc != 121 && statefulCC(cc) && s.cc[ch][cc] == int16(msg[2]&0x7f)
+	case msg[0]&0xf0 == 0xc0:
+		return s.program[ch] == int16(msg[1]&0x7f)
+	case msg[0]&0xf0 == 0xe0 && len(msg) >= 3:
+		return s.bend[ch] == int32(msg[1]&0x7f)|int32(msg[2]&0x7f)<<7
+	}
+	return false
+}
+
+// resetControllers applies Reset All Controllers to channel ch, which
+// resets pitch bend, modulation, expression and the pedals.
+func (s *controllerState) resetControllers(ch int) {
+	s.bend[ch] = PitchBendCenter
+	s.cc[ch][1] = 0
+	s.cc[ch][11] = 127
+	for cc := 64; cc <= 67; cc++ {
+		s.cc[ch][cc] = 0
+	}
+}
+
+// messages returns the messages recreating the known state of channel ch:
+// bank select, program change, the other controllers and pitch bend.
+func (s *controllerState) messages(ch int) [][]byte {
+	var msgs [][]byte
+	cc := func(n int) {
+		if v := s.cc[ch][n]; v >= 0 {
+			msgs = append(msgs, []byte{0xb0 | byte(ch), byte(n), byte(v)})
+		}
+	}
+	cc(0)
+	cc(32)
+	if p := s.program[ch]; p >= 0 {
+		msgs = append(msgs, []byte{0xc0 | byte(ch), byte(p)})
+	}
+	for n := 1; n < 128; n++ {
+		if n != 32 {
+			cc(n)
+		}
+	}
+	if b := s.bend[ch]; b >= 0 {
+		msgs = append(msgs, PitchBendMessage(ch, int(b)))
+	}
+	return msgs
+}
+
+// StateCache wraps a MIDIOut and remembers the last controller, program
+// change and pitch bend values sent on each channel. Sending a value a
+// channel already has is suppressed, and ResendState replays everything
+// after the device has been reconnected or power cycled, which helps with
+// unreliable USB devices and network bridges.
+//
+// Data entry, RPN and NRPN selection and channel mode messages are always
+// sent, as they trigger actions rather than set values. SysEx and System
+// Reset clear the cache, since they may reset the device.
+type StateCache struct {
+	MIDIOut
+
+	mu    sync.Mutex
+	state *controllerState
+}
+
+// NewStateCache returns a StateCache sending to out, with nothing known
+// about the state of the device.
+func NewStateCache(out MIDIOut) *StateCache {
+	return &StateCache{MIDIOut: out, state: newControllerState()}
+}
+
+// SendMessage sends msg unless it would not change the cached state. The
+// cache is only updated once msg is sent, so that a failed send can be
+// retried.
+func (c *StateCache) SendMessage(msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state.unchanged(msg) {
+		return nil
+	}
+	if err := c.MIDIOut.SendMessage(msg); err != nil {
+		return err
+	}
+	c.state.apply(msg)
+	return nil
+}
+
+// SendMessageAt schedules msg unless it would not change the cached state,
+// updating the cache straight away once it is scheduled.
+func (c *StateCache) SendMessageAt(msg []byte, at time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state.unchanged(msg) {
+		return nil
+	}
+	if err := SendMessageAt(c.MIDIOut, msg, at); err != nil {
+		return err
+	}
+	c.state.apply(msg)
+	return nil
+}
+
+// ResendState sends every cached value again, channel by channel.
+func (c *StateCache) ResendState() error {
+	c.mu.Lock()
+	var msgs [][]byte
+	for ch := 0; ch < 16; ch++ {
+		msgs = append(msgs, c.state.messages(ch)...)
+	}
+	c.mu.Unlock()
+	for _, msg := range msgs {
+		if err := c.MIDIOut.SendMessage(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Invalidate forgets the cached state, so that the next value sent for
+// every controller goes out even if it was sent before.
+func (c *StateCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state.clear()
+}
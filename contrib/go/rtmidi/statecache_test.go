@@ -0,0 +1,105 @@
+package rtmidi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestStateCache(t *testing.T) {
+	out := &fakeOut{}
+	c := NewStateCache(out)
+	for _, msg := range [][]byte{
+		{0xb0, 7, 100},
+		{0xb0, 7, 100}, // suppressed
+		{0xb0, 7, 90},
+		{0xc0, 5},
+		{0xc0, 5}, // suppressed
+		{0xb0, 0, 1},
+		{0xc0, 5}, // new bank: sent
+		{0xb0, 6, 12},
+		{0xb0, 6, 12}, // data entry: always sent
+		{0xe1, 0x00, 0x40},
+		{0xe1, 0x00, 0x40}, // suppressed
+		{0x90, 60, 100},
+		{0x90, 60, 100}, // notes are not cached
+	} {
+		if err := c.SendMessage(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := [][]byte{
+		{0xb0, 7, 100},
+		{0xb0, 7, 90},
+		{0xc0, 5},
+		{0xb0, 0, 1},
+		{0xc0, 5},
+		{0xb0, 6, 12},
+		{0xb0, 6, 12},
+		{0xe1, 0x00, 0x40},
+		{0x90, 60, 100},
+		{0x90, 60, 100},
+	}
+	if got := out.messages(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("sent % x\nwant % x", got, want)
+	}
+
+	resent := &fakeOut{}
+	c.MIDIOut = resent
+	if err := c.ResendState(); err != nil {
+		t.Fatal(err)
+	}
+	want = [][]byte{
+		{0xb0, 0, 1},
+		{0xc0, 5},
+		{0xb0, 7, 90},
+		{0xe1, 0x00, 0x40},
+	}
+	if got := resent.messages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("resent % x\nwant % x", got, want)
+	}
+
+	c.Invalidate()
+	c.SendMessage([]byte{0xb0, 7, 90})
+	if n := len(resent.messages()); n != 5 {
+		t.Errorf("value not sent after Invalidate")
+	}
+}
+
+func TestStateCacheResets(t *testing.T) {
+	out := &fakeOut{}
+	c := NewStateCache(out)
+	c.SendMessage([]byte{0xb0, 64, 127})
+	c.SendMessage([]byte{0xb0, 121, 0})
+	c.SendMessage([]byte{0xb0, 64, 0}) // already reset
+	c.SendMessage([]byte{0xb0, 7, 100})
+	c.SendMessage(GMSystemOn)
+	c.SendMessage([]byte{0xb0, 7, 100}) // device was reset
+	want := [][]byte{
+		{0xb0, 64, 127},
+		{0xb0, 121, 0},
+		{0xb0, 7, 100},
+		GMSystemOn,
+		{0xb0, 7, 100},
+	}
+	if got := out.messages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("sent % x\nwant % x", got, want)
+	}
+}
+
+func TestStateCacheFailedSend(t *testing.T) {
+	out := &fakeOut{err: errors.New("device unplugged")}
+	c := NewStateCache(out)
+	if err := c.SendMessage([]byte{0xb0, 7, 100}); err == nil {
+		t.Fatal("send to a failing output succeeded")
+	}
+	out.mu.Lock()
+	out.err = nil
+	out.mu.Unlock()
+	if err := c.SendMessage([]byte{0xb0, 7, 100}); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.messages(); !reflect.DeepEqual(got, [][]byte{{0xb0, 7, 100}}) {
+		t.Errorf("retry sent % X", got)
+	}
+}
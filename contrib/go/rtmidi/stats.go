@@ -0,0 +1,139 @@
+package rtmidi
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultStatsWindow is the window rates are measured over when none is
+// given.
+const DefaultStatsWindow = time.Second
+
+// statsBuckets is the number of slices a window is divided into; the rate
+// over the window moves in steps of a slice.
+const statsBuckets = 10
+
+// Rate is a rate of messages and bytes per second.
+type Rate struct {
+	Messages float64
+	Bytes    float64
+}
+
+// SendStats are the send rates of an output, averaged over a sliding
+// window.
+type SendStats struct {
+	Window time.Duration
+	// Total is the rate of all messages.
+	Total Rate
+	// Channels are the rates of channel messages on each channel (0-15).
+	Channels [16]Rate
+	// System is the rate of system messages, including SysEx.
+	System Rate
+	// Messages and Bytes count everything sent since the meter was made.
+	Messages, Bytes uint64
+}
+
+type statsBucket struct {
+	slot  int64
+	msgs  [17]int
+	bytes [17]int
+}
+
+// Meter wraps a MIDIOut and measures the rate of the messages sent through
+// it, per channel and in total, so that feedback loops and runaway
+// automation show up as soon as they start. Counting a message takes a
+// mutex and a few additions.
+type Meter struct {
+	MIDIOut
+
+	mu       sync.Mutex
+	window   time.Duration
+	buckets  [statsBuckets]statsBucket
+	messages uint64
+	bytes    uint64
+	now      func() time.Time
+}
+
+// NewMeter returns a Meter sending to out and measuring rates over window,
+// or DefaultStatsWindow if window is not positive. A window is at least a
+// nanosecond per slice, so 10 nanoseconds.
+func NewMeter(out MIDIOut, window time.Duration) *Meter {
+	switch {
+	case window <= 0:
+		window = DefaultStatsWindow
+	case window < statsBuckets:
+		window = statsBuckets
+	}
+	return &Meter{MIDIOut: out, window: window, now: time.Now}
+}
+
+// SendMessage sends msg and counts it if it was sent.
+func (m *Meter) SendMessage(msg []byte) error {
+	if err := m.MIDIOut.SendMessage(msg); err != nil {
+		return err
+	}
+	m.count(msg)
+	return nil
+}
+
+// SendMessageAt sends msg at the given time. It is counted when it is
+// handed to the output, not when it leaves it.
+func (m *Meter) SendMessageAt(msg []byte, at time.Time) error {
+	if err := SendMessageAt(m.MIDIOut, msg, at); err != nil {
+		return err
+	}
+	m.count(msg)
+	return nil
+}
+
+func (m *Meter) slot(t time.Time) int64 {
+	return t.UnixNano() / int64(m.window/statsBuckets)
+}
+
+func (m *Meter) count(msg []byte) {
+	if len(msg) == 0 {
+		return
+	}
+	i := 16
+	if msg[0] >= 0x80 && msg[0] < 0xf0 {
+		i = int(msg[0] & 0x0f)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	slot := m.slot(m.now())
+	b := &m.buckets[slot%statsBuckets]
+	if b.slot != slot {
+		*b = statsBucket{slot: slot}
+	}
+	b.msgs[i]++
+	b.bytes[i] += len(msg)
+	m.messages++
+	m.bytes += uint64(len(msg))
+}
+
+// Stats returns the rates over the last window.
+func (m *Meter) Stats() SendStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := SendStats{Window: m.window, Messages: m.messages, Bytes: m.bytes}
+	now := m.slot(m.now())
+	secs := m.window.Seconds()
+	for _, b := range m.buckets {
+		if b.slot <= now-statsBuckets || b.slot > now {
+			continue
+		}
+		for i := 0; i < 17; i++ {
+			r := Rate{float64(b.msgs[i]) / secs, float64(b.bytes[i]) / secs}
+			if i < 16 {
+				s.Channels[i].Messages += r.Messages
+				s.Channels[i].Bytes += r.Bytes
+			} else {
+				s.System.Messages += r.Messages
+				s.System.Bytes += r.Bytes
+			}
+			s.Total.Messages += r.Messages
+			s.Total.Bytes += r.Bytes
+		}
+	}
+	return s
+}
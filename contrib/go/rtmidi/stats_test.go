@@ -0,0 +1,52 @@
+package rtmidi
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMeter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	m := NewMeter(&fakeOut{}, time.Second)
+	m.now = func() time.Time { return now }
+
+	for i := 0; i < 10; i++ {
+		m.SendMessage([]byte{0x92, 60, 100})
+		m.SendMessage([]byte{0xf8})
+		now = now.Add(50 * time.Millisecond)
+	}
+	s := m.Stats()
+	if s.Channels[2] != (Rate{10, 30}) || s.System != (Rate{10, 10}) || s.Total != (Rate{20, 40}) {
+		t.Errorf("stats %+v", s)
+	}
+	if s.Messages != 20 || s.Bytes != 40 {
+		t.Errorf("counted %d messages, %d bytes", s.Messages, s.Bytes)
+	}
+
+	// The sends slide out of the window.
+	now = now.Add(1000 * time.Millisecond)
+	if s := m.Stats(); s.Channels[2].Messages != 0 || s.Messages != 20 {
+		t.Errorf("after the window: %+v", s)
+	}
+}
+
+func TestMeterError(t *testing.T) {
+	m := NewMeter(&fakeOut{err: errors.New("unplugged")}, 0)
+	if m.SendMessage([]byte{0xf8}) == nil {
+		t.Fatal("no error")
+	}
+	if s := m.Stats(); s.Messages != 0 || s.Window != DefaultStatsWindow {
+		t.Errorf("stats %+v", s)
+	}
+}
+
+func TestMeterShortWindow(t *testing.T) {
+	m := NewMeter(&fakeOut{}, time.Nanosecond)
+	if err := m.SendMessage([]byte{0xf8}); err != nil {
+		t.Fatal(err)
+	}
+	if s := m.Stats(); s.Window != 10*time.Nanosecond || s.Messages != 1 {
+		t.Errorf("stats %+v", s)
+	}
+}
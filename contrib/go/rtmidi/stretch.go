@@ -0,0 +1,95 @@
+package rtmidi
+
+import (
+	"math"
+	"time"
+)
+
+// Scale returns a copy of t with the time of every event multiplied by
+// factor, which stretches the track if it is above 1 and compresses it if
+// it is below. Scaling a track recorded at one tempo by old/new conforms it
+// to the new tempo. A negative factor counts as zero.
+func (t Track) Scale(factor float64) Track {
+	out := t.Clone()
+	if factor < 0 {
+		factor = 0
+	}
+	for i := range out {
+		out[i].Time = time.Duration(math.Round(float64(out[i].Time) * factor))
+	}
+	return out
+}
+
+// Shift returns a copy of t with every event moved by offset. Events that
+// would move before the start of the track are placed at its start, in
+// their original order.
+func (t Track) Shift(offset time.Duration) Track {
+	out := t.Clone()
+	for i := range out {
+		if out[i].Time += offset; out[i].Time < 0 {
+			out[i].Time = 0
+		}
+	}
+	return out
+}
+
+// Remap returns a copy of t recorded against the tempo map from, with every
+// event moved to the same musical position in the tempo map to. Positions
+// are kept to fractions of a tick, so remapping back and forth loses
+// nothing but rounding to the nanosecond.
+func (t Track) Remap(from, to *TempoMap) Track {
+	out := t.Clone()
+	for i := range out {
+		out[i].Time = to.durationAtBeats(from.beatsAt(out[i].Time))
+	}
+	return out
+}
+
+// beatsAt returns the position in quarter notes reached after d has elapsed
+// from the start of the sequence, like Tick but without rounding. As with
+// DurationToTicks, a map without a resolution or a tempo that is not
+// positive yields no beats.
+func (m *TempoMap) beatsAt(d time.Duration) float64 {
+	if m.PPQN <= 0 {
+		return 0
+	}
+	var elapsed time.Duration
+	last, bpm := 0, DefaultBPM
+	for _, c := range m.Changes {
+		seg := TicksToDuration(c.Tick-last, m.PPQN, bpm)
+		if elapsed+seg > d {
+			break
+		}
+		elapsed += seg
+		last, bpm = c.Tick, c.BPM
+	}
+	beats := float64(last) / float64(m.PPQN)
+	if bpm <= 0 {
+		return beats
+	}
+	return beats + (d-elapsed).Minutes()*bpm
+}
+
+// durationAtBeats returns the time elapsed from the start of the sequence
+// to a position in quarter notes, like Duration but for fractions of ticks.
+// As with TicksToDuration, a map without a resolution or a tempo that is
+// not positive takes no time.
+func (m *TempoMap) durationAtBeats(beats float64) time.Duration {
+	if m.PPQN <= 0 {
+		return 0
+	}
+	var d time.Duration
+	last, bpm := 0, DefaultBPM
+	for _, c := range m.Changes {
+		if float64(c.Tick) >= beats*float64(m.PPQN) {
+			break
+		}
+		d += TicksToDuration(c.Tick-last, m.PPQN, bpm)
+		last, bpm = c.Tick, c.BPM
+	}
+	if bpm <= 0 {
+		return d
+	}
+	rest := beats - float64(last)/float64(m.PPQN)
+	return d + time.Duration(math.Round(rest*float64(time.Minute)/bpm))
+}
@@ -0,0 +1,93 @@
+package rtmidi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTrackScaleShift(t *testing.T) {
+	ms := time.Millisecond
+	track := Track{
+		{0, []byte{0x90, 60, 100}},
+		{500 * ms, []byte{0x80, 60, 0}},
+		{1000 * ms, []byte{0x90, 62, 100}},
+	}
+	times := func(t Track) []time.Duration {
+		var d []time.Duration
+		for _, ev := range t {
+			d = append(d, ev.Time)
+		}
+		return d
+	}
+	for _, tt := range []struct {
+		name string
+		got  Track
+		want []time.Duration
+	}{
+		{"scale up", track.Scale(1.5), []time.Duration{0, 750 * ms, 1500 * ms}},
+		{"scale down", track.Scale(0.5), []time.Duration{0, 250 * ms, 500 * ms}},
+		{"shift later", track.Shift(100 * ms), []time.Duration{100 * ms, 600 * ms, 1100 * ms}},
+		{"shift earlier", track.Shift(-600 * ms), []time.Duration{0, 0, 400 * ms}},
+	} {
+		if got := times(tt.got); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if track[1].Time != 500*ms {
+		t.Error("original track modified")
+	}
+}
+
+func TestTrackRemap(t *testing.T) {
+	// Recorded at 100 BPM: a quarter note every 600ms.
+	rec := NewTempoMap(480, 100)
+	var track Track
+	for i := 0; i < 8; i++ {
+		track = append(track, Event{time.Duration(i) * 600 * time.Millisecond, []byte{0x90, 60, 100}})
+	}
+	// The project plays the first two beats at 120 BPM, then 60 BPM.
+	project := NewTempoMap(480, 120)
+	project.SetTempo(960, 60)
+	got := track.Remap(rec, project)
+	want := []time.Duration{0, 500, 1000, 2000, 3000, 4000, 5000, 6000}
+	for i, ev := range got {
+		if ev.Time != want[i]*time.Millisecond {
+			t.Errorf("beat %d at %v, want %v", i, ev.Time, want[i]*time.Millisecond)
+		}
+	}
+	back := got.Remap(project, rec)
+	for i, ev := range back {
+		if d := ev.Time - track[i].Time; d < -time.Microsecond || d > time.Microsecond {
+			t.Errorf("beat %d back at %v, want %v", i, ev.Time, track[i].Time)
+		}
+	}
+}
+
+func TestTrackRemapDegenerate(t *testing.T) {
+	track := Track{{0, []byte{0xf8}}, {time.Second, []byte{0xf8}}, {3 * time.Second, []byte{0xf8}}}
+	for _, ev := range track.Remap(&TempoMap{}, NewTempoMap(480, 120)) {
+		if ev.Time != 0 {
+			t.Errorf("remapped from an empty map to %v", ev.Time)
+		}
+	}
+	for _, ev := range track.Remap(NewTempoMap(480, 120), &TempoMap{}) {
+		if ev.Time != 0 {
+			t.Errorf("remapped to an empty map at %v", ev.Time)
+		}
+	}
+	// The tempo stops at beat 2, which is reached after a second.
+	stopped := NewTempoMap(480, 120)
+	stopped.SetTempo(960, 0)
+	want := []time.Duration{0, time.Second, time.Second}
+	for i, ev := range track.Remap(NewTempoMap(480, 120), stopped) {
+		if ev.Time != want[i] {
+			t.Errorf("event %d at %v, want %v", i, ev.Time, want[i])
+		}
+	}
+	for i, ev := range track.Remap(stopped, NewTempoMap(480, 120)) {
+		if ev.Time != want[i] {
+			t.Errorf("event %d back at %v, want %v", i, ev.Time, want[i])
+		}
+	}
+}
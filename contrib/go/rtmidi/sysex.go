@@ -0,0 +1,159 @@
+package rtmidi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrTimeout is returned when a device does not reply in time.
+var ErrTimeout = errors.New("rtmidi: timed out waiting for reply")
+
+// sysexReader collects the SysEx messages received on a MIDIIn while a
+// transfer is in progress. It takes over the input's callback.
+type sysexReader struct {
+	in    MIDIIn
+	ch    chan []byte
+	clock Clock
+}
+
+// newSysexReader returns a reader timing its waits by clock, SystemClock if
+// nil.
+func newSysexReader(in MIDIIn, clock Clock) (*sysexReader, error) {
+	r := &sysexReader{in: in, ch: make(chan []byte, 256), clock: clockOr(clock)}
+	if err := in.IgnoreTypes(false, true, true); err != nil {
+		return nil, err
+	}
+	err := in.SetCallback(func(m MIDIIn, msg []byte, t float64) {
+		if len(msg) == 0 || msg[0] != 0xf0 {
+			return
+		}
+		select {
+		case r.ch <- msg:
+		default:
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *sysexReader) Close() error {
+	return r.in.CancelCallback()
+}
+
+// drain discards any messages received so far.
+func (r *sysexReader) drain() {
+	for {
+		select {
+		case <-r.ch:
+		default:
+			return
+		}
+	}
+}
+
+// next returns the next SysEx message, waiting at most timeout for it. A
+// negative timeout waits until ctx is done.
+func (r *sysexReader) next(ctx context.Context, timeout time.Duration) ([]byte, error) {
+	var expired <-chan time.Time
+	if timeout >= 0 {
+		t := r.clock.NewTimer(timeout)
+		defer t.Stop()
+		expired = t.C()
+	}
+	select {
+	case msg := <-r.ch:
+		return msg, nil
+	case <-expired:
+		return nil, ErrTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// SysExRequest sends req to out and returns the first SysEx message received
+// on in for which match returns true, waiting at most timeout. A nil match
+// accepts any SysEx message. The input's callback is taken over for the
+// duration of the request. The timeout is counted on clock, SystemClock if
+// nil.
+func SysExRequest(ctx context.Context, clock Clock, in MIDIIn, out MIDIOut, req []byte, match func([]byte) bool, timeout time.Duration) ([]byte, error) {
+	r, err := newSysexReader(in, clock)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	if err := out.SendMessage(req); err != nil {
+		return nil, err
+	}
+	deadline := r.clock.Now().Add(timeout)
+	for {
+		msg, err := r.next(ctx, deadline.Sub(r.clock.Now()))
+		if err != nil {
+			return nil, err
+		}
+		if match == nil || match(msg) {
+			return msg, nil
+		}
+	}
+}
+
+// SplitSysEx splits a stream of bytes, such as the contents of a .syx file,
+// into individual SysEx messages. Bytes outside F0...F7 are skipped and an
+// unterminated trailing message is an error.
+func SplitSysEx(data []byte) ([][]byte, error) {
+	var msgs [][]byte
+	start := -1
+	for i, b := range data {
+		switch {
+		case b == 0xf0:
+			start = i
+		case b == 0xf7 && start >= 0:
+			msgs = append(msgs, append([]byte(nil), data[start:i+1]...))
+			start = -1
+		}
+	}
+	if start >= 0 {
+		return msgs, errors.New("rtmidi: unterminated SysEx message")
+	}
+	return msgs, nil
+}
+
+// ReadSyx reads the SysEx messages of a .syx file.
+func ReadSyx(r io.Reader) ([][]byte, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return SplitSysEx(data)
+}
+
+// WriteSyx writes SysEx messages in .syx file format.
+func WriteSyx(w io.Writer, msgs [][]byte) error {
+	for _, msg := range msgs {
+		if _, err := w.Write(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendSysEx sends SysEx messages one at a time with delay between them, which
+// slower devices need to process large dumps without dropping data. The
+// delay is timed by clock, SystemClock if nil.
+func SendSysEx(ctx context.Context, clock Clock, out MIDIOut, msgs [][]byte, delay time.Duration) error {
+	clock = clockOr(clock)
+	for i, msg := range msgs {
+		if i > 0 && delay > 0 {
+			if err := sleepCtx(ctx, clock, delay); err != nil {
+				return err
+			}
+		}
+		if err := out.SendMessage(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
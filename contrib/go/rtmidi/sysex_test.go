@@ -0,0 +1,43 @@
+package rtmidi
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplitSysEx(t *testing.T) {
+	msgs, err := SplitSysEx([]byte{0xf0, 1, 0xf7, 0x90, 0xf0, 2, 3, 0xf7})
+	if err != nil || !reflect.DeepEqual(msgs, [][]byte{{0xf0, 1, 0xf7}, {0xf0, 2, 3, 0xf7}}) {
+		t.Errorf("SplitSysEx = %v, %v", msgs, err)
+	}
+	if _, err := SplitSysEx([]byte{0xf0, 1}); err == nil {
+		t.Error("unterminated message accepted")
+	}
+	var buf bytes.Buffer
+	WriteSyx(&buf, msgs)
+	if got, err := ReadSyx(&buf); err != nil || !reflect.DeepEqual(got, msgs) {
+		t.Errorf("ReadSyx = %v, %v", got, err)
+	}
+}
+
+func TestSysExRequest(t *testing.T) {
+	in, out := &fakeIn{}, &fakeOut{}
+	out.onSend = func(msg []byte) {
+		go func() {
+			in.deliver([]byte{0xf0, 0x01, 0xf7})
+			in.deliver([]byte{0xf0, 0x02, msg[1], 0xf7})
+		}()
+	}
+	reply, err := SysExRequest(context.Background(), nil, in, out, []byte{0xf0, 0x55, 0xf7},
+		func(msg []byte) bool { return msg[1] == 0x02 }, time.Second)
+	if err != nil || !bytes.Equal(reply, []byte{0xf0, 0x02, 0x55, 0xf7}) {
+		t.Errorf("SysExRequest = % x, %v", reply, err)
+	}
+	out.onSend = nil
+	if _, err := SysExRequest(context.Background(), nil, in, out, []byte{0xf0, 0xf7}, nil, 10*time.Millisecond); err != ErrTimeout {
+		t.Errorf("SysExRequest without reply returned %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package rtmidi
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// TempoSetter is implemented by components whose tempo can be changed while
+// they run.
+type TempoSetter interface {
+	SetTempo(bpm float64)
+}
+
+// TapTempo computes a tempo from taps, such as a button pressed in time with
+// the music. The tempo is the mean of the recent tap intervals after those
+// too far from their median are rejected as mistimed taps.
+type TapTempo struct {
+	// History is the number of tap intervals averaged. Zero means 4.
+	History int
+	// Timeout is the longest interval still considered part of the same
+	// sequence of taps; a longer pause starts over. Zero means 2 seconds.
+	Timeout time.Duration
+	// Tolerance is the largest relative deviation from the median interval
+	// that is not rejected as an outlier. Zero means 0.2.
+	Tolerance float64
+
+	mu      sync.Mutex
+	last    time.Time
+	ivals   []time.Duration
+	bpm     float64
+	trigger func([]byte) bool
+	target  TempoSetter
+	clock   Clock
+}
+
+// NewTapTempo returns a TapTempo with the default settings.
+func NewTapTempo() *TapTempo {
+	return &TapTempo{}
+}
+
+// SetClock sets the clock Tap reads the time of taps from, SystemClock by
+// default.
+func (t *TapTempo) SetClock(c Clock) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.clock = c
+}
+
+// Tap registers a tap now and returns the resulting tempo, or zero if not
+// enough taps have been registered yet.
+func (t *TapTempo) Tap() float64 {
+	t.mu.Lock()
+	clock := clockOr(t.clock)
+	t.mu.Unlock()
+	return t.TapAt(clock.Now())
+}
+
+// TapAt registers a tap at the given time.
+func (t *TapTempo) TapAt(at time.Time) float64 {
+	t.mu.Lock()
+	timeout := t.Timeout
+	if timeout == 0 {
+		timeout = 2 * time.Second
+	}
+	history := t.History
+	if history <= 0 {
+		history = 4
+	}
+	if !t.last.IsZero() {
+		d := at.Sub(t.last)
+		if d <= 0 || d > timeout {
+			t.ivals = t.ivals[:0]
+		} else {
+			t.ivals = append(t.ivals, d)
+			if len(t.ivals) > history {
+				t.ivals = t.ivals[len(t.ivals)-history:]
+			}
+		}
+	}
+	t.last = at
+	bpm := t.compute()
+	if bpm > 0 {
+		t.bpm = bpm
+	}
+	target := t.target
+	t.mu.Unlock()
+	if bpm > 0 && target != nil {
+		target.SetTempo(bpm)
+	}
+	return bpm
+}
+
+func (t *TapTempo) compute() float64 {
+	if len(t.ivals) == 0 {
+		return 0
+	}
+	tol := t.Tolerance
+	if tol == 0 {
+		tol = 0.2
+	}
+	sorted := append([]time.Duration(nil), t.ivals...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	median := sorted[len(sorted)/2]
+	var sum time.Duration
+	n := 0
+	for _, d := range t.ivals {
+		dev := float64(d-median) / float64(median)
+		if dev < -tol || dev > tol {
+			continue
+		}
+		sum += d
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return float64(time.Minute) / (float64(sum) / float64(n))
+}
+
+// BPM returns the last computed tempo, or zero if there is none yet.
+func (t *TapTempo) BPM() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.bpm
+}
+
+// Reset forgets all taps.
+func (t *TapTempo) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.last = time.Time{}
+	t.ivals = t.ivals[:0]
+	t.bpm = 0
+}
+
+// Drive makes every new tempo computed be applied to s, such as a clock
+// generator. Pass nil to stop.
+func (t *TapTempo) Drive(s TempoSetter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.target = s
+}
+
+// SetTrigger sets the function that selects which received messages count as
+// taps when passed to Feed. See NoteTrigger and ControlTrigger.
+func (t *TapTempo) SetTrigger(trigger func(msg []byte) bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.trigger = trigger
+}
+
+// Feed registers a tap if msg matches the trigger.
+func (t *TapTempo) Feed(msg []byte) {
+	t.mu.Lock()
+	trigger := t.trigger
+	t.mu.Unlock()
+	if trigger != nil && trigger(msg) {
+		t.Tap()
+	}
+}
+
+// Callback can be passed to MIDIIn.SetCallback to tap from an input directly.
+func (t *TapTempo) Callback(m MIDIIn, msg []byte, ts float64) {
+	t.Feed(msg)
+}
+
+// NoteTrigger matches NoteOn messages for key on channel ch (0-15).
+func NoteTrigger(ch int, key int) func([]byte) bool {
+	return func(msg []byte) bool {
+		return len(msg) >= 3 && msg[0] == 0x90|byte(ch&0x0f) && int(msg[1]) == key && msg[2] > 0
+	}
+}
+
+// ControlTrigger matches Control Change messages for controller cc on
+// channel ch (0-15) with a value of 64 or more, as sent by switches.
+func ControlTrigger(ch int, cc int) func([]byte) bool {
+	return func(msg []byte) bool {
+		return len(msg) >= 3 && msg[0] == 0xb0|byte(ch&0x0f) && int(msg[1]) == cc && msg[2] >= 64
+	}
+}
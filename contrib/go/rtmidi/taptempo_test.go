@@ -0,0 +1,58 @@
+package rtmidi
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+type tempoRecorder struct{ bpm float64 }
+
+func (r *tempoRecorder) SetTempo(bpm float64) { r.bpm = bpm }
+
+func TestTapTempo(t *testing.T) {
+	tap := NewTapTempo()
+	rec := &tempoRecorder{}
+	tap.Drive(rec)
+	start := time.Now()
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	if bpm := tap.TapAt(at(0)); bpm != 0 {
+		t.Errorf("first tap gave %v", bpm)
+	}
+	for _, ms := range []int{500, 1000, 1500} {
+		tap.TapAt(at(ms))
+	}
+	if bpm := tap.BPM(); math.Abs(bpm-120) > 1e-9 {
+		t.Errorf("BPM() = %v, want 120", bpm)
+	}
+	// A late tap is rejected as an outlier.
+	if bpm := tap.TapAt(at(2300)); math.Abs(bpm-120) > 1e-9 {
+		t.Errorf("outlier changed tempo to %v", bpm)
+	}
+	if math.Abs(rec.bpm-120) > 1e-9 {
+		t.Errorf("driven tempo = %v", rec.bpm)
+	}
+	// A long pause starts over.
+	tap.TapAt(at(10000))
+	if bpm := tap.TapAt(at(11000)); math.Abs(bpm-60) > 1e-9 {
+		t.Errorf("after pause BPM = %v, want 60", bpm)
+	}
+}
+
+func TestTapTempoTrigger(t *testing.T) {
+	tap := NewTapTempo()
+	tap.SetTrigger(NoteTrigger(9, 36))
+	tap.Feed([]byte{0x99, 36, 0})
+	tap.Feed([]byte{0x90, 36, 100})
+	if !tap.last.IsZero() {
+		t.Error("non-matching messages registered a tap")
+	}
+	tap.Feed([]byte{0x99, 36, 100})
+	if tap.last.IsZero() {
+		t.Error("matching message did not register a tap")
+	}
+	if !ControlTrigger(0, 64)([]byte{0xb0, 64, 127}) || ControlTrigger(0, 64)([]byte{0xb0, 64, 0}) {
+		t.Error("ControlTrigger mismatch")
+	}
+}
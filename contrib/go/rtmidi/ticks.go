@@ -0,0 +1,112 @@
+package rtmidi
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultBPM is the tempo assumed by Standard MIDI Files that do not set one.
+const DefaultBPM = 120.0
+
+// TicksToDuration converts a number of ticks at the given resolution in pulses
+// per quarter note and a constant tempo in beats per minute into a duration.
+func TicksToDuration(ticks int, ppqn int, bpm float64) time.Duration {
+	if ppqn <= 0 || bpm <= 0 {
+		return 0
+	}
+	return time.Duration(math.Round(float64(ticks) * float64(time.Minute) / (bpm * float64(ppqn))))
+}
+
+// DurationToTicks converts a duration into the nearest number of ticks at the
+// given resolution and constant tempo.
+func DurationToTicks(d time.Duration, ppqn int, bpm float64) int {
+	if ppqn <= 0 || bpm <= 0 {
+		return 0
+	}
+	return int(math.Round(float64(d) * bpm * float64(ppqn) / float64(time.Minute)))
+}
+
+// BPMToMicroseconds converts a tempo into microseconds per quarter note, as
+// stored in Standard MIDI File set tempo events.
+func BPMToMicroseconds(bpm float64) int {
+	return int(math.Round(60e6 / bpm))
+}
+
+// MicrosecondsToBPM converts microseconds per quarter note into a tempo.
+func MicrosecondsToBPM(usec int) float64 {
+	return 60e6 / float64(usec)
+}
+
+// TempoChange sets the tempo from a given tick onwards.
+type TempoChange struct {
+	Tick int
+	BPM  float64
+}
+
+// TempoMap converts between ticks and wall-clock time for a sequence with
+// tempo changes. Changes are kept sorted by tick; if none is at tick 0 the
+// sequence starts at DefaultBPM.
+type TempoMap struct {
+	PPQN    int
+	Changes []TempoChange
+}
+
+// NewTempoMap returns a TempoMap with the given resolution and initial tempo.
+func NewTempoMap(ppqn int, bpm float64) *TempoMap {
+	return &TempoMap{PPQN: ppqn, Changes: []TempoChange{{Tick: 0, BPM: bpm}}}
+}
+
+// SetTempo adds a tempo change at tick, replacing any existing change there.
+func (m *TempoMap) SetTempo(tick int, bpm float64) {
+	i := sort.Search(len(m.Changes), func(i int) bool { return m.Changes[i].Tick >= tick })
+	if i < len(m.Changes) && m.Changes[i].Tick == tick {
+		m.Changes[i].BPM = bpm
+		return
+	}
+	m.Changes = append(m.Changes, TempoChange{})
+	copy(m.Changes[i+1:], m.Changes[i:])
+	m.Changes[i] = TempoChange{Tick: tick, BPM: bpm}
+}
+
+// BPM returns the tempo in effect at tick.
+func (m *TempoMap) BPM(tick int) float64 {
+	bpm := DefaultBPM
+	for _, c := range m.Changes {
+		if c.Tick > tick {
+			break
+		}
+		bpm = c.BPM
+	}
+	return bpm
+}
+
+// Duration returns the time elapsed from the start of the sequence to tick.
+func (m *TempoMap) Duration(tick int) time.Duration {
+	var d time.Duration
+	last, bpm := 0, DefaultBPM
+	for _, c := range m.Changes {
+		if c.Tick >= tick {
+			break
+		}
+		d += TicksToDuration(c.Tick-last, m.PPQN, bpm)
+		last, bpm = c.Tick, c.BPM
+	}
+	return d + TicksToDuration(tick-last, m.PPQN, bpm)
+}
+
+// Tick returns the tick reached after d has elapsed from the start of the
+// sequence.
+func (m *TempoMap) Tick(d time.Duration) int {
+	var elapsed time.Duration
+	last, bpm := 0, DefaultBPM
+	for _, c := range m.Changes {
+		seg := TicksToDuration(c.Tick-last, m.PPQN, bpm)
+		if elapsed+seg > d {
+			break
+		}
+		elapsed += seg
+		last, bpm = c.Tick, c.BPM
+	}
+	return last + DurationToTicks(d-elapsed, m.PPQN, bpm)
+}
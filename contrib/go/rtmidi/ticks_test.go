@@ -0,0 +1,56 @@
+package rtmidi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTicksToDuration(t *testing.T) {
+	if d := TicksToDuration(96, 96, 120); d != 500*time.Millisecond {
+		t.Errorf("TicksToDuration(96, 96, 120) = %v", d)
+	}
+	if d := TicksToDuration(480, 480, 60); d != time.Second {
+		t.Errorf("TicksToDuration(480, 480, 60) = %v", d)
+	}
+	if n := DurationToTicks(time.Second, 24, 120); n != 48 {
+		t.Errorf("DurationToTicks(1s, 24, 120) = %d", n)
+	}
+	if us := BPMToMicroseconds(120); us != 500000 {
+		t.Errorf("BPMToMicroseconds(120) = %d", us)
+	}
+	if bpm := MicrosecondsToBPM(500000); bpm != 120 {
+		t.Errorf("MicrosecondsToBPM(500000) = %v", bpm)
+	}
+}
+
+func TestTempoMap(t *testing.T) {
+	m := NewTempoMap(480, 120)
+	m.SetTempo(960, 60)
+	m.SetTempo(480, 240)
+	m.SetTempo(480, 60)
+	if len(m.Changes) != 3 || m.Changes[1].Tick != 480 || m.Changes[1].BPM != 60 {
+		t.Fatalf("unexpected changes %+v", m.Changes)
+	}
+	for _, test := range []struct {
+		tick int
+		d    time.Duration
+	}{
+		{0, 0},
+		{480, 500 * time.Millisecond},
+		{960, 1500 * time.Millisecond},
+		{1440, 2500 * time.Millisecond},
+	} {
+		if d := m.Duration(test.tick); d != test.d {
+			t.Errorf("Duration(%d) = %v, want %v", test.tick, d, test.d)
+		}
+		if tick := m.Tick(test.d); tick != test.tick {
+			t.Errorf("Tick(%v) = %d, want %d", test.d, tick, test.tick)
+		}
+	}
+	if bpm := m.BPM(500); bpm != 60 {
+		t.Errorf("BPM(500) = %v", bpm)
+	}
+	if bpm := (&TempoMap{PPQN: 96}).BPM(0); bpm != DefaultBPM {
+		t.Errorf("empty map BPM = %v", bpm)
+	}
+}
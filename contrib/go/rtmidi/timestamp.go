@@ -0,0 +1,127 @@
+package rtmidi
+
+import (
+	"sync"
+	"time"
+)
+
+// TimestampSource selects where the timestamps of received messages come
+// from.
+type TimestampSource int
+
+const (
+	// TimestampDriver places messages by the timestamps RtMidi passes to
+	// input callbacks, the time since the previous message, accumulated
+	// from the first. RtMidi works them out from whatever time its
+	// backend gives a message, which the binding has no say in: it cannot
+	// pick another clock of the backend, such as ALSA tick rather than
+	// real time.
+	TimestampDriver TimestampSource = iota
+	// TimestampArrival uses the time the message reached the binding, which
+	// includes any scheduling latency of the backend.
+	TimestampArrival
+)
+
+func (s TimestampSource) String() string {
+	switch s {
+	case TimestampDriver:
+		return "driver"
+	case TimestampArrival:
+		return "arrival"
+	}
+	return "unknown"
+}
+
+// Timestamper turns the timestamps passed to input callbacks, which are the
+// seconds elapsed since the previous message measured by the backend's own
+// clock, into times on Go's monotonic clock, so that messages from inputs of
+// different backends can be compared and merged.
+//
+// With TimestampDriver the first message is anchored at its arrival time and
+// later ones are placed by accumulating the backend's deltas. A time that
+// would lie in the future is clamped to the arrival time and the anchor
+// moved, which keeps a backend clock running fast from drifting ahead. A
+// backend clock running slow falls behind instead: after every second of
+// backend time, the anchor is moved forward by the smallest delay between
+// the time of a message and its arrival during that second, so that the
+// messages delivered soonest keep matching their arrival.
+type Timestamper struct {
+	source TimestampSource
+
+	mu      sync.Mutex
+	started bool
+	base    time.Time
+	elapsed time.Duration
+	// window is the elapsed time at which the current drift window
+	// started, and minLag the smallest delay measured in it.
+	window   time.Duration
+	minLag   time.Duration
+	measured bool
+}
+
+// driftWindow is the backend time over which a Timestamper measures how far
+// a slow backend clock has fallen behind.
+const driftWindow = time.Second
+
+// NewTimestamper returns a Timestamper using the given source.
+func NewTimestamper(source TimestampSource) *Timestamper {
+	return &Timestamper{source: source}
+}
+
+// Source returns the timestamp source in use.
+func (t *Timestamper) Source() TimestampSource {
+	return t.source
+}
+
+// Stamp returns the time of a message received now whose callback
+// timestamp was delta.
+func (t *Timestamper) Stamp(delta float64) time.Time {
+	return t.stampAt(delta, time.Now())
+}
+
+func (t *Timestamper) stampAt(delta float64, now time.Time) time.Time {
+	if t.source == TimestampArrival {
+		return now
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.started {
+		t.started = true
+		t.base, t.elapsed = now, 0
+		t.window, t.measured = 0, false
+		return now
+	}
+	if delta > 0 {
+		t.elapsed += time.Duration(delta * float64(time.Second))
+	}
+	at := t.base.Add(t.elapsed)
+	if at.After(now) {
+		t.base = now.Add(-t.elapsed)
+		at = now
+	}
+	if lag := now.Sub(at); !t.measured || lag < t.minLag {
+		t.minLag, t.measured = lag, true
+	}
+	if t.elapsed-t.window >= driftWindow {
+		t.base = t.base.Add(t.minLag)
+		t.window, t.measured = t.elapsed, false
+	}
+	return at
+}
+
+// Reset forgets the anchor, so that the next message is anchored again. Call
+// it when the port is reopened.
+func (t *Timestamper) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.started = false
+}
+
+// Wrap returns a callback for MIDIIn.SetCallback that passes each message
+// on to cb with its normalized time. With SetBufferedCallback arrival times
+// are taken when the message is dispatched rather than when it arrived.
+func (t *Timestamper) Wrap(cb func(MIDIIn, []byte, time.Time)) func(MIDIIn, []byte, float64) {
+	return func(m MIDIIn, msg []byte, delta float64) {
+		cb(m, msg, t.Stamp(delta))
+	}
+}
@@ -0,0 +1,64 @@
+package rtmidi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimestamperDriver(t *testing.T) {
+	ts := NewTimestamper(TimestampDriver)
+	start := time.Now()
+	ms := func(n int) time.Time { return start.Add(time.Duration(n) * time.Millisecond) }
+
+	for i, tt := range []struct {
+		delta   float64
+		arrival time.Time
+		want    time.Time
+	}{
+		{0, ms(0), ms(0)},
+		// Delivered late: the driver time is kept.
+		{0.010, ms(15), ms(10)},
+		{0.010, ms(21), ms(20)},
+		// Driver clock ahead of arrival: clamped and re-anchored.
+		{0.030, ms(40), ms(40)},
+		{0.005, ms(46), ms(45)},
+	} {
+		if got := ts.stampAt(tt.delta, tt.arrival); !got.Equal(tt.want) {
+			t.Errorf("message %d at %v, want %v", i, got.Sub(start), tt.want.Sub(start))
+		}
+	}
+
+	ts.Reset()
+	if got := ts.stampAt(0.5, ms(100)); !got.Equal(ms(100)) {
+		t.Errorf("after Reset: %v, want 100ms", got.Sub(start))
+	}
+}
+
+func TestTimestamperArrival(t *testing.T) {
+	ts := NewTimestamper(TimestampArrival)
+	now := time.Now()
+	if got := ts.stampAt(0.5, now); !got.Equal(now) {
+		t.Errorf("arrival stamp %v, want %v", got, now)
+	}
+	var got time.Time
+	ts.Wrap(func(_ MIDIIn, _ []byte, at time.Time) { got = at })(nil, []byte{0xf8}, 0)
+	if got.Before(now) {
+		t.Error("wrapped callback got a time before the message arrived")
+	}
+}
+
+func TestTimestamperSlowClock(t *testing.T) {
+	ts := NewTimestamper(TimestampDriver)
+	start := time.Now()
+	var at, arrival time.Time
+	// The backend clock measures 9ms for every 10ms, which would leave
+	// the stamps 3s behind after 30s. The drift corrected after each
+	// window keeps them within about two windows of it.
+	for i := 0; i <= 3000; i++ {
+		arrival = start.Add(time.Duration(i) * 10 * time.Millisecond)
+		at = ts.stampAt(0.009, arrival)
+	}
+	if lag := arrival.Sub(at); lag > 250*time.Millisecond {
+		t.Errorf("stamp %v behind arrival after 30s", lag)
+	}
+}
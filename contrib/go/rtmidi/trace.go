@@ -0,0 +1,216 @@
+package rtmidi
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+)
+
+// TraceKind is what happened to a message at a step of a Trace.
+type TraceKind int
+
+const (
+	// TraceMatch is a rule matching the message.
+	TraceMatch TraceKind = iota
+	// TraceTransform is a transform of a rule changing the message.
+	TraceTransform
+	// TraceDrop is a transform of a rule dropping the message.
+	TraceDrop
+	// TraceSend is the message being sent to an output of a rule.
+	TraceSend
+)
+
+func (k TraceKind) String() string {
+	switch k {
+	case TraceMatch:
+		return "match"
+	case TraceTransform:
+		return "transform"
+	case TraceDrop:
+		return "drop"
+	case TraceSend:
+		return "send"
+	}
+	return "?"
+}
+
+// TraceStep is a step of a message through a Router.
+type TraceStep struct {
+	Kind TraceKind
+	// Rule is the index of the rule in the router, or -1 if the rule has
+	// been removed, and Name the name given to it with Named.
+	Rule int
+	Name string
+	// Index is the index of the transform in the rule for TraceTransform
+	// and TraceDrop, and of the output in the rule's SendTo list for
+	// TraceSend.
+	Index int
+	// Message is the message as transformed or sent.
+	Message []byte
+	// Err is the error sending the message, if any.
+	Err error
+
+	out MIDIOut
+}
+
+func (s TraceStep) String() string {
+	rule := fmt.Sprintf("rule %d", s.Rule)
+	if s.Name != "" {
+		rule += fmt.Sprintf(" %q", s.Name)
+	}
+	switch s.Kind {
+	case TraceMatch:
+		return rule + " matched"
+	case TraceTransform:
+		return fmt.Sprintf("%s transform %d: %s", rule, s.Index, FormatMessage(s.Message))
+	case TraceDrop:
+		return fmt.Sprintf("%s transform %d: dropped", rule, s.Index)
+	}
+	if s.Err != nil {
+		return fmt.Sprintf("%s output %d: %s: %v", rule, s.Index, FormatMessage(s.Message), s.Err)
+	}
+	return fmt.Sprintf("%s output %d: %s", rule, s.Index, FormatMessage(s.Message))
+}
+
+// Trace is the path of an incoming message through a Router.
+type Trace struct {
+	// ID numbers the messages routed while tracing, from 1.
+	ID uint64
+	// At is when the message was routed.
+	At time.Time
+	// Port is the port the message came from, if routed with
+	// Router.RouteEvent.
+	Port    PortRef
+	Message []byte
+	Steps   []TraceStep
+}
+
+// String describes the trace on several lines, such as:
+//
+//	#3 12:00:00.000000 from alsa:Keystation 49 NoteOn ch=1 C4 vel=100
+//	  rule 0 "drums" matched
+//	  rule 0 "drums" transform 0: NoteOn ch=10 C4 vel=100
+//	  rule 0 "drums" output 0: NoteOn ch=10 C4 vel=100
+func (t Trace) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "#%d %s", t.ID, t.At.Format("15:04:05.000000"))
+	if t.Port.ID != "" {
+		fmt.Fprintf(&b, " from %s", t.Port)
+	}
+	fmt.Fprintf(&b, " %s", FormatMessage(t.Message))
+	if len(t.Steps) == 0 {
+		b.WriteString("\n  no rule matched")
+	}
+	for _, s := range t.Steps {
+		fmt.Fprintf(&b, "\n  %v", s)
+	}
+	return b.String()
+}
+
+// traceLog keeps the latest traces of a Router.
+type traceLog struct {
+	size   int
+	nextID uint64
+	traces []*Trace
+}
+
+// SetTracing makes the router keep the traces of the last n messages it
+// routes, recording the rules they matched, their transforms and the
+// outputs they were sent to. Zero turns tracing off and drops the traces
+// kept.
+func (r *Router) SetTracing(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.traces.size = n
+	if n <= 0 {
+		r.traces.traces = nil
+	} else if len(r.traces.traces) > n {
+		r.traces.traces = r.traces.traces[len(r.traces.traces)-n:]
+	}
+}
+
+// Traces returns the traces kept, oldest first.
+func (r *Router) Traces() []Trace {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	traces := make([]Trace, len(r.traces.traces))
+	for i, t := range r.traces.traces {
+		traces[i] = *t
+	}
+	return traces
+}
+
+// Cause returns the latest trace kept in which msg was sent to out, which
+// tells the incoming message and the rules that caused it.
+func (r *Router) Cause(out MIDIOut, msg []byte) (Trace, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.traces.traces) - 1; i >= 0; i-- {
+		t := r.traces.traces[i]
+		for _, s := range t.Steps {
+			if s.Kind == TraceSend && s.out == out && bytes.Equal(s.Message, msg) {
+				return *t, true
+			}
+		}
+	}
+	return Trace{}, false
+}
+
+// DumpTraces writes the traces kept to w, oldest first.
+func (r *Router) DumpTraces(w io.Writer) error {
+	for _, t := range r.Traces() {
+		if _, err := fmt.Fprintln(w, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startTrace returns a new trace for msg from the port origin, or nil if
+// tracing is off.
+func (r *Router) startTrace(origin PortRef, msg []byte) *Trace {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.traces.size <= 0 {
+		return nil
+	}
+	r.traces.nextID++
+	return &Trace{ID: r.traces.nextID, At: time.Now(), Port: origin, Message: append([]byte(nil), msg...)}
+}
+
+// trace adds a step to tr, if tracing.
+func (r *Router) trace(tr *Trace, kind TraceKind, rule *Rule, index int, msg []byte, out MIDIOut, err error) {
+	if tr == nil {
+		return
+	}
+	r.mu.Lock()
+	link := r.linkLocked(rule, index)
+	r.mu.Unlock()
+	tr.Steps = append(tr.Steps, TraceStep{
+		Kind:    kind,
+		Rule:    link.Rule,
+		Name:    rule.name,
+		Index:   index,
+		Message: append([]byte(nil), msg...),
+		Err:     err,
+		out:     out,
+	})
+}
+
+// endTrace keeps tr, dropping the oldest trace if there are too many.
+func (r *Router) endTrace(tr *Trace) {
+	if tr == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.traces.size <= 0 {
+		return
+	}
+	r.traces.traces = append(r.traces.traces, tr)
+	if n := len(r.traces.traces) - r.traces.size; n > 0 {
+		r.traces.traces[0] = nil
+		r.traces.traces = r.traces.traces[n:]
+	}
+}
@@ -0,0 +1,82 @@
+package rtmidi
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRouterTracing(t *testing.T) {
+	drums, rest := &fakeOut{}, &fakeOut{}
+	toDrums := func(msg []byte) []byte { msg[0] = msg[0]&0xf0 | 9; return msg }
+	dropSoft := func(msg []byte) []byte {
+		if isNoteOn(msg) && msg[2] < 10 {
+			return nil
+		}
+		return msg
+	}
+	r := NewRouter(
+		When(NoteRange(35, 59)).Transform(dropSoft).Transform(toDrums).SendTo(drums).Named("drums").Final(),
+		When(Any()).SendTo(rest),
+	)
+	r.Route([]byte{0x90, 60, 100})
+	r.SetTracing(2)
+	r.Route([]byte{0x90, 36, 5})
+	r.Route([]byte{0x90, 36, 100})
+	r.Route([]byte{0xf8})
+
+	traces := r.Traces()
+	if len(traces) != 2 {
+		t.Fatalf("kept %d traces, want 2", len(traces))
+	}
+	if traces[0].ID != 2 || traces[1].ID != 3 {
+		t.Errorf("trace IDs %d, %d, want 2, 3", traces[0].ID, traces[1].ID)
+	}
+	want := []TraceStep{
+		{Kind: TraceMatch, Rule: 0, Name: "drums", Index: -1},
+		{Kind: TraceTransform, Rule: 0, Name: "drums", Index: 0, Message: []byte{0x90, 36, 100}},
+		{Kind: TraceTransform, Rule: 0, Name: "drums", Index: 1, Message: []byte{0x99, 36, 100}},
+		{Kind: TraceSend, Rule: 0, Name: "drums", Index: 0, Message: []byte{0x99, 36, 100}, out: drums},
+	}
+	if got := traces[0].Steps; !reflect.DeepEqual(got, want) {
+		t.Errorf("steps\n%v\nwant\n%v", got, want)
+	}
+
+	tr, ok := r.Cause(drums, []byte{0x99, 36, 100})
+	if !ok || !bytes.Equal(tr.Message, []byte{0x90, 36, 100}) {
+		t.Errorf("Cause = %v, %v", tr, ok)
+	}
+	if _, ok := r.Cause(rest, []byte{0x90, 60, 100}); ok {
+		t.Error("Cause found a message routed before tracing")
+	}
+
+	var b bytes.Buffer
+	if err := r.DumpTraces(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`rule 0 "drums" transform 1: NoteOn ch=10 C2 vel=100`,
+		`rule 1 output 0: Clock`,
+	} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("dump lacks %q:\n%s", s, b.String())
+		}
+	}
+
+	r.SetTracing(0)
+	r.Route([]byte{0xf8})
+	if n := len(r.Traces()); n != 0 {
+		t.Errorf("kept %d traces with tracing off", n)
+	}
+}
+
+func TestRouterTracingDrop(t *testing.T) {
+	r := NewRouter(When(Any()).Transform(func([]byte) []byte { return nil }).SendTo(&fakeOut{}))
+	r.SetTracing(1)
+	r.Route([]byte{0x90, 60, 100})
+	steps := r.Traces()[0].Steps
+	if len(steps) != 2 || steps[1].Kind != TraceDrop {
+		t.Errorf("steps %v, want match and drop", steps)
+	}
+}
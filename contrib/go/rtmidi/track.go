@@ -0,0 +1,47 @@
+package rtmidi
+
+import (
+	"sort"
+	"time"
+)
+
+// Event is a MIDI message at a point in time, relative to the start of the
+// track it belongs to.
+type Event struct {
+	Time    time.Duration
+	Message []byte
+}
+
+// Track is a sequence of events in time order.
+type Track []Event
+
+// Sort orders the events by time, keeping the original order of events that
+// happen at the same time.
+func (t Track) Sort() {
+	sort.SliceStable(t, func(i, j int) bool { return t[i].Time < t[j].Time })
+}
+
+// Duration returns the time of the last event.
+func (t Track) Duration() time.Duration {
+	if len(t) == 0 {
+		return 0
+	}
+	return t[len(t)-1].Time
+}
+
+// Clone returns a copy of the track that shares no memory with it.
+func (t Track) Clone() Track {
+	c := make(Track, len(t))
+	for i, ev := range t {
+		c[i] = Event{Time: ev.Time, Message: append([]byte(nil), ev.Message...)}
+	}
+	return c
+}
+
+func isNoteOn(msg []byte) bool {
+	return len(msg) >= 3 && msg[0]&0xf0 == 0x90 && msg[2] > 0
+}
+
+func isNoteOff(msg []byte) bool {
+	return len(msg) >= 3 && (msg[0]&0xf0 == 0x80 || msg[0]&0xf0 == 0x90 && msg[2] == 0)
+}
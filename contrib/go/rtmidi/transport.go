@@ -0,0 +1,217 @@
+package rtmidi
+
+import (
+	"fmt"
+	"sync"
+)
+
+// MIDI realtime and song position status bytes.
+const (
+	statusSongPosition = 0xf2
+	statusClock        = 0xf8
+	statusStart        = 0xfa
+	statusContinue     = 0xfb
+	statusStop         = 0xfc
+)
+
+// ClocksPerQuarter is the number of MIDI timing clocks sent per quarter note.
+const ClocksPerQuarter = 24
+
+// SongPositionMessage returns a Song Position Pointer message locating to the
+// given number of MIDI beats (sixteenth notes).
+func SongPositionMessage(sixteenths int) []byte {
+	if sixteenths < 0 {
+		sixteenths = 0
+	}
+	if sixteenths > 0x3fff {
+		sixteenths = 0x3fff
+	}
+	return []byte{statusSongPosition, byte(sixteenths & 0x7f), byte(sixteenths >> 7 & 0x7f)}
+}
+
+// Transport sends transport control messages to a MIDIOut.
+type Transport struct {
+	out MIDIOut
+}
+
+// NewTransport returns a Transport controlling the devices connected to out.
+func NewTransport(out MIDIOut) *Transport {
+	return &Transport{out: out}
+}
+
+// Start makes receivers play from the beginning of the song.
+func (t *Transport) Start() error {
+	return t.out.SendMessage([]byte{statusStart})
+}
+
+// Stop makes receivers stop, remembering the current position.
+func (t *Transport) Stop() error {
+	return t.out.SendMessage([]byte{statusStop})
+}
+
+// Continue makes receivers play from the current position.
+func (t *Transport) Continue() error {
+	return t.out.SendMessage([]byte{statusContinue})
+}
+
+// Locate moves receivers to a position given in quarter notes from the start
+// of the song. Song Position Pointer has a resolution of a sixteenth note, so
+// the position is rounded down to the nearest one. Receivers should be
+// stopped when locating; follow with Continue to resume playback there.
+func (t *Transport) Locate(beats float64) error {
+	if beats < 0 || beats*4 > 0x3fff {
+		return fmt.Errorf("rtmidi: song position %v out of range", beats)
+	}
+	return t.out.SendMessage(SongPositionMessage(int(beats * 4)))
+}
+
+// TransportState is the playing state of a MIDI transport.
+type TransportState int
+
+const (
+	// TransportStopped means the transport is not playing.
+	TransportStopped TransportState = iota
+	// TransportPlaying means the transport is playing.
+	TransportPlaying
+)
+
+func (s TransportState) String() string {
+	switch s {
+	case TransportStopped:
+		return "stopped"
+	case TransportPlaying:
+		return "playing"
+	}
+	return "?"
+}
+
+// TransportEvent reports a change of transport state or position.
+type TransportEvent struct {
+	State TransportState
+	// Position is in quarter notes from the start of the song.
+	Position float64
+}
+
+// TransportFollower tracks the transport of a device from the Start, Stop,
+// Continue, Song Position Pointer and timing clock messages it sends.
+type TransportFollower struct {
+	mu        sync.Mutex
+	state     TransportState
+	clocks    int
+	events    chan TransportEvent
+	listeners transportHub
+}
+
+// transportHub calls the functions registered with OnTransport.
+type transportHub struct {
+	mu   sync.Mutex
+	next int
+	ids  []int
+	fns  []func(TransportEvent)
+}
+
+func (h *transportHub) add(fn func(TransportEvent)) (cancel func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	id := h.next
+	h.next++
+	h.ids = append(h.ids, id)
+	h.fns = append(h.fns, fn)
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		for i := range h.ids {
+			if h.ids[i] == id {
+				h.ids = append(h.ids[:i:i], h.ids[i+1:]...)
+				h.fns = append(h.fns[:i:i], h.fns[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (h *transportHub) emit(ev TransportEvent) {
+	h.mu.Lock()
+	fns := h.fns
+	h.mu.Unlock()
+	for _, fn := range fns {
+		fn(ev)
+	}
+}
+
+// NewTransportFollower returns a TransportFollower whose event stream buffers
+// up to n events. Events are dropped rather than blocking the MIDI input when
+// the buffer is full.
+func NewTransportFollower(n int) *TransportFollower {
+	return &TransportFollower{events: make(chan TransportEvent, n)}
+}
+
+// Events returns the stream of transport state changes and locates.
+func (f *TransportFollower) Events() <-chan TransportEvent {
+	return f.events
+}
+
+// OnTransport registers fn to be called with every event sent on the
+// Events stream, from the goroutine feeding the follower. Unlike the stream
+// it never drops events, so fn must not block.
+func (f *TransportFollower) OnTransport(fn func(TransportEvent)) (cancel func()) {
+	return f.listeners.add(fn)
+}
+
+// State returns the current transport state and position in quarter notes.
+func (f *TransportFollower) State() (TransportState, float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.state, float64(f.clocks) / ClocksPerQuarter
+}
+
+// Callback can be passed to MIDIIn.SetCallback to follow the input directly.
+func (f *TransportFollower) Callback(m MIDIIn, msg []byte, t float64) {
+	f.Feed(msg)
+}
+
+// Feed processes a received MIDI message.
+func (f *TransportFollower) Feed(msg []byte) {
+	f.feed(msg)
+}
+
+// feed processes msg and, if it is a timing clock received while playing,
+// returns the pulse it marks counted from the start of the song.
+func (f *TransportFollower) feed(msg []byte) (pulse int, ok bool) {
+	if len(msg) == 0 {
+		return 0, false
+	}
+	f.mu.Lock()
+	switch msg[0] {
+	case statusClock:
+		pulse, ok = f.clocks, f.state == TransportPlaying
+		if ok {
+			f.clocks++
+		}
+		f.mu.Unlock()
+		return pulse, ok
+	case statusStart:
+		f.state, f.clocks = TransportPlaying, 0
+	case statusContinue:
+		f.state = TransportPlaying
+	case statusStop:
+		f.state = TransportStopped
+	case statusSongPosition:
+		if len(msg) < 3 {
+			f.mu.Unlock()
+			return 0, false
+		}
+		f.clocks = (int(msg[1]) | int(msg[2])<<7) * ClocksPerQuarter / 4
+	default:
+		f.mu.Unlock()
+		return 0, false
+	}
+	ev := TransportEvent{State: f.state, Position: float64(f.clocks) / ClocksPerQuarter}
+	f.mu.Unlock()
+	select {
+	case f.events <- ev:
+	default:
+	}
+	f.listeners.emit(ev)
+	return 0, false
+}
@@ -0,0 +1,59 @@
+package rtmidi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	out := &fakeOut{}
+	tr := NewTransport(out)
+	tr.Start()
+	tr.Stop()
+	if err := tr.Locate(33.5); err != nil {
+		t.Fatal(err)
+	}
+	tr.Continue()
+	want := [][]byte{{0xfa}, {0xfc}, {0xf2, 6, 1}, {0xfb}}
+	if got := out.messages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("sent %v, want %v", got, want)
+	}
+	if err := tr.Locate(-1); err == nil {
+		t.Error("expected error locating before the start")
+	}
+}
+
+func TestTransportFollower(t *testing.T) {
+	f := NewTransportFollower(8)
+	for _, msg := range [][]byte{{0xfa}, {0xf8}, {0xf8}, {0xf8}, {0xf8}, {0xf8}, {0xf8}, {0xfc}, {0xf2, 8, 0}, {0xfb}, {0xf8}} {
+		f.Feed(msg)
+	}
+	want := []TransportEvent{
+		{TransportPlaying, 0},
+		{TransportStopped, 0.25},
+		{TransportStopped, 2},
+		{TransportPlaying, 2},
+	}
+	for i, w := range want {
+		if ev := <-f.Events(); ev != w {
+			t.Errorf("event %d = %+v, want %+v", i, ev, w)
+		}
+	}
+	if state, pos := f.State(); state != TransportPlaying || pos != 2+1.0/24 {
+		t.Errorf("State() = %v, %v", state, pos)
+	}
+}
+
+func TestTransportFollowerOnTransport(t *testing.T) {
+	f := NewTransportFollower(0)
+	var got []TransportEvent
+	cancel := f.OnTransport(func(ev TransportEvent) { got = append(got, ev) })
+	f.Feed([]byte{statusStart})
+	f.Feed(SongPositionMessage(8))
+	cancel()
+	f.Feed([]byte{statusStop})
+	want := []TransportEvent{{TransportPlaying, 0}, {TransportPlaying, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("OnTransport got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+//go:build !cgo
+
+package rtmidi
+
+// Without cgo none of the RtMidi backends can be built. This file lets the
+// package build anyway, so that programs using MIDI only where it is
+// available still compile everywhere: no API is compiled in, and opening a
+// port fails with ErrUnsupportedPlatform.
+
+// API is an enumeration of possible MIDI API specifiers.
+type API int
+
+// The values match those of RtMidi.
+const (
+	// APIUnspecified searches for a working compiled API.
+	APIUnspecified API = iota
+	// APIMacOSXCore uses Macintosh OS-X CoreMIDI API.
+	APIMacOSXCore
+	// APILinuxALSA uses the Advanced Linux Sound Architecture API.
+	APILinuxALSA
+	// APIUnixJack uses the JACK Low-Latency MIDI Server API.
+	APIUnixJack
+	// APIWindowsMM uses the Microsoft Multimedia MIDI API.
+	APIWindowsMM
+	// APIDummy is a compilable but non-functional API.
+	APIDummy
+)
+
+// CompiledAPI determines the available compiled MIDI APIs, of which there
+// are none.
+func CompiledAPI() []API {
+	return nil
+}
+
+// NewMIDIInDefault returns ErrUnsupportedPlatform.
+func NewMIDIInDefault() (MIDIIn, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// NewMIDIIn returns ErrUnsupportedPlatform.
+func NewMIDIIn(api API, name string, queueSize int) (MIDIIn, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// NewMIDIOutDefault returns ErrUnsupportedPlatform.
+func NewMIDIOutDefault() (MIDIOut, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// NewMIDIOut returns ErrUnsupportedPlatform.
+func NewMIDIOut(api API, name string) (MIDIOut, error) {
+	return nil, ErrUnsupportedPlatform
+}
+
+// Native holds no handles without a backend.
+type Native struct{}
+
+// Unwrap returns ErrUnsupportedPlatform, as no port can be created by this
+// package.
+func Unwrap(m MIDI) (*Native, error) {
+	return nil, ErrUnsupportedPlatform
+}
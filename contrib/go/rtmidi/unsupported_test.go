@@ -0,0 +1,23 @@
+//go:build !cgo
+
+package rtmidi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUnsupportedPlatform(t *testing.T) {
+	if apis := CompiledAPI(); len(apis) != 0 {
+		t.Errorf("CompiledAPI() = %v, want none", apis)
+	}
+	if _, err := NewMIDIInDefault(); err != ErrUnsupportedPlatform {
+		t.Errorf("NewMIDIInDefault: %v, want ErrUnsupportedPlatform", err)
+	}
+	if _, err := NewMIDIOut(APIDummy, "test"); err != ErrUnsupportedPlatform {
+		t.Errorf("NewMIDIOut: %v, want ErrUnsupportedPlatform", err)
+	}
+	if _, err := OpenAll(PortSpec{Name: "in"}); !errors.Is(err, ErrUnsupportedPlatform) {
+		t.Errorf("OpenAll: %v, want ErrUnsupportedPlatform", err)
+	}
+}
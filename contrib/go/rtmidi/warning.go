@@ -0,0 +1,123 @@
+package rtmidi
+
+import (
+	"errors"
+	"log"
+	"sync"
+)
+
+// WarningPolicy says what a port does with warnings: conditions that do
+// not stop it from working, such as a port name matching nothing or an
+// incomplete SysEx message. Without a policy, RtMidi prints warnings to
+// standard error and the binding returns the ones it raises itself as
+// errors. RtMidi prints that the input queue is full straight to standard
+// error whatever the policy; see Dropped for the messages a buffered
+// callback drops.
+type WarningPolicy int
+
+const (
+	// WarningsLog logs warnings with the log package.
+	WarningsLog WarningPolicy = iota
+	// WarningsIgnore drops warnings.
+	WarningsIgnore
+	// WarningsCallback passes warnings to a function.
+	WarningsCallback
+	// WarningsError makes the next call on the port return the warning as
+	// its error, which is usually the call that raised it.
+	WarningsError
+)
+
+func (p WarningPolicy) String() string {
+	switch p {
+	case WarningsLog:
+		return "log"
+	case WarningsIgnore:
+		return "ignore"
+	case WarningsCallback:
+		return "callback"
+	case WarningsError:
+		return "error"
+	}
+	return "?"
+}
+
+// Warning is a warning from a port.
+type Warning struct {
+	Message string
+	// Debug is set for warnings only meant for debugging, which
+	// WarningsLog and WarningsError leave out.
+	Debug bool
+}
+
+func (w *Warning) Error() string {
+	return "rtmidi: warning: " + w.Message
+}
+
+// warner is implemented by ports whose warnings can be handled.
+type warner interface {
+	setWarningPolicy(policy WarningPolicy, fn func(*Warning)) error
+}
+
+// SetWarningPolicy sets what port m does with its warnings. fn receives
+// them under WarningsCallback, and is otherwise unused.
+func SetWarningPolicy(m MIDI, policy WarningPolicy, fn func(*Warning)) error {
+	w, ok := m.(warner)
+	if !ok {
+		return errors.New("rtmidi: port does not support warning policies")
+	}
+	if policy == WarningsCallback && fn == nil {
+		return errors.New("rtmidi: no warning callback")
+	}
+	return w.setWarningPolicy(policy, fn)
+}
+
+// warningHandler applies the warning policy of a port.
+type warningHandler struct {
+	mu      sync.Mutex
+	policy  WarningPolicy
+	fn      func(*Warning)
+	pending *Warning
+}
+
+func (h *warningHandler) set(policy WarningPolicy, fn func(*Warning)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.policy, h.fn, h.pending = policy, fn, nil
+}
+
+func (h *warningHandler) handle(w *Warning) {
+	h.mu.Lock()
+	policy, fn := h.policy, h.fn
+	if policy == WarningsError && !w.Debug && h.pending == nil {
+		h.pending = w
+	}
+	h.mu.Unlock()
+	switch {
+	case policy == WarningsLog && !w.Debug:
+		log.Print(w)
+	case policy == WarningsCallback:
+		fn(w)
+	}
+}
+
+// take returns the warning held for the next call under WarningsError.
+func (h *warningHandler) take() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.pending == nil {
+		return nil
+	}
+	w := h.pending
+	h.pending = nil
+	return w
+}
+
+// warn raises a warning of the binding itself. With no policy set it is
+// returned as an error, as before policies existed.
+func (h *warningHandler) warn(msg string) error {
+	if h == nil {
+		return errors.New("rtmidi: " + msg)
+	}
+	h.handle(&Warning{Message: msg})
+	return h.take()
+}
@@ -0,0 +1,56 @@
+package rtmidi
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWarningHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	var h warningHandler
+	h.handle(&Warning{Message: "queue full"})
+	h.handle(&Warning{Message: "parsing", Debug: true})
+	if s := buf.String(); !strings.Contains(s, "rtmidi: warning: queue full") || strings.Contains(s, "parsing") {
+		t.Errorf("logged %q", s)
+	}
+	if err := h.take(); err != nil {
+		t.Errorf("warning held while logging: %v", err)
+	}
+
+	var got []string
+	h.set(WarningsCallback, func(w *Warning) { got = append(got, w.Message) })
+	h.handle(&Warning{Message: "a"})
+	h.handle(&Warning{Message: "b", Debug: true})
+	if len(got) != 2 {
+		t.Errorf("callback got %q", got)
+	}
+
+	h.set(WarningsError, nil)
+	if err := h.warn("no port matches"); err == nil || err.(*Warning).Message != "no port matches" {
+		t.Errorf("warn returned %v", err)
+	}
+	if err := h.take(); err != nil {
+		t.Errorf("warning returned twice: %v", err)
+	}
+
+	h.set(WarningsIgnore, nil)
+	if err := h.warn("no port matches"); err != nil {
+		t.Errorf("ignored warning returned %v", err)
+	}
+	var none *warningHandler
+	if err := none.warn("no port matches"); err == nil {
+		t.Error("warning without a policy not returned")
+	}
+}
+
+func TestSetWarningPolicy(t *testing.T) {
+	if err := SetWarningPolicy(&fakeOut{}, WarningsIgnore, nil); err == nil {
+		t.Error("fake port accepted a policy")
+	}
+}